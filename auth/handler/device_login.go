@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"gin-server/auth/service"
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceLoginRequest 设备登录请求
+type DeviceLoginRequest struct {
+	DeviceID            int    `json:"device_id" binding:"required"` // 设备唯一标识
+	PassWD              string `json:"pass_wd" binding:"required"`   // 设备登录口令
+	HardwareFingerprint string `json:"hardware_fingerprint"`         // 设备硬件指纹，设备登记了指纹时必填
+}
+
+// DeviceLogin 处理设备登录请求，校验通过后签发设备会话令牌
+func DeviceLogin(c *gin.Context) {
+	cfg := config.GetConfig()
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到设备登录请求")
+	}
+
+	var request DeviceLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	signer, err := service.GetTokenSigner()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	device, err := service.NewDeviceLoginService(repoFactory).Login(
+		request.DeviceID, request.PassWD, request.HardwareFingerprint, c.ClientIP())
+	if err != nil {
+		var lockedErr *service.DeviceLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			c.JSON(http.StatusLocked, gin.H{
+				"error":        lockedErr.Error(),
+				"locked_until": lockedErr.Until.Format("2006-01-02T15:04:05Z07:00"),
+			})
+		case errors.Is(err, service.ErrDeviceDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidCredentials),
+			errors.Is(err, service.ErrFingerprintMismatch),
+			errors.Is(err, service.ErrDeviceFingerprintRequired):
+			// 不向调用方区分具体原因，避免泄露口令是否正确
+			if cfg.DebugLevel == "true" {
+				log.Printf("设备 %d 登录失败: %v\n", request.DeviceID, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "设备ID、口令或硬件指纹错误"})
+		default:
+			log.Printf("设备登录校验失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "登录校验失败"})
+		}
+		return
+	}
+
+	token, expiresIn, err := service.NewTokenService(repoFactory, signer).IssueDeviceToken(device)
+	if err != nil {
+		log.Printf("签发设备令牌失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签发令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "设备登录成功",
+		"data": gin.H{
+			"device_id":     device.DeviceID,
+			"device_name":   device.DeviceName,
+			"device_type":   device.DeviceType,
+			"device_status": device.DeviceStatus,
+			"access_token":  token,
+			"token_type":    "Bearer",
+			"expires_in":    expiresIn,
+		},
+	})
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"gin-server/auth/middleware"
+	"gin-server/auth/service"
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database"
+	"gin-server/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginRequest 用户登录请求
+type LoginRequest struct {
+	UserName string `json:"user_name" binding:"required"` // 用户名
+	PassWD   string `json:"pass_wd" binding:"required"`   // 口令
+}
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"` // 刷新令牌
+}
+
+// LogoutRequest 注销请求
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // 刷新令牌，提供时一并吊销
+}
+
+// Login 处理用户登录请求，校验口令后签发访问令牌和刷新令牌
+func Login(c *gin.Context) {
+	cfg := config.GetConfig()
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到用户登录请求")
+	}
+
+	var request LoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	signer, err := service.GetTokenSigner()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	credentialService := service.NewCredentialService(repoFactory)
+
+	user, err := credentialService.VerifyUser(request.UserName, request.PassWD)
+	if err != nil {
+		var frozenErr *service.UserFrozenError
+		switch {
+		case errors.As(err, &frozenErr):
+			response := gin.H{"error": frozenErr.Error()}
+			if frozenErr.Until != nil {
+				response["frozen_until"] = frozenErr.Until.Format("2006-01-02T15:04:05Z07:00")
+			}
+			c.JSON(http.StatusLocked, response)
+		case errors.Is(err, service.ErrUserCancelled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			log.Printf("用户登录校验失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "登录校验失败"})
+		}
+		return
+	}
+
+	tokens, err := service.NewTokenService(repoFactory, signer).IssueTokenPair(user)
+	if err != nil {
+		log.Printf("签发令牌失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签发令牌失败"})
+		return
+	}
+
+	// 记录登录时间和IP
+	if err := repoFactory.GetUserRepository().UpdateLastLogin(user.ID, c.ClientIP()); err != nil {
+		log.Printf("更新用户最后登录信息失败: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录成功",
+		"data": gin.H{
+			"user_id":            user.UserID,
+			"user_name":          user.Username,
+			"user_type":          user.UserType,
+			"permission_mask":    user.PermissionMask,
+			"access_token":       tokens.AccessToken,
+			"refresh_token":      tokens.RefreshToken,
+			"token_type":         tokens.TokenType,
+			"expires_in":         tokens.ExpiresIn,
+			"refresh_expires_in": tokens.RefreshExpiresIn,
+		},
+	})
+}
+
+// RefreshToken 处理刷新令牌请求，旧的刷新令牌使用后即失效
+func RefreshToken(c *gin.Context) {
+	cfg := config.GetConfig()
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到刷新令牌请求")
+	}
+
+	var request RefreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	signer, err := service.GetTokenSigner()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+
+	tokens, err := service.NewTokenService(repositories.NewRepositoryFactory(db), signer).Refresh(request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, crypto.ErrTokenExpired) ||
+			errors.Is(err, service.ErrInvalidToken) ||
+			errors.Is(err, service.ErrTokenRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("刷新令牌失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刷新令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "令牌刷新成功",
+		"data":    tokens,
+	})
+}
+
+// Logout 处理注销请求，吊销当前访问令牌及可选的刷新令牌
+func Logout(c *gin.Context) {
+	cfg := config.GetConfig()
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到注销请求")
+	}
+
+	var request LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	signer, err := service.GetTokenSigner()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	tokenService := service.NewTokenService(repositories.NewRepositoryFactory(db), signer)
+
+	// 客户端证书认证的设备没有令牌可吊销
+	if claims, ok := middleware.GetClaims(c); ok && claims.ID != "" {
+		if err := tokenService.Revoke(claims); err != nil {
+			log.Printf("吊销访问令牌失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "注销失败"})
+			return
+		}
+	}
+
+	if request.RefreshToken != "" {
+		if err := tokenService.RevokeToken(request.RefreshToken); err != nil {
+			if errors.Is(err, service.ErrInvalidToken) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "刷新令牌无效"})
+				return
+			}
+			log.Printf("吊销刷新令牌失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "注销失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "注销成功",
+	})
+}
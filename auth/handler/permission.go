@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+
+	"gin-server/auth/permission"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPermissions 返回权限位定义
+func GetPermissions(c *gin.Context) {
+	perms := make([]gin.H, 0, len(permission.All()))
+	for _, p := range permission.All() {
+		perms = append(perms, gin.H{
+			"bit":         uint(p),
+			"name":        p.String(),
+			"description": p.Description(),
+			"mask":        permission.NewMask(p).String(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取权限定义成功",
+		"data":    perms,
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gin-server/auth/middleware"
+	"gin-server/auth/service"
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnlockUser 处理管理员解冻用户请求，路径参数为用户唯一标识(user_id)
+// 解冻后用户状态恢复为离线，非法登录次数清零
+func UnlockUser(c *gin.Context) {
+	cfg := config.GetConfig()
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到解冻用户请求")
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	userRepo := repoFactory.GetUserRepository()
+
+	user, err := userRepo.FindByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	actor := ""
+	if claims, ok := middleware.GetClaims(c); ok {
+		actor = claims.Name
+	}
+	if err := service.NewLockoutService(repoFactory).Unlock(user, actor); err != nil {
+		if errors.Is(err, service.ErrUserCancelled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("解冻用户失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解冻用户失败"})
+		return
+	}
+
+	user, err = userRepo.FindByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户已解冻",
+		"data": gin.H{
+			"user_id":             user.UserID,
+			"user_name":           user.Username,
+			"status":              user.Status,
+			"illegal_login_times": user.IllegalLoginTimes,
+		},
+	})
+}
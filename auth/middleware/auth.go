@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gin-server/auth/service"
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database"
+	"gin-server/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyClaims 认证通过后令牌声明在gin上下文中的键名
+const ContextKeyClaims = "auth_claims"
+
+// TokenAuth 令牌认证中间件
+// 除免认证路由外，所有请求都必须携带有效的访问令牌或设备会话令牌: Authorization: Bearer <token>
+// 已由ClientCertAuth识别的设备直接放行
+func TokenAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.GetConfig()
+		if !cfg.Auth.Enabled || IsPublicRoute(cfg.Auth.PublicRoutes, c.Request.Method, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		// 已通过客户端证书认证的设备无需再携带令牌
+		if _, ok := GetDevice(c); ok {
+			c.Next()
+			return
+		}
+
+		token := bearerToken(c.GetHeader("Authorization"))
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "缺少访问令牌"})
+			return
+		}
+
+		signer, err := service.GetTokenSigner()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		db, err := database.GetDB()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+			return
+		}
+
+		tokenService := service.NewTokenService(repositories.NewRepositoryFactory(db), signer)
+		claims, err := tokenService.ValidateToken(token, crypto.TokenTypeAccess, crypto.TokenTypeDevice)
+		if err != nil {
+			switch {
+			case errors.Is(err, crypto.ErrTokenExpired),
+				errors.Is(err, service.ErrInvalidToken),
+				errors.Is(err, service.ErrTokenRevoked):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrUserDisabled),
+				errors.Is(err, service.ErrDeviceDisabled):
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			default:
+				log.Printf("校验访问令牌失败: %v\n", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "校验访问令牌失败"})
+			}
+			return
+		}
+
+		if cfg.DebugLevel == "true" {
+			log.Printf("令牌认证通过: %s %s, 路径 %s\n", claims.Type, claims.Name, c.Request.URL.Path)
+		}
+
+		c.Set(ContextKeyClaims, claims)
+		c.Next()
+	}
+}
+
+// GetClaims 获取当前请求的令牌声明
+func GetClaims(c *gin.Context) (*crypto.TokenClaims, bool) {
+	value, exists := c.Get(ContextKeyClaims)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*crypto.TokenClaims)
+	return claims, ok
+}
+
+// GetDeviceID 获取当前请求的设备ID，仅在使用设备会话令牌时存在
+func GetDeviceID(c *gin.Context) (int, bool) {
+	claims, ok := GetClaims(c)
+	if !ok || claims.Type != crypto.TokenTypeDevice {
+		return 0, false
+	}
+	deviceID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, false
+	}
+	return deviceID, true
+}
+
+// IsPublicRoute 判断请求是否匹配免认证路由
+// 路由格式: "/path" 或 "METHOD /path"，以 "*" 结尾表示前缀匹配
+func IsPublicRoute(routes []string, method, path string) bool {
+	for _, route := range routes {
+		routeMethod := ""
+		routePath := route
+		if fields := strings.Fields(route); len(fields) == 2 {
+			routeMethod, routePath = fields[0], fields[1]
+		}
+
+		if routeMethod != "" && !strings.EqualFold(routeMethod, method) {
+			continue
+		}
+
+		if strings.HasSuffix(routePath, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(routePath, "*")) {
+				return true
+			}
+			continue
+		}
+		if path == routePath {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken 从Authorization头中提取令牌
+func bearerToken(header string) string {
+	const prefix = "Bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gin-server/auth/service"
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyDevice 客户端证书认证通过后设备信息在gin上下文中的键名
+const ContextKeyDevice = "auth_device"
+
+// ClientCertAuth 客户端证书认证中间件
+// HTTPS请求携带客户端证书时，按certs表中设备绑定的证书识别设备，
+// 识别成功后等同于持有该设备的会话令牌；未携带证书的请求交由令牌认证处理
+func ClientCertAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+			c.Next()
+			return
+		}
+
+		cfg := config.GetConfig()
+		db, err := database.GetDB()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+			return
+		}
+
+		leaf := c.Request.TLS.PeerCertificates[0]
+		device, err := service.NewDeviceCertService(repositories.NewRepositoryFactory(db)).ResolveDevice(leaf)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrCertNotBound),
+				errors.Is(err, service.ErrCertNotValid),
+				errors.Is(err, service.ErrCertRevoked),
+				errors.Is(err, service.ErrCertInactive),
+				errors.Is(err, service.ErrDeviceNotExists),
+				errors.Is(err, service.ErrDeviceDisabled):
+				if cfg.DebugLevel == "true" {
+					log.Printf("客户端证书认证失败: %v, 证书主题: %s\n", err, leaf.Subject.String())
+				}
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			default:
+				log.Printf("客户端证书认证失败: %v\n", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "客户端证书认证失败"})
+			}
+			return
+		}
+
+		if cfg.DebugLevel == "true" {
+			log.Printf("客户端证书认证通过: 设备 %d, 路径 %s\n", device.DeviceID, c.Request.URL.Path)
+		}
+
+		c.Set(ContextKeyDevice, device)
+		c.Set(ContextKeyClaims, &crypto.TokenClaims{
+			Subject: strconv.Itoa(device.DeviceID),
+			Type:    crypto.TokenTypeDevice,
+			Name:    device.DeviceName,
+		})
+		c.Next()
+	}
+}
+
+// GetDevice 获取通过客户端证书认证的设备
+func GetDevice(c *gin.Context) (*models.Device, bool) {
+	value, exists := c.Get(ContextKeyDevice)
+	if !exists {
+		return nil, false
+	}
+	device, ok := value.(*models.Device)
+	return device, ok
+}
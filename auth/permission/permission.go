@@ -0,0 +1,131 @@
+package permission
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission 权限位
+// 权限位掩码以二进制字符串保存在 users.permission_mask 中，最右边一位为第0位，
+// 例如 "0000000000000101" 表示拥有第0位（用户管理）和第2位（证书管理）权限
+type Permission uint
+
+// 权限位定义
+const (
+	UserManage     Permission = 0 // 用户注册、查询和修改
+	DeviceManage   Permission = 1 // 设备注册、查询和修改
+	CertManage     Permission = 2 // 证书和密钥绑定、证书信息查询
+	AuthRecordRead Permission = 3 // 查询认证记录
+	LogGenerate    Permission = 4 // 手动触发日志生成
+	LogRead        Permission = 5 // 查询日志文件、事件和用户行为
+	EventWrite     Permission = 6 // 上报事件和用户行为
+	SystemAdmin    Permission = 7 // 系统管理员，隐含除私钥导出外的所有权限
+	KeyExport      Permission = 8 // 导出已绑定的私钥，每次导出都会记录审计日志，必须显式授予
+)
+
+// MaskLength 权限位掩码的长度
+// 旧的8位掩码仍可解析，高位视为0
+const MaskLength = 16
+
+// definition 权限位说明
+type definition struct {
+	name        string
+	description string
+}
+
+var definitions = map[Permission]definition{
+	UserManage:     {"user_manage", "用户管理"},
+	DeviceManage:   {"device_manage", "设备管理"},
+	CertManage:     {"cert_manage", "证书管理"},
+	AuthRecordRead: {"auth_record_read", "认证记录查询"},
+	LogGenerate:    {"log_generate", "日志生成"},
+	LogRead:        {"log_read", "日志查询"},
+	EventWrite:     {"event_write", "事件上报"},
+	SystemAdmin:    {"system_admin", "系统管理"},
+	KeyExport:      {"key_export", "私钥导出"},
+}
+
+// All 返回所有已定义的权限位，按位序排列
+func All() []Permission {
+	perms := make([]Permission, 0, len(definitions))
+	for p := Permission(0); p < MaskLength; p++ {
+		if _, ok := definitions[p]; ok {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// String 返回权限名称
+func (p Permission) String() string {
+	if d, ok := definitions[p]; ok {
+		return d.name
+	}
+	return fmt.Sprintf("bit_%d", uint(p))
+}
+
+// Description 返回权限的中文说明
+func (p Permission) Description() string {
+	if d, ok := definitions[p]; ok {
+		return d.description
+	}
+	return fmt.Sprintf("未定义权限位%d", uint(p))
+}
+
+// Mask 权限位掩码
+type Mask uint64
+
+// ParseMask 解析二进制字符串形式的权限位掩码
+// 空字符串表示没有任何权限
+func ParseMask(s string) (Mask, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > MaskLength {
+		return 0, fmt.Errorf("权限位掩码长度不能超过%d位: %s", MaskLength, s)
+	}
+
+	var mask Mask
+	for _, ch := range s {
+		mask <<= 1
+		switch ch {
+		case '1':
+			mask |= 1
+		case '0':
+		default:
+			return 0, fmt.Errorf("权限位掩码只能包含0和1: %s", s)
+		}
+	}
+	return mask, nil
+}
+
+// NewMask 使用权限位创建掩码
+func NewMask(perms ...Permission) Mask {
+	var mask Mask
+	for _, p := range perms {
+		mask |= 1 << p
+	}
+	return mask
+}
+
+// Has 判断是否拥有指定权限，系统管理员拥有除私钥导出外的所有权限
+func (m Mask) Has(p Permission) bool {
+	if p != KeyExport && m&(1<<SystemAdmin) != 0 {
+		return true
+	}
+	return m&(1<<p) != 0
+}
+
+// Missing 返回缺少的权限
+func (m Mask) Missing(perms ...Permission) []Permission {
+	var missing []Permission
+	for _, p := range perms {
+		if !m.Has(p) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// String 返回定长的二进制字符串
+func (m Mask) String() string {
+	return fmt.Sprintf("%0*b", MaskLength, uint64(m))
+}
@@ -0,0 +1,79 @@
+package permission
+
+import (
+	"testing"
+)
+
+func TestParseMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Mask
+		wantErr bool
+	}{
+		{name: "空掩码", input: "", want: 0},
+		{name: "最低位", input: "00000001", want: NewMask(UserManage)},
+		{name: "多个权限位", input: "00100110", want: NewMask(DeviceManage, CertManage, LogRead)},
+		{name: "短掩码", input: "101", want: NewMask(UserManage, CertManage)},
+		{name: "非法字符", input: "0000000x", wantErr: true},
+		{name: "旧的8位掩码", input: "10000000", want: NewMask(SystemAdmin)},
+		{name: "16位掩码", input: "0000000100000100", want: NewMask(CertManage, KeyExport)},
+		{name: "超长掩码", input: "00000000000000001", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMask(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMask(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseMask(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskHas(t *testing.T) {
+	mask := NewMask(DeviceManage, LogRead)
+
+	if !mask.Has(DeviceManage) || !mask.Has(LogRead) {
+		t.Error("应拥有设备管理和日志查询权限")
+	}
+	if mask.Has(CertManage) {
+		t.Error("不应拥有证书管理权限")
+	}
+
+	missing := mask.Missing(DeviceManage, CertManage, LogGenerate)
+	if len(missing) != 2 || missing[0] != CertManage || missing[1] != LogGenerate {
+		t.Errorf("Missing() = %v, want [cert_manage log_generate]", missing)
+	}
+
+	admin := NewMask(SystemAdmin)
+	for _, p := range All() {
+		if p == KeyExport {
+			continue
+		}
+		if !admin.Has(p) {
+			t.Errorf("系统管理员应拥有权限 %s", p)
+		}
+	}
+
+	// 私钥导出必须显式授予
+	if admin.Has(KeyExport) {
+		t.Error("系统管理员不应隐含私钥导出权限")
+	}
+	if !NewMask(SystemAdmin, KeyExport).Has(KeyExport) {
+		t.Error("显式授予私钥导出权限的系统管理员应拥有该权限")
+	}
+}
+
+func TestMaskString(t *testing.T) {
+	mask, err := ParseMask("101")
+	if err != nil {
+		t.Fatalf("ParseMask() error = %v", err)
+	}
+	if mask.String() != "0000000000000101" {
+		t.Errorf("String() = %s, want 0000000000000101", mask.String())
+	}
+}
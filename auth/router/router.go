@@ -2,6 +2,8 @@ package router
 
 import (
 	"gin-server/auth/handler" // 导入处理器包
+	"gin-server/auth/middleware"
+	"gin-server/auth/permission"
 	"gin-server/config"
 	"log"
 
@@ -23,6 +25,20 @@ func SetupRouter(r *gin.Engine) {
 		})
 	}
 
+	// 登录、刷新令牌和注销接口
+	authGroup.POST("/login", handler.Login)
+	authGroup.POST("/refresh", handler.RefreshToken)
+	authGroup.POST("/logout", handler.Logout)
+
+	// 设备登录接口
+	authGroup.POST("/devices/login", handler.DeviceLogin)
+
+	// 用户解冻接口
+	authGroup.POST("/users/:id/unlock", middleware.RequirePermission(permission.UserManage), handler.UnlockUser)
+
 	// 认证记录查询接口
-	authGroup.GET("/records", handler.GetAuthRecords)
+	authGroup.GET("/records", middleware.RequirePermission(permission.AuthRecordRead), handler.GetAuthRecords)
+
+	// 权限位定义查询接口
+	authGroup.GET("/permissions", handler.GetPermissions)
 }
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// EnsureBootstrapAdmin 确保初始管理员存在
+// 启用令牌认证后所有管理接口都需要登录，初始管理员用于首次登录
+func EnsureBootstrapAdmin(repoFactory repositories.RepositoryFactory, cfg *config.Config) error {
+	name := cfg.Auth.BootstrapAdminName
+	password := cfg.Auth.BootstrapAdminPassword
+	if name == "" {
+		return nil
+	}
+	if password == "" {
+		return errors.New("未配置初始管理员口令")
+	}
+
+	userRepo := repoFactory.GetUserRepository()
+	if _, err := userRepo.FindByUsername(name); err == nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("初始管理员 %s 已存在\n", name)
+		}
+		return nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("查询初始管理员失败: %w", err)
+	}
+
+	// 分配一个未被占用的用户唯一标识
+	users, err := userRepo.FindAll()
+	if err != nil {
+		return fmt.Errorf("查询用户列表失败: %w", err)
+	}
+	userID := 1
+	for _, user := range users {
+		if user.UserID >= userID {
+			userID = user.UserID + 1
+		}
+	}
+
+	passwordHash, err := crypto.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("计算初始管理员口令哈希失败: %w", err)
+	}
+
+	admin := &models.User{
+		Username:       name,
+		Password:       passwordHash,
+		UserID:         userID,
+		UserType:       1,
+		PermissionMask: "11111111",
+	}
+	if err := userRepo.Create(admin); err != nil {
+		return fmt.Errorf("创建初始管理员失败: %w", err)
+	}
+
+	log.Printf("已创建初始管理员 %s (user_id=%d)\n", name, userID)
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCredentials 用户名或口令错误
+// 用户不存在与口令错误返回同一个错误，避免泄露账号是否存在
+var ErrInvalidCredentials = errors.New("用户名或口令错误")
+
+// CredentialService 凭据校验服务接口
+type CredentialService interface {
+	// VerifyUser 校验用户名和口令，成功时返回用户信息
+	// 口令错误计入非法登录次数，达到阈值后冻结用户
+	VerifyUser(username, password string) (*models.User, error)
+
+	// VerifyDevice 校验设备ID和口令，成功时返回设备信息
+	VerifyDevice(deviceID int, password string) (*models.Device, error)
+}
+
+// credentialService 凭据校验服务实现
+type credentialService struct {
+	repoFactory repositories.RepositoryFactory
+	hasher      *crypto.PasswordHasher
+	lockout     LockoutService
+}
+
+// NewCredentialService 创建凭据校验服务实例
+func NewCredentialService(repoFactory repositories.RepositoryFactory) CredentialService {
+	return &credentialService{
+		repoFactory: repoFactory,
+		hasher:      crypto.DefaultPasswordHasher(),
+		lockout:     NewLockoutService(repoFactory),
+	}
+}
+
+// VerifyUser 校验用户名和口令
+// 口令哈希参数与当前配置不一致时（包括历史明文口令），校验通过后自动重新哈希
+func (s *credentialService) VerifyUser(username, password string) (*models.User, error) {
+	userRepo := s.repoFactory.GetUserRepository()
+
+	user, err := userRepo.FindByUsername(username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 对不存在的用户同样计算一次哈希，使响应时间与口令错误时一致
+			s.hasher.Hash(password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("查询用户失败: %w", err)
+	}
+
+	// 冻结或注销的用户不再校验口令
+	if err := s.lockout.CheckStatus(user); err != nil {
+		return nil, err
+	}
+
+	match, needsRehash, err := s.hasher.Verify(password, user.Password)
+	if err != nil {
+		return nil, fmt.Errorf("校验用户口令失败: %w", err)
+	}
+	if !match {
+		if err := s.lockout.RecordFailure(user, "接口登录"); err != nil {
+			if errors.Is(err, ErrUserFrozen) {
+				return nil, err
+			}
+			log.Printf("记录用户 %s 非法登录失败: %v\n", user.Username, err)
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.lockout.RecordSuccess(user); err != nil {
+		log.Printf("清零用户 %s 非法登录次数失败: %v\n", user.Username, err)
+	}
+
+	if needsRehash {
+		s.rehash(user.ID, password, userRepo.UpdatePassword)
+	}
+
+	return user, nil
+}
+
+// VerifyDevice 校验设备ID和口令
+func (s *credentialService) VerifyDevice(deviceID int, password string) (*models.Device, error) {
+	deviceRepo := s.repoFactory.GetDeviceRepository()
+
+	device, err := deviceRepo.FindByDeviceID(deviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.hasher.Hash(password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+
+	match, needsRehash, err := s.hasher.Verify(password, device.Password)
+	if err != nil {
+		return nil, fmt.Errorf("校验设备口令失败: %w", err)
+	}
+	if !match {
+		return nil, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		s.rehash(device.ID, password, deviceRepo.UpdatePassword)
+	}
+
+	return device, nil
+}
+
+// rehash 使用当前配置重新计算口令哈希并保存
+// 重新哈希失败不影响本次登录，下次登录时会再次尝试
+func (s *credentialService) rehash(id uint, password string, update func(id uint, passwordHash string) error) {
+	cfg := config.GetConfig()
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Printf("重新计算口令哈希失败: %v\n", err)
+		return
+	}
+	if err := update(id, hash); err != nil {
+		log.Printf("保存重新计算的口令哈希失败: %v\n", err)
+		return
+	}
+
+	if cfg.DebugLevel == "true" {
+		log.Printf("记录 %d 的口令哈希已按当前参数更新\n", id)
+	}
+}
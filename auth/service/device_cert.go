@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/store"
+
+	"gorm.io/gorm"
+)
+
+// 设备证书认证错误
+var (
+	ErrCertNotBound    = errors.New("客户端证书未绑定任何设备")
+	ErrCertNotValid    = errors.New("客户端证书不在有效期内")
+	ErrCertRevoked     = errors.New("客户端证书已吊销")
+	ErrCertInactive    = errors.New("客户端证书不是设备当前使用的证书")
+	ErrDeviceDisabled  = errors.New("设备已冻结或注销")
+	ErrDeviceNotExists = errors.New("证书绑定的设备不存在")
+)
+
+// DeviceCertService 设备证书认证服务接口
+type DeviceCertService interface {
+	// ResolveDevice 根据客户端证书查找绑定的设备
+	// 按证书指纹匹配certs表中设备绑定的证书，已吊销的证书认证失败
+	// 只接受当前版本的证书，以及仍在轮换重叠期内的上一版本证书
+	ResolveDevice(cert *x509.Certificate) (*models.Device, error)
+}
+
+// deviceCertService 设备证书认证服务实现
+type deviceCertService struct {
+	repoFactory repositories.RepositoryFactory
+}
+
+// NewDeviceCertService 创建设备证书认证服务实例
+func NewDeviceCertService(repoFactory repositories.RepositoryFactory) DeviceCertService {
+	return &deviceCertService{
+		repoFactory: repoFactory,
+	}
+}
+
+// ResolveDevice 根据客户端证书查找绑定的设备
+func (s *deviceCertService) ResolveDevice(cert *x509.Certificate) (*models.Device, error) {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, ErrCertNotValid
+	}
+
+	fingerprint := pki.Fingerprint(cert)
+	revoked, err := s.repoFactory.GetCertRevocationRepository().IsRevoked(fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("查询证书吊销状态失败: %w", err)
+	}
+	if revoked {
+		return nil, ErrCertRevoked
+	}
+
+	certRepo := s.repoFactory.GetCertRepository()
+	record, err := certRepo.FindByFingerprint("device", fingerprint)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("查询设备证书失败: %w", err)
+		}
+		// 兼容未记录指纹的历史证书，逐个比对证书文件
+		if record, err = s.findByCertFile(certRepo, cert); err != nil {
+			return nil, err
+		}
+	}
+	if !record.Active && (record.OverlapUntil == nil || now.After(*record.OverlapUntil)) {
+		return nil, ErrCertInactive
+	}
+
+	deviceID, err := strconv.Atoi(record.EntityID)
+	if err != nil {
+		return nil, ErrDeviceNotExists
+	}
+	device, err := s.repoFactory.GetDeviceRepository().FindByDeviceID(deviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeviceNotExists
+		}
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+	if device.DeviceStatus == models.DeviceStatusFrozen || device.DeviceStatus == models.DeviceStatusCancelled {
+		return nil, ErrDeviceDisabled
+	}
+	return device, nil
+}
+
+// findByCertFile 在未记录指纹的设备证书中查找与客户端证书一致的记录
+func (s *deviceCertService) findByCertFile(certRepo repositories.CertRepository, cert *x509.Certificate) (*models.Cert, error) {
+	certs, err := certRepo.FindByEntityType("device")
+	if err != nil {
+		return nil, fmt.Errorf("查询设备证书失败: %w", err)
+	}
+
+	for i := range certs {
+		if certs[i].Fingerprint == "" && certs[i].CertPath != "" && certFileMatches(&certs[i], cert) {
+			return &certs[i], nil
+		}
+	}
+	return nil, ErrCertNotBound
+}
+
+// certFileMatches 判断证书记录中的首个证书是否与客户端证书一致
+func certFileMatches(record *models.Cert, cert *x509.Certificate) bool {
+	certStore, err := store.Default()
+	if err != nil {
+		log.Printf("获取证书存储失败: %v\n", err)
+		return false
+	}
+	data, err := certStore.GetCert(record)
+	if err != nil {
+		log.Printf("读取设备证书文件 %s 失败: %v\n", record.CertPath, err)
+		return false
+	}
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return false
+		}
+		if block.Type == "CERTIFICATE" {
+			return bytes.Equal(block.Bytes, cert.Raw)
+		}
+	}
+}
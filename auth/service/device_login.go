@@ -0,0 +1,149 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 设备登录错误
+var (
+	ErrDeviceLocked              = errors.New("设备登录已锁定")
+	ErrFingerprintMismatch       = errors.New("设备硬件指纹不匹配")
+	ErrDeviceFingerprintRequired = errors.New("设备已登记硬件指纹，登录时必须提供")
+)
+
+// DeviceLockedError 设备登录锁定错误，携带锁定截止时间
+type DeviceLockedError struct {
+	Until time.Time
+}
+
+// Error 实现error接口
+func (e *DeviceLockedError) Error() string {
+	return fmt.Sprintf("%s，请于 %s 后重试", ErrDeviceLocked.Error(), e.Until.Format("2006-01-02 15:04:05"))
+}
+
+// Unwrap 支持errors.Is(err, ErrDeviceLocked)
+func (e *DeviceLockedError) Unwrap() error {
+	return ErrDeviceLocked
+}
+
+// DeviceLoginService 设备登录服务接口
+type DeviceLoginService interface {
+	// Login 校验设备口令和硬件指纹，成功后将设备置为在线并记录登录IP
+	// 连续失败达到阈值后锁定设备登录；冻结或注销的设备返回ErrDeviceDisabled
+	Login(deviceID int, password, fingerprint, ip string) (*models.Device, error)
+}
+
+// deviceLoginService 设备登录服务实现
+type deviceLoginService struct {
+	repoFactory repositories.RepositoryFactory
+	hasher      *crypto.PasswordHasher
+	cfg         *config.Config
+}
+
+// NewDeviceLoginService 创建设备登录服务实例
+func NewDeviceLoginService(repoFactory repositories.RepositoryFactory) DeviceLoginService {
+	return &deviceLoginService{
+		repoFactory: repoFactory,
+		hasher:      crypto.DefaultPasswordHasher(),
+		cfg:         config.GetConfig(),
+	}
+}
+
+// Login 设备登录
+func (s *deviceLoginService) Login(deviceID int, password, fingerprint, ip string) (*models.Device, error) {
+	deviceRepo := s.repoFactory.GetDeviceRepository()
+
+	device, err := deviceRepo.FindByDeviceID(deviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.hasher.Hash(password)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+
+	// 冻结或注销的设备不能登录，不计入失败次数
+	if device.DeviceStatus == models.DeviceStatusFrozen || device.DeviceStatus == models.DeviceStatusCancelled {
+		return nil, ErrDeviceDisabled
+	}
+
+	// 锁定期间直接拒绝，不再校验口令
+	if device.LockedUntil != nil && time.Now().Before(*device.LockedUntil) {
+		return nil, &DeviceLockedError{Until: *device.LockedUntil}
+	}
+
+	match, needsRehash, err := s.hasher.Verify(password, device.Password)
+	if err != nil {
+		return nil, fmt.Errorf("校验设备口令失败: %w", err)
+	}
+	if !match {
+		return nil, s.recordFailure(device, ErrInvalidCredentials)
+	}
+
+	// 设备登记了硬件指纹时，登录时提供的指纹必须一致
+	if device.HardwareFingerprint != "" {
+		if fingerprint == "" {
+			return nil, s.recordFailure(device, ErrDeviceFingerprintRequired)
+		}
+		if subtle.ConstantTimeCompare([]byte(fingerprint), []byte(device.HardwareFingerprint)) != 1 {
+			return nil, s.recordFailure(device, ErrFingerprintMismatch)
+		}
+	}
+
+	if needsRehash {
+		if hash, err := s.hasher.Hash(password); err == nil {
+			if err := deviceRepo.UpdatePassword(device.ID, hash); err != nil {
+				log.Printf("保存重新计算的设备口令哈希失败: %v\n", err)
+			}
+		}
+	}
+
+	recorded, err := deviceRepo.RecordLogin(device.ID, ip)
+	if err != nil {
+		return nil, fmt.Errorf("更新设备登录状态失败: %w", err)
+	}
+	if !recorded {
+		return nil, ErrDeviceDisabled
+	}
+
+	if s.cfg.DebugLevel == "true" {
+		log.Printf("设备 %d 登录成功，IP: %s\n", device.DeviceID, ip)
+	}
+
+	return deviceRepo.FindByID(device.ID)
+}
+
+// recordFailure 记录一次登录失败，达到阈值时锁定设备
+func (s *deviceLoginService) recordFailure(device *models.Device, cause error) error {
+	deviceRepo := s.repoFactory.GetDeviceRepository()
+
+	failures, err := deviceRepo.IncrementLoginFailures(device.ID)
+	if err != nil {
+		log.Printf("记录设备 %d 登录失败次数失败: %v\n", device.DeviceID, err)
+		return cause
+	}
+
+	maxFailures := s.cfg.Auth.DeviceMaxLoginFailures
+	if maxFailures > 0 && failures >= maxFailures {
+		until := time.Now().Add(time.Duration(s.cfg.Auth.DeviceLockDuration) * time.Second)
+		if err := deviceRepo.LockLogin(device.ID, until); err != nil {
+			log.Printf("锁定设备 %d 登录失败: %v\n", device.DeviceID, err)
+			return cause
+		}
+		log.Printf("设备 %d 连续登录失败 %d 次，锁定至 %s\n", device.DeviceID, failures, until.Format("2006-01-02 15:04:05"))
+		return &DeviceLockedError{Until: until}
+	}
+
+	return cause
+}
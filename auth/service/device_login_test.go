@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// fakeLoginRepoFactory 只提供设备仓库的仓库工厂
+type fakeLoginRepoFactory struct {
+	repositories.RepositoryFactory
+	deviceRepo *fakeLoginDeviceRepo
+}
+
+func (f *fakeLoginRepoFactory) GetDeviceRepository() repositories.DeviceRepository {
+	return f.deviceRepo
+}
+
+// fakeLoginDeviceRepo 内存中的设备仓库，只实现登录用到的方法
+type fakeLoginDeviceRepo struct {
+	repositories.DeviceRepository
+	device   models.Device
+	recorded bool
+}
+
+func (r *fakeLoginDeviceRepo) FindByDeviceID(deviceID int) (*models.Device, error) {
+	if deviceID != r.device.DeviceID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	device := r.device
+	return &device, nil
+}
+
+func (r *fakeLoginDeviceRepo) FindByID(id uint) (*models.Device, error) {
+	device := r.device
+	return &device, nil
+}
+
+func (r *fakeLoginDeviceRepo) UpdatePassword(id uint, passwordHash string) error {
+	r.device.Password = passwordHash
+	return nil
+}
+
+func (r *fakeLoginDeviceRepo) IncrementLoginFailures(id uint) (int, error) {
+	r.device.LoginFailures++
+	return r.device.LoginFailures, nil
+}
+
+func (r *fakeLoginDeviceRepo) LockLogin(id uint, until time.Time) error {
+	r.device.LoginFailures = 0
+	r.device.LockedUntil = &until
+	return nil
+}
+
+func (r *fakeLoginDeviceRepo) RecordLogin(id uint, ip string) (bool, error) {
+	if r.device.DeviceStatus == models.DeviceStatusFrozen || r.device.DeviceStatus == models.DeviceStatusCancelled {
+		return false, nil
+	}
+	r.recorded = true
+	if r.device.LastLoginTime == nil {
+		r.device.RegisterIP = ip
+	}
+	now := time.Now()
+	r.device.LastLoginTime = &now
+	r.device.DeviceStatus = models.DeviceStatusOnline
+	r.device.LastLoginIP = ip
+	r.device.LoginFailures = 0
+	r.device.LockedUntil = nil
+	return true, nil
+}
+
+// newTestDeviceLogin 创建使用内存设备仓库的登录服务，设备口令为device-pass
+func newTestDeviceLogin(t *testing.T, device models.Device) (*deviceLoginService, *fakeLoginDeviceRepo) {
+	t.Helper()
+	hasher, err := crypto.NewPasswordHasher(config.PasswordConfig{
+		Algorithm:         "argon2id",
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        4,
+	})
+	if err != nil {
+		t.Fatalf("NewPasswordHasher() error = %v", err)
+	}
+	device.Password, err = hasher.Hash("device-pass")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	repo := &fakeLoginDeviceRepo{device: device}
+	return &deviceLoginService{
+		repoFactory: &fakeLoginRepoFactory{deviceRepo: repo},
+		hasher:      hasher,
+		cfg: &config.Config{Auth: config.AuthConfig{
+			DeviceMaxLoginFailures: 3,
+			DeviceLockDuration:     60,
+		}},
+	}, repo
+}
+
+func TestDeviceLoginSuccess(t *testing.T) {
+	s, repo := newTestDeviceLogin(t, models.Device{
+		DeviceID:            1001,
+		DeviceStatus:        models.DeviceStatusOffline,
+		RegisterIP:          "10.0.0.1",
+		HardwareFingerprint: "fp-1",
+		LoginFailures:       2,
+	})
+
+	device, err := s.Login(1001, "device-pass", "fp-1", "10.0.0.9")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if device.DeviceStatus != models.DeviceStatusOnline || device.LoginFailures != 0 {
+		t.Errorf("登录后状态 = %d，失败次数 = %d, want 在线、0", device.DeviceStatus, device.LoginFailures)
+	}
+	if device.LastLoginIP != "10.0.0.9" || device.RegisterIP != "10.0.0.9" {
+		t.Errorf("首次登录后登录IP = %q，注册IP = %q, want 10.0.0.9、10.0.0.9", device.LastLoginIP, device.RegisterIP)
+	}
+	if !repo.recorded {
+		t.Error("登录成功后未记录登录状态")
+	}
+
+	// 之后的登录只更新登录IP
+	device, err = s.Login(1001, "device-pass", "fp-1", "10.0.0.10")
+	if err != nil {
+		t.Fatalf("再次 Login() error = %v", err)
+	}
+	if device.LastLoginIP != "10.0.0.10" || device.RegisterIP != "10.0.0.9" {
+		t.Errorf("再次登录后登录IP = %q，注册IP = %q, want 10.0.0.10、10.0.0.9", device.LastLoginIP, device.RegisterIP)
+	}
+}
+
+func TestDeviceLoginLockout(t *testing.T) {
+	s, repo := newTestDeviceLogin(t, models.Device{DeviceID: 1001, DeviceStatus: models.DeviceStatusOffline})
+
+	for i := 1; i < 3; i++ {
+		if _, err := s.Login(1001, "wrong", "", "10.0.0.9"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("第%d次口令错误 Login() error = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+
+	_, err := s.Login(1001, "wrong", "", "10.0.0.9")
+	var lockedErr *DeviceLockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("达到失败阈值 Login() error = %v, want DeviceLockedError", err)
+	}
+	if repo.device.LockedUntil == nil || repo.device.LoginFailures != 0 {
+		t.Errorf("锁定后 locked_until = %v，失败次数 = %d", repo.device.LockedUntil, repo.device.LoginFailures)
+	}
+
+	// 锁定期间口令正确也拒绝
+	if _, err := s.Login(1001, "device-pass", "", "10.0.0.9"); !errors.Is(err, ErrDeviceLocked) {
+		t.Errorf("锁定期间 Login() error = %v, want ErrDeviceLocked", err)
+	}
+	if repo.recorded {
+		t.Error("锁定期间不应记录登录")
+	}
+}
+
+func TestDeviceLoginFingerprint(t *testing.T) {
+	tests := []struct {
+		name        string
+		fingerprint string
+		want        error
+	}{
+		{"未提供指纹", "", ErrDeviceFingerprintRequired},
+		{"指纹不一致", "fp-2", ErrFingerprintMismatch},
+	}
+	for _, tt := range tests {
+		s, repo := newTestDeviceLogin(t, models.Device{DeviceID: 1001, HardwareFingerprint: "fp-1"})
+		if _, err := s.Login(1001, "device-pass", tt.fingerprint, "10.0.0.9"); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Login() error = %v, want %v", tt.name, err, tt.want)
+		}
+		if repo.device.LoginFailures != 1 {
+			t.Errorf("%s: 失败次数 = %d, want 1", tt.name, repo.device.LoginFailures)
+		}
+	}
+}
+
+func TestDeviceLoginDisabled(t *testing.T) {
+	for _, status := range []int{models.DeviceStatusFrozen, models.DeviceStatusCancelled} {
+		s, repo := newTestDeviceLogin(t, models.Device{DeviceID: 1001, DeviceStatus: status})
+		if _, err := s.Login(1001, "device-pass", "", "10.0.0.9"); !errors.Is(err, ErrDeviceDisabled) {
+			t.Errorf("状态%d的设备 Login() error = %v, want ErrDeviceDisabled", status, err)
+		}
+		if repo.recorded || repo.device.DeviceStatus != status {
+			t.Errorf("状态%d的设备登录后状态被改为 %d", status, repo.device.DeviceStatus)
+		}
+		if repo.device.LoginFailures != 0 {
+			t.Errorf("状态%d的设备登录被计为失败", status)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/alert"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	registService "gin-server/regist/service"
+)
+
+// 用户登录状态错误
+var (
+	ErrUserFrozen    = errors.New("用户已冻结")
+	ErrUserCancelled = errors.New("用户已注销")
+)
+
+// UserFrozenError 用户冻结错误，携带自动解冻时间
+type UserFrozenError struct {
+	Until *time.Time // 自动解冻时间，为空表示只能由管理员解冻
+}
+
+// Error 实现error接口
+func (e *UserFrozenError) Error() string {
+	if e.Until == nil {
+		return fmt.Sprintf("%s，请联系管理员解冻", ErrUserFrozen.Error())
+	}
+	return fmt.Sprintf("%s，将于 %s 自动解冻", ErrUserFrozen.Error(), e.Until.Format("2006-01-02 15:04:05"))
+}
+
+// Unwrap 支持errors.Is(err, ErrUserFrozen)
+func (e *UserFrozenError) Unwrap() error {
+	return ErrUserFrozen
+}
+
+// LockoutService 用户登录锁定服务接口
+// 非法登录次数记录在users.illegal_login_times，达到阈值后将用户置为冻结状态
+type LockoutService interface {
+	// CheckStatus 检查用户是否允许登录，冻结已到期的用户会被自动解冻
+	CheckStatus(user *models.User) error
+
+	// RecordFailure 记录一次非法登录，达到阈值时冻结用户并返回冻结错误
+	RecordFailure(user *models.User, source string) error
+
+	// RecordSuccess 登录成功后清零非法登录次数
+	RecordSuccess(user *models.User) error
+
+	// Unlock 管理员解冻用户，actor为操作人用户名
+	Unlock(user *models.User, actor string) error
+
+	// UnfreezeExpired 解冻所有冻结已到期的用户，返回解冻数量
+	UnfreezeExpired() (int, error)
+}
+
+// lockoutService 用户登录锁定服务实现
+type lockoutService struct {
+	repoFactory repositories.RepositoryFactory
+	alerter     alert.Alerter
+	cfg         *config.Config
+	now         func() time.Time
+}
+
+// NewLockoutService 创建用户登录锁定服务实例
+func NewLockoutService(repoFactory repositories.RepositoryFactory) LockoutService {
+	return &lockoutService{
+		repoFactory: repoFactory,
+		alerter:     alert.GetDefaultAlerter(),
+		cfg:         config.GetConfig(),
+		now:         time.Now,
+	}
+}
+
+// CheckStatus 检查用户是否允许登录
+func (s *lockoutService) CheckStatus(user *models.User) error {
+	switch {
+	case user.StatusIs(models.UserStatusCancelled):
+		return ErrUserCancelled
+	case user.StatusIs(models.UserStatusFrozen):
+		until := s.unfreezeTime(user)
+		if until == nil || s.now().Before(*until) {
+			return &UserFrozenError{Until: until}
+		}
+		// 冻结已到期，登录前自动解冻
+		if _, err := registService.TransitionUser(s.repoFactory, user, registService.UserTransition{
+			To:     models.UserStatusOffline,
+			Reason: "冻结到期自动解冻",
+		}); err != nil {
+			return fmt.Errorf("自动解冻用户失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次非法登录
+func (s *lockoutService) RecordFailure(user *models.User, source string) error {
+	userRepo := s.repoFactory.GetUserRepository()
+
+	failures, err := userRepo.IncrementIllegalLoginTimes(user.ID)
+	if err != nil {
+		return fmt.Errorf("记录非法登录次数失败: %w", err)
+	}
+	user.IllegalLoginTimes = &failures
+
+	if s.cfg.DebugLevel == "true" {
+		log.Printf("用户 %s 非法登录（%s），累计 %d 次\n", user.Username, source, failures)
+	}
+
+	maxFailures := s.cfg.Auth.UserMaxLoginFailures
+	if maxFailures <= 0 || failures < maxFailures ||
+		!registService.CanTransitionUser(registService.CurrentUserStatus(user), models.UserStatusFrozen) {
+		return nil
+	}
+
+	frozenAt := s.now()
+	if _, err := registService.TransitionUser(s.repoFactory, user, registService.UserTransition{
+		To:       models.UserStatusFrozen,
+		Reason:   fmt.Sprintf("连续非法登录 %d 次（%s）", failures, source),
+		At:       frozenAt,
+		Expiring: true,
+	}); err != nil {
+		return fmt.Errorf("冻结用户失败: %w", err)
+	}
+
+	message := fmt.Sprintf("用户 %s(%d) 连续非法登录 %d 次（%s），已冻结", user.Username, user.UserID, failures, source)
+	s.alerter.Alert(&alert.Alert{
+		Level:     alert.AlertLevelWarning,
+		Type:      alert.AlertTypeAccountFreeze,
+		Message:   message,
+		Module:    "auth",
+		Timestamp: frozenAt,
+	})
+
+	return &UserFrozenError{Until: s.unfreezeTime(user)}
+}
+
+// RecordSuccess 登录成功后清零非法登录次数
+func (s *lockoutService) RecordSuccess(user *models.User) error {
+	if user.IllegalLoginTimes == nil || *user.IllegalLoginTimes == 0 {
+		return nil
+	}
+	if err := s.repoFactory.GetUserRepository().ResetIllegalLoginTimes(user.ID); err != nil {
+		return fmt.Errorf("清零非法登录次数失败: %w", err)
+	}
+	zero := 0
+	user.IllegalLoginTimes = &zero
+	return nil
+}
+
+// Unlock 管理员解冻用户
+// 未冻结的用户只清零非法登录次数
+func (s *lockoutService) Unlock(user *models.User, actor string) error {
+	switch {
+	case user.StatusIs(models.UserStatusCancelled):
+		return ErrUserCancelled
+	case user.StatusIs(models.UserStatusFrozen):
+		if _, err := registService.TransitionUser(s.repoFactory, user, registService.UserTransition{
+			To:     models.UserStatusOffline,
+			Reason: "管理员解冻",
+			Actor:  actor,
+		}); err != nil {
+			return fmt.Errorf("解冻用户失败: %w", err)
+		}
+	default:
+		if err := s.repoFactory.GetUserRepository().ResetIllegalLoginTimes(user.ID); err != nil {
+			return fmt.Errorf("清零非法登录次数失败: %w", err)
+		}
+	}
+	log.Printf("用户 %s 已由管理员解冻\n", user.Username)
+	return nil
+}
+
+// UnfreezeExpired 解冻所有冻结已到期的用户
+func (s *lockoutService) UnfreezeExpired() (int, error) {
+	duration := s.cfg.Auth.UserFreezeDuration
+	if duration <= 0 {
+		return 0, nil
+	}
+
+	userRepo := s.repoFactory.GetUserRepository()
+	users, err := userRepo.FindFrozenBefore(s.now().Add(-time.Duration(duration) * time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("查询冻结到期用户失败: %w", err)
+	}
+
+	count := 0
+	for i := range users {
+		user := &users[i]
+		if _, err := registService.TransitionUser(s.repoFactory, user, registService.UserTransition{
+			To:     models.UserStatusOffline,
+			Reason: "冻结到期自动解冻",
+		}); err != nil {
+			log.Printf("自动解冻用户 %s 失败: %v\n", user.Username, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// unfreezeTime 计算用户的自动解冻时间
+func (s *lockoutService) unfreezeTime(user *models.User) *time.Time {
+	duration := s.cfg.Auth.UserFreezeDuration
+	if duration <= 0 || user.FrozenAt == nil {
+		return nil
+	}
+	until := user.FrozenAt.Add(time.Duration(duration) * time.Second)
+	return &until
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Radius认证结果
+const (
+	RadiusReplyAccept = "Access-Accept"
+	RadiusReplyReject = "Access-Reject"
+)
+
+// radiusScanBatchSize 每次读取的认证记录数量
+const radiusScanBatchSize = 500
+
+// radiusScanCursor 扫描进度在scan_cursors表中的名称
+const radiusScanCursor = "radpostauth"
+
+// RadiusScanner Radius认证记录扫描器
+// 定期读取radpostauth中新增的认证记录，Access-Reject计入对应用户的非法登录次数，
+// Access-Accept清零非法登录次数，同时负责解冻冻结到期的用户
+type RadiusScanner struct {
+	radiusRepo repositories.RadiusAuthRepository
+	userRepo   repositories.UserRepository
+	cursorRepo repositories.ScanCursorRepository
+	lockout    LockoutService
+	cfg        *config.Config
+	lastID     int
+	stopChan   chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewRadiusScanner 创建Radius认证记录扫描器
+func NewRadiusScanner(repoFactory repositories.RepositoryFactory, radiusDB *gorm.DB) *RadiusScanner {
+	return &RadiusScanner{
+		radiusRepo: repositories.NewRadiusAuthRepository(radiusDB),
+		userRepo:   repoFactory.GetUserRepository(),
+		cursorRepo: repoFactory.GetScanCursorRepository(),
+		lockout:    NewLockoutService(repoFactory),
+		cfg:        config.GetConfig(),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start 启动扫描器
+// 从上次处理到的记录继续扫描，停机期间新增的认证记录同样计数；
+// 首次启动时只处理启动之后新增的认证记录，历史记录不再重复计数
+func (s *RadiusScanner) Start() error {
+	lastID, err := s.startID()
+	if err != nil {
+		return err
+	}
+	s.lastID = lastID
+
+	interval := time.Duration(s.cfg.Auth.RadiusScanInterval) * time.Second
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+				s.Scan()
+			}
+		}
+	}()
+
+	if s.cfg.DebugLevel == "true" {
+		log.Printf("Radius认证记录扫描器已启动，起始记录ID: %d，扫描间隔: %v\n", lastID, interval)
+	}
+	return nil
+}
+
+// startID 返回扫描的起始记录ID
+func (s *RadiusScanner) startID() (int, error) {
+	maxID, err := s.radiusRepo.MaxID()
+	if err != nil {
+		return 0, err
+	}
+	position, found, err := s.cursorRepo.Get(radiusScanCursor)
+	if err != nil {
+		return 0, err
+	}
+	// 首次启动，或radpostauth表被清空重建后记录ID重新开始
+	if !found || position > int64(maxID) {
+		if err := s.cursorRepo.Save(radiusScanCursor, int64(maxID)); err != nil {
+			return 0, err
+		}
+		return maxID, nil
+	}
+	return int(position), nil
+}
+
+// Stop 停止扫描器
+func (s *RadiusScanner) Stop() {
+	close(s.stopChan)
+	s.wg.Wait()
+}
+
+// Scan 执行一次扫描
+func (s *RadiusScanner) Scan() {
+	if _, err := s.lockout.UnfreezeExpired(); err != nil {
+		log.Printf("解冻到期用户失败: %v\n", err)
+	}
+
+	for {
+		records, err := s.radiusRepo.FindAfterID(s.lastID, radiusScanBatchSize)
+		if err != nil {
+			log.Printf("读取Radius认证记录失败: %v\n", err)
+			return
+		}
+
+		for _, record := range records {
+			s.lastID = record.ID
+			if record.Reply != RadiusReplyReject && record.Reply != RadiusReplyAccept {
+				continue
+			}
+
+			user, err := s.userRepo.FindByUsername(record.Username)
+			if err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					log.Printf("查询用户 %s 失败: %v\n", record.Username, err)
+				}
+				continue
+			}
+
+			if record.Reply == RadiusReplyAccept {
+				if err := s.lockout.RecordSuccess(user); err != nil {
+					log.Printf("清零用户 %s 非法登录次数失败: %v\n", user.Username, err)
+				}
+				continue
+			}
+
+			if err := s.lockout.RecordFailure(user, "Radius认证"); err != nil && !errors.Is(err, ErrUserFrozen) {
+				log.Printf("记录用户 %s 非法登录失败: %v\n", user.Username, err)
+			}
+		}
+
+		if len(records) > 0 {
+			if err := s.cursorRepo.Save(radiusScanCursor, int64(s.lastID)); err != nil {
+				log.Printf("保存Radius认证记录扫描进度失败: %v\n", err)
+			}
+		}
+		if len(records) < radiusScanBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+
+	"gin-server/database/repositories"
+)
+
+// fakeRadiusAuthRepo 只提供最大记录ID的Radius认证仓库
+type fakeRadiusAuthRepo struct {
+	repositories.RadiusAuthRepository
+	maxID int
+}
+
+func (r *fakeRadiusAuthRepo) MaxID() (int, error) {
+	return r.maxID, nil
+}
+
+// fakeScanCursorRepo 内存中的扫描进度仓库
+type fakeScanCursorRepo struct {
+	repositories.ScanCursorRepository
+	positions map[string]int64
+}
+
+func (r *fakeScanCursorRepo) Get(name string) (int64, bool, error) {
+	position, ok := r.positions[name]
+	return position, ok, nil
+}
+
+func (r *fakeScanCursorRepo) Save(name string, position int64) error {
+	r.positions[name] = position
+	return nil
+}
+
+func TestRadiusScannerStartID(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxID     int
+		positions map[string]int64
+		want      int
+	}{
+		{"首次启动从最大ID开始", 120, map[string]int64{}, 120},
+		{"从保存的进度继续", 120, map[string]int64{radiusScanCursor: 80}, 80},
+		{"认证记录表重建后从最大ID开始", 10, map[string]int64{radiusScanCursor: 80}, 10},
+	}
+	for _, tt := range tests {
+		cursorRepo := &fakeScanCursorRepo{positions: tt.positions}
+		s := &RadiusScanner{
+			radiusRepo: &fakeRadiusAuthRepo{maxID: tt.maxID},
+			cursorRepo: cursorRepo,
+		}
+		got, err := s.startID()
+		if err != nil {
+			t.Fatalf("%s: startID() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: startID() = %d, want %d", tt.name, got, tt.want)
+		}
+		if cursorRepo.positions[radiusScanCursor] != int64(tt.want) {
+			t.Errorf("%s: 保存的进度 = %d, want %d", tt.name, cursorRepo.positions[radiusScanCursor], tt.want)
+		}
+	}
+}
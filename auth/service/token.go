@@ -0,0 +1,312 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// 令牌服务错误
+var (
+	ErrTokenUnavailable = errors.New("令牌服务不可用")
+	ErrInvalidToken     = errors.New("令牌无效")
+	ErrTokenRevoked     = errors.New("令牌已吊销")
+	ErrUserDisabled     = errors.New("用户已冻结或注销")
+)
+
+var (
+	tokenSigner   *crypto.TokenSigner
+	tokenSignerMu sync.RWMutex
+)
+
+// InitTokenSigner 使用系统密钥对初始化令牌签名器
+func InitTokenSigner(cfg *config.Config) error {
+	signer, err := crypto.NewKeyManager(cfg).LoadTokenSigner()
+	if err != nil {
+		return err
+	}
+
+	SetTokenSigner(signer)
+
+	if cfg.DebugLevel == "true" {
+		log.Printf("令牌签名器初始化成功，签名算法: %s\n", signer.Algorithm())
+	}
+	return nil
+}
+
+// SetTokenSigner 设置全局令牌签名器
+func SetTokenSigner(signer *crypto.TokenSigner) {
+	tokenSignerMu.Lock()
+	defer tokenSignerMu.Unlock()
+	tokenSigner = signer
+}
+
+// GetTokenSigner 获取全局令牌签名器
+func GetTokenSigner() (*crypto.TokenSigner, error) {
+	tokenSignerMu.RLock()
+	defer tokenSignerMu.RUnlock()
+	if tokenSigner == nil {
+		return nil, ErrTokenUnavailable
+	}
+	return tokenSigner, nil
+}
+
+// TokenPair 访问令牌和刷新令牌
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`         // 访问令牌有效期（秒）
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // 刷新令牌有效期（秒）
+}
+
+// TokenService 令牌服务接口
+type TokenService interface {
+	// IssueTokenPair 为用户签发访问令牌和刷新令牌
+	IssueTokenPair(user *models.User) (*TokenPair, error)
+
+	// IssueDeviceToken 为设备签发会话令牌，返回令牌及有效期（秒）
+	IssueDeviceToken(device *models.Device) (string, int, error)
+
+	// ValidateAccessToken 校验访问令牌，包括签名、有效期和吊销状态
+	ValidateAccessToken(token string) (*crypto.TokenClaims, error)
+
+	// ValidateToken 校验令牌，令牌类型必须是types之一
+	// 访问令牌和设备会话令牌还会校验用户或设备的当前状态，访问令牌的权限掩码替换为用户当前的权限掩码
+	ValidateToken(token string, types ...string) (*crypto.TokenClaims, error)
+
+	// Refresh 使用刷新令牌换取新的令牌对，旧的刷新令牌随即吊销
+	Refresh(refreshToken string) (*TokenPair, error)
+
+	// Revoke 吊销令牌
+	Revoke(claims *crypto.TokenClaims) error
+
+	// RevokeToken 解析并吊销令牌字符串，已过期的令牌直接忽略
+	RevokeToken(token string) error
+}
+
+// tokenService 令牌服务实现
+type tokenService struct {
+	repoFactory repositories.RepositoryFactory
+	signer      *crypto.TokenSigner
+	cfg         *config.Config
+}
+
+// NewTokenService 创建令牌服务实例
+func NewTokenService(repoFactory repositories.RepositoryFactory, signer *crypto.TokenSigner) TokenService {
+	return &tokenService{
+		repoFactory: repoFactory,
+		signer:      signer,
+		cfg:         config.GetConfig(),
+	}
+}
+
+// IssueTokenPair 为用户签发访问令牌和刷新令牌
+func (s *tokenService) IssueTokenPair(user *models.User) (*TokenPair, error) {
+	claims := crypto.TokenClaims{
+		Subject: strconv.Itoa(user.UserID),
+		Issuer:  s.cfg.Auth.Issuer,
+		Name:    user.Username,
+		Mask:    user.PermissionMask,
+	}
+
+	accessTTL := time.Duration(s.cfg.Auth.AccessTokenTTL) * time.Second
+	refreshTTL := time.Duration(s.cfg.Auth.RefreshTokenTTL) * time.Second
+
+	claims.Type = crypto.TokenTypeAccess
+	accessToken, _, err := s.signer.Issue(claims, accessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("签发访问令牌失败: %w", err)
+	}
+
+	claims.Type = crypto.TokenTypeRefresh
+	refreshToken, _, err := s.signer.Issue(claims, refreshTTL)
+	if err != nil {
+		return nil, fmt.Errorf("签发刷新令牌失败: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        s.cfg.Auth.AccessTokenTTL,
+		RefreshExpiresIn: s.cfg.Auth.RefreshTokenTTL,
+	}, nil
+}
+
+// IssueDeviceToken 为设备签发会话令牌
+func (s *tokenService) IssueDeviceToken(device *models.Device) (string, int, error) {
+	claims := crypto.TokenClaims{
+		Subject: strconv.Itoa(device.DeviceID),
+		Issuer:  s.cfg.Auth.Issuer,
+		Type:    crypto.TokenTypeDevice,
+		Name:    device.DeviceName,
+	}
+
+	token, _, err := s.signer.Issue(claims, time.Duration(s.cfg.Auth.DeviceTokenTTL)*time.Second)
+	if err != nil {
+		return "", 0, fmt.Errorf("签发设备令牌失败: %w", err)
+	}
+	return token, s.cfg.Auth.DeviceTokenTTL, nil
+}
+
+// ValidateAccessToken 校验访问令牌
+func (s *tokenService) ValidateAccessToken(token string) (*crypto.TokenClaims, error) {
+	return s.ValidateToken(token, crypto.TokenTypeAccess)
+}
+
+// ValidateToken 校验令牌，令牌类型必须是types之一
+func (s *tokenService) ValidateToken(token string, types ...string) (*crypto.TokenClaims, error) {
+	claims, err := s.validate(token, types...)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSubject(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkSubject 校验令牌主体的当前状态
+// 冻结、注销或修改权限在令牌有效期内立即生效，不必等待访问令牌过期
+func (s *tokenService) checkSubject(claims *crypto.TokenClaims) error {
+	switch claims.Type {
+	case crypto.TokenTypeAccess:
+		userID, err := strconv.Atoi(claims.Subject)
+		if err != nil {
+			return ErrInvalidToken
+		}
+		user, err := s.repoFactory.GetUserRepository().FindByUserID(userID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// 用户已被删除，令牌随之失效
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("查询令牌用户失败: %w", err)
+		}
+		if user.StatusIs(models.UserStatusFrozen) || user.StatusIs(models.UserStatusCancelled) {
+			return ErrUserDisabled
+		}
+		claims.Mask = user.PermissionMask
+	case crypto.TokenTypeDevice:
+		deviceID, err := strconv.Atoi(claims.Subject)
+		if err != nil {
+			return ErrInvalidToken
+		}
+		device, err := s.repoFactory.GetDeviceRepository().FindByDeviceID(deviceID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("查询令牌设备失败: %w", err)
+		}
+		if device.DeviceStatus == models.DeviceStatusFrozen || device.DeviceStatus == models.DeviceStatusCancelled {
+			return ErrDeviceDisabled
+		}
+	}
+	return nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对
+func (s *tokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	claims, err := s.validate(refreshToken, crypto.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	user, err := s.repoFactory.GetUserRepository().FindByUserID(userID)
+	if err != nil {
+		// 用户已被删除，刷新令牌随之失效
+		return nil, ErrInvalidToken
+	}
+	// 冻结或注销的用户不能续期
+	if user.StatusIs(models.UserStatusFrozen) || user.StatusIs(models.UserStatusCancelled) {
+		return nil, ErrInvalidToken
+	}
+
+	// 刷新令牌只能使用一次
+	if err := s.Revoke(claims); err != nil {
+		return nil, err
+	}
+
+	return s.IssueTokenPair(user)
+}
+
+// Revoke 吊销令牌
+func (s *tokenService) Revoke(claims *crypto.TokenClaims) error {
+	repo := s.repoFactory.GetRevokedTokenRepository()
+	if err := repo.Revoke(&models.RevokedToken{
+		TokenID:   claims.ID,
+		TokenType: claims.Type,
+		Subject:   claims.Subject,
+		ExpiresAt: claims.ExpiresTime(),
+	}); err != nil {
+		return fmt.Errorf("吊销令牌失败: %w", err)
+	}
+
+	// 顺带清理已过期的吊销记录，避免表无限增长
+	if _, err := repo.DeleteExpired(time.Now()); err != nil {
+		log.Printf("清理过期吊销令牌失败: %v\n", err)
+	}
+	return nil
+}
+
+// RevokeToken 解析并吊销令牌字符串
+func (s *tokenService) RevokeToken(token string) error {
+	claims, err := s.signer.Verify(token)
+	if err != nil {
+		if errors.Is(err, crypto.ErrTokenExpired) {
+			return nil
+		}
+		return ErrInvalidToken
+	}
+	return s.Revoke(claims)
+}
+
+// validate 校验令牌签名、有效期、类型和吊销状态
+func (s *tokenService) validate(token string, types ...string) (*crypto.TokenClaims, error) {
+	claims, err := s.signer.Verify(token)
+	if err != nil {
+		if errors.Is(err, crypto.ErrTokenExpired) {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+	if claims.Issuer != s.cfg.Auth.Issuer {
+		return nil, ErrInvalidToken
+	}
+	typeAllowed := false
+	for _, tokenType := range types {
+		if claims.Type == tokenType {
+			typeAllowed = true
+			break
+		}
+	}
+	if !typeAllowed {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.repoFactory.GetRevokedTokenRepository().IsRevoked(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询令牌吊销状态失败: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,157 @@
+// rewrap-keys 更换私钥加密主密钥
+//
+// 将私钥目录中由原主密钥加密的私钥文件改为由新主密钥加密，只重新加密每个文件的数据密钥，私钥密文不变。
+// 已由新主密钥加密的文件会被跳过，中断后可以重复执行。
+//
+// 用法:
+//
+//	mv keys/master.key keys/master.key.old
+//	go run ./cmd/rewrap-keys -old keys/master.key.old
+//
+// 新主密钥默认为 KEY_MASTER_KEY_PATH，不存在时自动生成。全部文件处理成功后再启动服务，并妥善销毁原主密钥。
+// 使用数据库证书存储（CERT_STORE_TYPE=database）时指定 -db，同时处理certs表中保存的私钥。
+package main
+
+import (
+	"flag"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/configmanager/common/fileutil"
+	"gin-server/database/connections"
+	"gin-server/regist/store"
+)
+
+func main() {
+	config.InitConfig()
+	cfg := config.GetConfig()
+
+	oldPath := flag.String("old", "", "原主密钥文件路径")
+	newPath := flag.String("new", cfg.KeyEncryption.MasterKeyPath, "新主密钥文件路径，不存在时自动生成")
+	keyDir := flag.String("dir", filepath.Join(cfg.CertStore.LocalDir, store.KeysDir), "私钥文件目录")
+	withDB := flag.Bool("db", false, "同时处理数据库中保存的私钥")
+	flag.Parse()
+
+	if *oldPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	from, err := loadEnvelope(*oldPath)
+	if err != nil {
+		log.Fatalf("加载原主密钥失败: %v", err)
+	}
+
+	if !fileutil.IsFileExists(*newPath) {
+		if err := crypto.GenerateMasterKey(*newPath); err != nil {
+			log.Fatalf("生成新主密钥失败: %v", err)
+		}
+		log.Printf("已生成新主密钥: %s", *newPath)
+	}
+	to, err := loadEnvelope(*newPath)
+	if err != nil {
+		log.Fatalf("加载新主密钥失败: %v", err)
+	}
+	if from.KeyID() == to.KeyID() {
+		log.Fatalf("新主密钥与原主密钥相同")
+	}
+
+	var rewrapped, skipped, failed int
+	err = filepath.WalkDir(*keyDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".pem") {
+			return nil
+		}
+
+		changed, err := crypto.RewrapKeyFile(path, from, to)
+		switch {
+		case err == crypto.ErrNotEnvelope:
+			log.Printf("跳过未加密的私钥文件: %s", path)
+			skipped++
+		case err != nil:
+			log.Printf("处理私钥文件 %s 失败: %v", path, err)
+			failed++
+		case changed:
+			rewrapped++
+		default:
+			skipped++
+		}
+		return nil
+	})
+	if err != nil && !(os.IsNotExist(err) && *withDB) {
+		log.Fatalf("遍历私钥目录失败: %v", err)
+	}
+
+	if *withDB {
+		r, s, f, err := rewrapDBKeys(cfg, from, to)
+		if err != nil {
+			log.Fatalf("处理数据库中的私钥失败: %v", err)
+		}
+		rewrapped, skipped, failed = rewrapped+r, skipped+s, failed+f
+	}
+
+	log.Printf("处理完成，重新加密: %d，跳过: %d，失败: %d，新主密钥标识: %s", rewrapped, skipped, failed, to.KeyID())
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// rewrapDBKeys 重新加密certs表中保存的私钥，返回重新加密、跳过和失败的数量
+func rewrapDBKeys(cfg *config.Config, from, to *crypto.EnvelopeEncryptor) (rewrapped, skipped, failed int, err error) {
+	manager := connections.NewConnectionManager(cfg)
+	defer manager.CloseAll()
+	conn, err := manager.Default()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	db := conn.GetDB()
+
+	var rows []struct {
+		ID     uint
+		KeyPEM []byte
+	}
+	if err := db.Table("certs").Select("id, key_pem").Where("key_pem IS NOT NULL").Find(&rows).Error; err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, row := range rows {
+		keyID, err := crypto.EnvelopeKeyID(row.KeyPEM)
+		if err != nil {
+			log.Printf("跳过未加密的私钥: 证书记录 %d", row.ID)
+			skipped++
+			continue
+		}
+		if keyID == to.KeyID() {
+			skipped++
+			continue
+		}
+
+		data, err := from.Rewrap(row.KeyPEM, to)
+		if err == nil {
+			err = db.Table("certs").Where("id = ?", row.ID).Update("key_pem", data).Error
+		}
+		if err != nil {
+			log.Printf("处理证书记录 %d 的私钥失败: %v", row.ID, err)
+			failed++
+			continue
+		}
+		rewrapped++
+	}
+	return rewrapped, skipped, failed, nil
+}
+
+// loadEnvelope 从主密钥文件创建信封加密器
+func loadEnvelope(path string) (*crypto.EnvelopeEncryptor, error) {
+	masterKey, err := crypto.LoadMasterKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.NewEnvelopeEncryptor(masterKey)
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 系统全局配置结构体
@@ -75,6 +76,50 @@ type Config struct {
 	// TestData 测试数据配置
 	// 用于控制测试数据的生成和维护
 	TestData TestDataConfig
+
+	// Password 口令哈希配置
+	// 控制用户和设备口令的哈希算法及参数
+	Password PasswordConfig
+
+	// Auth 接口认证配置
+	// 控制令牌签发、有效期和免认证路由
+	Auth AuthConfig
+
+	// TLS HTTPS监听配置
+	// 启用后额外提供HTTPS接口，并支持设备使用客户端证书认证
+	TLS TLSConfig
+
+	// CA 内置证书颁发机构配置
+	// 用于为用户和设备签发证书
+	CA CAConfig
+
+	// CRL 证书吊销列表配置
+	// 控制吊销列表的生成周期和远程发布
+	CRL CRLConfig
+
+	// CertExpiry 证书过期提醒配置
+	// 控制证书有效期扫描周期和告警阈值
+	CertExpiry CertExpiryConfig
+
+	// CertRotation 证书轮换配置
+	// 控制切换证书版本时上一版本的重叠期
+	CertRotation CertRotationConfig
+
+	// KeyEncryption 私钥加密存储配置
+	// 控制绑定的私钥文件是否使用主密钥加密保存
+	KeyEncryption KeyEncryptionConfig
+
+	// CertStore 证书存储配置
+	// 控制绑定的证书和私钥保存在本地文件还是数据库中
+	CertStore CertStoreConfig
+
+	// Heartbeat 设备心跳配置
+	// 控制设备上报心跳的间隔和性能峰值的统计窗口
+	Heartbeat HeartbeatConfig
+
+	// Metrics 设备性能数据配置
+	// 控制心跳采样数据的聚合周期和各精度数据的保留时长
+	Metrics MetricsConfig
 }
 
 // ConfigManagerConfig 配置管理模块配置结构体
@@ -254,6 +299,212 @@ type TestDataConfig struct {
 	RealtimeEndTimeOffset int `json:"realtime_end_time_offset" yaml:"realtime_end_time_offset"`
 }
 
+// PasswordConfig 口令哈希配置结构体
+type PasswordConfig struct {
+	// Algorithm 哈希算法
+	// 可选值: "argon2id", "bcrypt"
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+
+	// Argon2Memory argon2id内存开销（KiB）
+	// 默认65536，即64MB
+	Argon2Memory int `json:"argon2_memory" yaml:"argon2_memory"`
+
+	// Argon2Iterations argon2id迭代次数
+	Argon2Iterations int `json:"argon2_iterations" yaml:"argon2_iterations"`
+
+	// Argon2Parallelism argon2id并行度
+	Argon2Parallelism int `json:"argon2_parallelism" yaml:"argon2_parallelism"`
+
+	// BcryptCost bcrypt计算代价
+	// 可选值: 4-31
+	BcryptCost int `json:"bcrypt_cost" yaml:"bcrypt_cost"`
+}
+
+// AuthConfig 接口认证配置结构体
+type AuthConfig struct {
+	// Enabled 是否启用令牌认证
+	// 关闭后所有接口均可匿名访问，仅用于调试
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Issuer 令牌签发者
+	Issuer string `json:"issuer" yaml:"issuer"`
+
+	// AccessTokenTTL 访问令牌有效期（秒）
+	AccessTokenTTL int `json:"access_token_ttl" yaml:"access_token_ttl"`
+
+	// RefreshTokenTTL 刷新令牌有效期（秒）
+	RefreshTokenTTL int `json:"refresh_token_ttl" yaml:"refresh_token_ttl"`
+
+	// PublicRoutes 免认证路由列表
+	// 格式: "/path" 或 "METHOD /path"，以 "*" 结尾表示前缀匹配
+	PublicRoutes []string `json:"public_routes" yaml:"public_routes"`
+
+	// BootstrapAdminName 初始管理员用户名
+	// 启动时该用户不存在则自动创建，为空则不创建
+	BootstrapAdminName string `json:"bootstrap_admin_name" yaml:"bootstrap_admin_name"`
+
+	// BootstrapAdminPassword 初始管理员口令
+	BootstrapAdminPassword string `json:"bootstrap_admin_password" yaml:"bootstrap_admin_password"`
+
+	// DeviceTokenTTL 设备会话令牌有效期（秒）
+	DeviceTokenTTL int `json:"device_token_ttl" yaml:"device_token_ttl"`
+
+	// DeviceMaxLoginFailures 设备连续登录失败多少次后锁定
+	DeviceMaxLoginFailures int `json:"device_max_login_failures" yaml:"device_max_login_failures"`
+
+	// DeviceLockDuration 设备登录锁定时长（秒）
+	DeviceLockDuration int `json:"device_lock_duration" yaml:"device_lock_duration"`
+
+	// UserMaxLoginFailures 用户非法登录多少次后冻结，0表示不冻结
+	UserMaxLoginFailures int `json:"user_max_login_failures" yaml:"user_max_login_failures"`
+
+	// UserFreezeDuration 用户冻结后自动解冻的时长（秒），0表示只能由管理员解冻
+	UserFreezeDuration int `json:"user_freeze_duration" yaml:"user_freeze_duration"`
+
+	// RadiusScanInterval 扫描Radius认证记录的间隔（秒），0表示不扫描
+	RadiusScanInterval int `json:"radius_scan_interval" yaml:"radius_scan_interval"`
+}
+
+// TLSConfig HTTPS监听配置结构体
+type TLSConfig struct {
+	// Enabled 是否启用HTTPS监听，HTTP监听不受影响
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Port HTTPS监听端口
+	Port string `json:"port" yaml:"port"`
+
+	// CertFile 服务端证书文件路径
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+
+	// KeyFile 服务端私钥文件路径
+	KeyFile string `json:"key_file" yaml:"key_file"`
+
+	// ClientCAFile 签发客户端证书的CA证书文件路径
+	// 为空时不校验证书链，仅要求客户端证书与设备绑定的证书完全一致
+	ClientCAFile string `json:"client_ca_file" yaml:"client_ca_file"`
+
+	// RequireClientCert 是否要求所有HTTPS请求都提供客户端证书
+	// 关闭时未提供证书的请求仍可使用令牌认证
+	RequireClientCert bool `json:"require_client_cert" yaml:"require_client_cert"`
+}
+
+// CAConfig 内置证书颁发机构配置结构体
+type CAConfig struct {
+	// Dir CA证书和私钥的存储目录
+	Dir string `json:"dir" yaml:"dir"`
+
+	// KeyAlgorithm CA私钥算法
+	// 可选值: "RSA", "ECDSA", "ED25519"
+	KeyAlgorithm string `json:"key_algorithm" yaml:"key_algorithm"`
+
+	// KeyLength CA私钥长度
+	// RSA推荐: 3072或4096
+	// ECDSA可选: 256, 384, 521
+	KeyLength int `json:"key_length" yaml:"key_length"`
+
+	// CommonName CA证书通用名称
+	CommonName string `json:"common_name" yaml:"common_name"`
+
+	// Organization CA证书组织名称
+	Organization string `json:"organization" yaml:"organization"`
+
+	// ValidityDays CA证书有效期（天）
+	ValidityDays int `json:"validity_days" yaml:"validity_days"`
+
+	// CertValidityDays 签发证书的默认有效期（天）
+	CertValidityDays int `json:"cert_validity_days" yaml:"cert_validity_days"`
+
+	// MaxCertValidityDays 签发证书允许的最长有效期（天）
+	MaxCertValidityDays int `json:"max_cert_validity_days" yaml:"max_cert_validity_days"`
+}
+
+// CRLConfig 证书吊销列表配置结构体
+type CRLConfig struct {
+	// Path CRL文件保存路径（DER编码）
+	Path string `json:"path" yaml:"path"`
+
+	// UpdateInterval 定期重新生成CRL的间隔（秒），0表示只在吊销证书时生成
+	UpdateInterval int `json:"update_interval" yaml:"update_interval"`
+
+	// Validity CRL有效期（秒），即下次更新时间与本次生成时间的间隔，应大于UpdateInterval
+	Validity int `json:"validity" yaml:"validity"`
+
+	// RemoteEnabled 是否将生成的CRL推送到远程存储
+	// 远程存储方式与ConfigManager.Storage.Type一致
+	RemoteEnabled bool `json:"remote_enabled" yaml:"remote_enabled"`
+
+	// RemotePath CRL在远程存储中的路径
+	RemotePath string `json:"remote_path" yaml:"remote_path"`
+}
+
+// CertExpiryConfig 证书过期提醒配置结构体
+type CertExpiryConfig struct {
+	// ScanInterval 扫描证书有效期的间隔（秒），0表示不扫描
+	ScanInterval int `json:"scan_interval" yaml:"scan_interval"`
+
+	// AlertThresholds 剩余有效期告警阈值（天）
+	// 证书剩余有效期首次低于某个阈值时产生一次告警
+	AlertThresholds []int `json:"alert_thresholds" yaml:"alert_thresholds"`
+}
+
+// CertRotationConfig 证书轮换配置结构体
+type CertRotationConfig struct {
+	// Overlap 切换证书版本后上一版本继续可用于认证的时长（秒），0表示立即停用
+	// 绑定、签发和切换证书时可在请求中单独指定
+	Overlap int `json:"overlap" yaml:"overlap"`
+}
+
+// KeyEncryptionConfig 私钥加密存储配置结构体
+type KeyEncryptionConfig struct {
+	// Enabled 是否加密保存绑定的私钥文件
+	// 关闭后新绑定的私钥以明文保存，已加密的私钥仍可读取
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// MasterKeyPath 主密钥文件路径，不存在时自动生成
+	MasterKeyPath string `json:"master_key_path" yaml:"master_key_path"`
+}
+
+// CertStoreConfig 证书存储配置结构体
+type CertStoreConfig struct {
+	// Type 存储类型
+	// 可选值: local(本地文件), database(数据库)，多个服务实例共享证书时使用database
+	Type string `json:"type" yaml:"type"`
+
+	// LocalDir 本地存储目录，证书和私钥分别保存在其certs和keys子目录中
+	LocalDir string `json:"local_dir" yaml:"local_dir"`
+}
+
+// HeartbeatConfig 设备心跳配置结构体
+type HeartbeatConfig struct {
+	// Interval 设备上报心跳的间隔（秒），心跳响应中返回给设备
+	Interval int `json:"interval" yaml:"interval"`
+
+	// PeakWindow CPU和内存峰值的统计窗口（秒），按窗口对齐，进入新窗口时峰值重新统计
+	// 与日志生成间隔一致时，日志中的峰值即为该周期内的峰值
+	PeakWindow int `json:"peak_window" yaml:"peak_window"`
+
+	// MissedThreshold 连续多少个心跳间隔未上报时判定设备离线
+	MissedThreshold int `json:"missed_threshold" yaml:"missed_threshold"`
+
+	// CheckInterval 检查设备心跳超时的间隔（秒），0表示不检查
+	CheckInterval int `json:"check_interval" yaml:"check_interval"`
+}
+
+// MetricsConfig 设备性能数据配置结构体
+type MetricsConfig struct {
+	// RollupInterval 将采样数据聚合为分钟和小时数据并清理过期数据的间隔（秒），0表示不聚合也不清理
+	RollupInterval int `json:"rollup_interval" yaml:"rollup_interval"`
+
+	// RawRetention 原始采样数据的保留时长（小时）
+	RawRetention int `json:"raw_retention" yaml:"raw_retention"`
+
+	// MinuteRetention 分钟聚合数据的保留时长（小时）
+	MinuteRetention int `json:"minute_retention" yaml:"minute_retention"`
+
+	// HourRetention 小时聚合数据的保留时长（小时），0表示永久保留
+	HourRetention int `json:"hour_retention" yaml:"hour_retention"`
+}
+
 // EncryptionConfig 加密配置结构体
 type EncryptionConfig struct {
 	// AESKeyLength AES密钥长度
@@ -371,6 +622,84 @@ func InitConfig() {
 			RealtimeStartTimeOffset:      getEnvInt("TEST_REALTIME_START_TIME_OFFSET", 0),
 			RealtimeEndTimeOffset:        getEnvInt("TEST_REALTIME_END_TIME_OFFSET", -2),
 		},
+
+		// 口令哈希配置
+		Password: PasswordConfig{
+			Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			Argon2Memory:      getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+			Argon2Iterations:  getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: getEnvInt("PASSWORD_ARGON2_PARALLELISM", 2),
+			BcryptCost:        getEnvInt("PASSWORD_BCRYPT_COST", 12),
+		},
+
+		// 接口认证配置
+		Auth: AuthConfig{
+			Enabled:                getEnvBool("AUTH_ENABLED", true),
+			Issuer:                 getEnv("AUTH_TOKEN_ISSUER", "gin-server"),
+			AccessTokenTTL:         getEnvInt("AUTH_ACCESS_TOKEN_TTL", 15*60),
+			RefreshTokenTTL:        getEnvInt("AUTH_REFRESH_TOKEN_TTL", 7*24*3600),
+			PublicRoutes:           getEnvList("AUTH_PUBLIC_ROUTES", []string{"POST /auth/login", "POST /auth/refresh", "POST /auth/devices/login", "GET /cert/crl"}),
+			BootstrapAdminName:     getEnv("AUTH_BOOTSTRAP_ADMIN_NAME", ""),
+			BootstrapAdminPassword: getEnv("AUTH_BOOTSTRAP_ADMIN_PASSWORD", ""),
+			DeviceTokenTTL:         getEnvInt("AUTH_DEVICE_TOKEN_TTL", 3600),
+			DeviceMaxLoginFailures: getEnvInt("AUTH_DEVICE_MAX_LOGIN_FAILURES", 5),
+			DeviceLockDuration:     getEnvInt("AUTH_DEVICE_LOCK_DURATION", 15*60),
+			UserMaxLoginFailures:   getEnvInt("AUTH_USER_MAX_LOGIN_FAILURES", 5),
+			UserFreezeDuration:     getEnvInt("AUTH_USER_FREEZE_DURATION", 30*60),
+			RadiusScanInterval:     getEnvInt("AUTH_RADIUS_SCAN_INTERVAL", 60),
+		},
+		TLS: TLSConfig{
+			Enabled:           getEnvBool("TLS_ENABLED", false),
+			Port:              getEnv("TLS_PORT", "8443"),
+			CertFile:          getEnv("TLS_CERT_FILE", "keys/server.crt"),
+			KeyFile:           getEnv("TLS_KEY_FILE", "keys/server.key"),
+			ClientCAFile:      getEnv("TLS_CLIENT_CA_FILE", ""),
+			RequireClientCert: getEnvBool("TLS_REQUIRE_CLIENT_CERT", false),
+		},
+		CA: CAConfig{
+			Dir:                 getEnv("CA_DIR", "keys/ca"),
+			KeyAlgorithm:        getEnv("CA_KEY_ALGORITHM", "ECDSA"),
+			KeyLength:           getEnvInt("CA_KEY_LENGTH", 384),
+			CommonName:          getEnv("CA_COMMON_NAME", "gin-server Root CA"),
+			Organization:        getEnv("CA_ORGANIZATION", "gin-server"),
+			ValidityDays:        getEnvInt("CA_VALIDITY_DAYS", 3650),
+			CertValidityDays:    getEnvInt("CA_CERT_VALIDITY_DAYS", 365),
+			MaxCertValidityDays: getEnvInt("CA_MAX_CERT_VALIDITY_DAYS", 825),
+		},
+		CRL: CRLConfig{
+			Path:           getEnv("CRL_PATH", "keys/ca/ca.crl"),
+			UpdateInterval: getEnvInt("CRL_UPDATE_INTERVAL", 3600),
+			Validity:       getEnvInt("CRL_VALIDITY", 24*3600),
+			RemoteEnabled:  getEnvBool("CRL_REMOTE_ENABLED", false),
+			RemotePath:     getEnv("CRL_REMOTE_PATH", "crl/ca.crl"),
+		},
+		CertExpiry: CertExpiryConfig{
+			ScanInterval:    getEnvInt("CERT_EXPIRY_SCAN_INTERVAL", 3600),
+			AlertThresholds: getEnvIntList("CERT_EXPIRY_ALERT_THRESHOLDS", []int{30, 7, 1}),
+		},
+		CertRotation: CertRotationConfig{
+			Overlap: getEnvInt("CERT_ROTATION_OVERLAP", 0),
+		},
+		KeyEncryption: KeyEncryptionConfig{
+			Enabled:       getEnvBool("KEY_ENCRYPTION_ENABLED", true),
+			MasterKeyPath: getEnv("KEY_MASTER_KEY_PATH", "keys/master.key"),
+		},
+		CertStore: CertStoreConfig{
+			Type:     getEnv("CERT_STORE_TYPE", "local"),
+			LocalDir: getEnv("CERT_STORE_DIR", "regist/certs"),
+		},
+		Heartbeat: HeartbeatConfig{
+			Interval:        getEnvInt("HEARTBEAT_INTERVAL", 30),
+			PeakWindow:      getEnvInt("HEARTBEAT_PEAK_WINDOW", 600),
+			MissedThreshold: getEnvInt("HEARTBEAT_MISSED_THRESHOLD", 3),
+			CheckInterval:   getEnvInt("HEARTBEAT_CHECK_INTERVAL", 30),
+		},
+		Metrics: MetricsConfig{
+			RollupInterval:  getEnvInt("METRICS_ROLLUP_INTERVAL", 60),
+			RawRetention:    getEnvInt("METRICS_RAW_RETENTION", 24),
+			MinuteRetention: getEnvInt("METRICS_MINUTE_RETENTION", 7*24),
+			HourRetention:   getEnvInt("METRICS_HOUR_RETENTION", 90*24),
+		},
 	}
 
 	// 设置Gitee配置
@@ -424,6 +753,36 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvList 获取以逗号分隔的环境变量列表
+func getEnvList(key string, defaultValue []string) []string {
+	if value, exists := os.LookupEnv(key); exists {
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	return defaultValue
+}
+
+// getEnvIntList 获取以逗号分隔的整数列表，包含无效整数时使用默认值
+func getEnvIntList(key string, defaultValue []int) []int {
+	if _, exists := os.LookupEnv(key); !exists {
+		return defaultValue
+	}
+	items := make([]int, 0)
+	for _, item := range getEnvList(key, nil) {
+		intValue, err := strconv.Atoi(item)
+		if err != nil {
+			return defaultValue
+		}
+		items = append(items, intValue)
+	}
+	return items
+}
+
 // getEnvBool 获取环境变量并转换为布尔值
 func getEnvBool(key string, defaultValue bool) bool {
 	if value, exists := os.LookupEnv(key); exists {
@@ -504,5 +863,75 @@ func DefaultConfig() *Config {
 			RealtimeStartTimeOffset:      2,
 			RealtimeEndTimeOffset:        0,
 		},
+		Password: PasswordConfig{
+			Algorithm:         "argon2id",
+			Argon2Memory:      64 * 1024,
+			Argon2Iterations:  3,
+			Argon2Parallelism: 2,
+			BcryptCost:        12,
+		},
+		Auth: AuthConfig{
+			Enabled:                true,
+			Issuer:                 "gin-server",
+			AccessTokenTTL:         15 * 60,
+			RefreshTokenTTL:        7 * 24 * 3600,
+			PublicRoutes:           []string{"POST /auth/login", "POST /auth/refresh", "POST /auth/devices/login", "GET /cert/crl"},
+			DeviceTokenTTL:         3600,
+			DeviceMaxLoginFailures: 5,
+			DeviceLockDuration:     15 * 60,
+			UserMaxLoginFailures:   5,
+			UserFreezeDuration:     30 * 60,
+			RadiusScanInterval:     60,
+		},
+		TLS: TLSConfig{
+			Enabled:  false,
+			Port:     "8443",
+			CertFile: "keys/server.crt",
+			KeyFile:  "keys/server.key",
+		},
+		CA: CAConfig{
+			Dir:                 "keys/ca",
+			KeyAlgorithm:        "ECDSA",
+			KeyLength:           384,
+			CommonName:          "gin-server Root CA",
+			Organization:        "gin-server",
+			ValidityDays:        3650,
+			CertValidityDays:    365,
+			MaxCertValidityDays: 825,
+		},
+		CRL: CRLConfig{
+			Path:           "keys/ca/ca.crl",
+			UpdateInterval: 3600,
+			Validity:       24 * 3600,
+			RemoteEnabled:  false,
+			RemotePath:     "crl/ca.crl",
+		},
+		CertExpiry: CertExpiryConfig{
+			ScanInterval:    3600,
+			AlertThresholds: []int{30, 7, 1},
+		},
+		CertRotation: CertRotationConfig{
+			Overlap: 0,
+		},
+		KeyEncryption: KeyEncryptionConfig{
+			Enabled:       true,
+			MasterKeyPath: "keys/master.key",
+		},
+		CertStore: CertStoreConfig{
+			Type:     "local",
+			LocalDir: "regist/certs",
+		},
+		Heartbeat: HeartbeatConfig{
+			Interval:        30,
+			PeakWindow:      600,
+			MissedThreshold: 3,
+			CheckInterval:   30,
+		},
+		Metrics: MetricsConfig{
+			RollupInterval:  60,
+			RawRetention:    24,
+			MinuteRetention: 7 * 24,
+			HourRetention:   90 * 24,
+		},
 	}
 }
@@ -27,6 +27,10 @@ const (
 	AlertTypeLogUpload     AlertType = 3 // 日志上传
 	AlertTypeStrategySync  AlertType = 4 // 策略同步
 	AlertTypeStrategyApply AlertType = 5 // 策略应用
+	AlertTypeAccountFreeze AlertType = 6 // 账号冻结
+	AlertTypeCRLPublish    AlertType = 7 // 证书吊销列表发布
+	AlertTypeCertExpiry    AlertType = 8 // 证书即将过期或已过期
+	AlertTypeDeviceOffline AlertType = 9 // 设备离线或恢复
 )
 
 // Alert 告警信息
@@ -106,6 +110,14 @@ func (t AlertType) String() string {
 		return "STRATEGY_SYNC"
 	case AlertTypeStrategyApply:
 		return "STRATEGY_APPLY"
+	case AlertTypeAccountFreeze:
+		return "ACCOUNT_FREEZE"
+	case AlertTypeCRLPublish:
+		return "CRL_PUBLISH"
+	case AlertTypeCertExpiry:
+		return "CERT_EXPIRY"
+	case AlertTypeDeviceOffline:
+		return "DEVICE_OFFLINE"
 	default:
 		return "UNKNOWN"
 	}
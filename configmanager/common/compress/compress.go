@@ -1,9 +1,12 @@
 package compress
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"gin-server/config"
 )
@@ -14,6 +17,14 @@ type CompressFormat string
 const (
 	// FormatTarGz tar.gz格式
 	FormatTarGz CompressFormat = "tar.gz"
+	// FormatZip zip格式
+	FormatZip CompressFormat = "zip"
+)
+
+// 解压错误
+var (
+	ErrUnsafePath = errors.New("压缩包中的文件路径超出目标目录")
+	ErrSizeLimit  = errors.New("解压后的文件大小超过限制")
 )
 
 // ProgressCallback 进度回调函数
@@ -31,6 +42,8 @@ type Options struct {
 	ExcludePatterns []string
 	// ProgressCallback 进度回调
 	ProgressCallback ProgressCallback
+	// MaxSize 解压后文件的总大小上限（字节），0表示不限制
+	MaxSize int64
 }
 
 // Option 选项设置函数
@@ -125,6 +138,27 @@ func WithProgressCallback(callback ProgressCallback) Option {
 	}
 }
 
+// WithMaxSize 设置解压后文件的总大小上限
+func WithMaxSize(size int64) Option {
+	return func(o *Options) {
+		if size > 0 {
+			o.MaxSize = size
+		}
+	}
+}
+
+// NewCompressor 根据格式创建压缩器
+func NewCompressor(format CompressFormat) (Compressor, error) {
+	switch format {
+	case FormatTarGz:
+		return NewTarGzCompressor(), nil
+	case FormatZip:
+		return NewZipCompressor(), nil
+	default:
+		return nil, fmt.Errorf("不支持的压缩格式: %s", format)
+	}
+}
+
 // processOptions 处理选项
 func processOptions(opts ...Option) Options {
 	options := DefaultOptions
@@ -173,6 +207,40 @@ func copyWithProgress(dst io.Writer, src io.Reader, size int64, callback Progres
 	return nil
 }
 
+// safeTarget 返回压缩包中的文件在目标目录中的路径
+// 拒绝绝对路径和跳出目标目录的相对路径，防止解压时覆盖目标目录以外的文件
+func safeTarget(dest, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", ErrUnsafePath
+	}
+
+	target := filepath.Join(dest, name)
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrUnsafePath
+	}
+	return target, nil
+}
+
+// sizeLimiter 累计解压的文件大小，超过上限时返回ErrSizeLimit
+type sizeLimiter struct {
+	max   int64
+	total int64
+}
+
+// add 累计一个文件的大小
+func (l *sizeLimiter) add(size int64) error {
+	if l.max <= 0 {
+		return nil
+	}
+	l.total += size
+	if size < 0 || l.total > l.max {
+		return ErrSizeLimit
+	}
+	return nil
+}
+
 // logDebug 输出调试日志
 func logDebug(format string, v ...interface{}) {
 	cfg := config.GetConfig()
@@ -0,0 +1,135 @@
+package compress
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTarGz 生成包含指定文件的tar.gz压缩包
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("写入tar头失败: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("写入tar内容失败: %v", err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("写入压缩包失败: %v", err)
+	}
+}
+
+// writeZip 生成包含指定文件的zip压缩包
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("创建zip条目失败: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("写入zip内容失败: %v", err)
+		}
+	}
+	zw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("写入压缩包失败: %v", err)
+	}
+}
+
+func TestDecompressRejectsPathTraversal(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"上级目录", "../evil.txt"},
+		{"嵌套上级目录", "certs/../../evil.txt"},
+		{"绝对路径", "/tmp/evil.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dest := filepath.Join(dir, "out")
+
+			tarPath := filepath.Join(dir, "a.tar.gz")
+			writeTarGz(t, tarPath, map[string]string{tt.entry: "x"})
+			if err := NewTarGzCompressor().Decompress(tarPath, dest); !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("tar.gz Decompress() error = %v, want ErrUnsafePath", err)
+			}
+
+			zipPath := filepath.Join(dir, "a.zip")
+			writeZip(t, zipPath, map[string]string{tt.entry: "x"})
+			if err := NewZipCompressor().Decompress(zipPath, dest); !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("zip Decompress() error = %v, want ErrUnsafePath", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+				t.Error("文件被解压到了目标目录之外")
+			}
+		})
+	}
+}
+
+func TestDecompressMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.txt": "0123456789", "b.txt": "0123456789"}
+
+	tarPath := filepath.Join(dir, "a.tar.gz")
+	writeTarGz(t, tarPath, files)
+	if err := NewTarGzCompressor().Decompress(tarPath, filepath.Join(dir, "tar"), WithMaxSize(15)); !errors.Is(err, ErrSizeLimit) {
+		t.Errorf("tar.gz Decompress() error = %v, want ErrSizeLimit", err)
+	}
+
+	zipPath := filepath.Join(dir, "a.zip")
+	writeZip(t, zipPath, files)
+	if err := NewZipCompressor().Decompress(zipPath, filepath.Join(dir, "zip"), WithMaxSize(15)); !errors.Is(err, ErrSizeLimit) {
+		t.Errorf("zip Decompress() error = %v, want ErrSizeLimit", err)
+	}
+	if err := NewZipCompressor().Decompress(zipPath, filepath.Join(dir, "zip"), WithMaxSize(20)); err != nil {
+		t.Errorf("未超过上限时 Decompress() error = %v", err)
+	}
+}
+
+func TestZipRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "certs"), 0755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "certs", "1001.pem"), []byte("cert"), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	archive := filepath.Join(dir, "certs.zip")
+	c, err := NewCompressor(FormatZip)
+	if err != nil {
+		t.Fatalf("NewCompressor() error = %v", err)
+	}
+	if err := c.Compress(src, archive); err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+	dest := filepath.Join(dir, "dest")
+	if err := c.Decompress(archive, dest); err != nil {
+		t.Fatalf("Decompress() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "certs", "1001.pem"))
+	if err != nil || string(data) != "cert" {
+		t.Errorf("解压后的文件内容 = %q, %v, want %q", data, err, "cert")
+	}
+}
@@ -109,6 +109,7 @@ func (c *TarGzCompressor) Decompress(src string, dest string, opts ...Option) er
 	}
 
 	// 遍历tar文件
+	limiter := &sizeLimiter{max: options.MaxSize}
 	for {
 		header, err := tr.Next()
 		if err == io.EOF {
@@ -124,8 +125,12 @@ func (c *TarGzCompressor) Decompress(src string, dest string, opts ...Option) er
 		}
 
 		// 构建目标路径
-		target := filepath.Join(dest, header.Name)
+		target, err := safeTarget(dest, header.Name)
+		if err != nil {
+			return NewCompressError("extract", header.Name, err)
+		}
 
+		// 只解压目录和普通文件，忽略链接等其他类型
 		switch header.Typeflag {
 		case tar.TypeDir:
 			// 创建目录
@@ -133,6 +138,9 @@ func (c *TarGzCompressor) Decompress(src string, dest string, opts ...Option) er
 				return NewCompressError("mkdir", target, err)
 			}
 		case tar.TypeReg:
+			if err := limiter.add(header.Size); err != nil {
+				return NewCompressError("extract", header.Name, err)
+			}
 			// 创建文件
 			if err := extractFile(tr, target, header.Size, options.BufferSize, options.ProgressCallback); err != nil {
 				return NewCompressError("extract", target, err)
@@ -0,0 +1,177 @@
+package compress
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ZipCompressor zip格式压缩器
+type ZipCompressor struct{}
+
+// NewZipCompressor 创建zip格式压缩器
+func NewZipCompressor() *ZipCompressor {
+	return &ZipCompressor{}
+}
+
+// Compress 压缩文件或目录
+func (c *ZipCompressor) Compress(src string, dest string, opts ...Option) error {
+	logDebug("开始压缩: %s -> %s", src, dest)
+	options := processOptions(opts...)
+
+	// 创建目标文件
+	destFile, err := os.Create(dest)
+	if err != nil {
+		return NewCompressError("create", dest, err)
+	}
+	defer destFile.Close()
+
+	// 创建zip写入器
+	zw := zip.NewWriter(destFile)
+	defer zw.Close()
+
+	// 获取源文件信息
+	fi, err := os.Stat(src)
+	if err != nil {
+		return NewCompressError("stat", src, err)
+	}
+
+	// 如果是目录，遍历并添加文件
+	if fi.IsDir() {
+		err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if path == src {
+				return nil
+			}
+
+			// 检查是否匹配包含/排除模式
+			if !shouldInclude(path, src, options.IncludePatterns, options.ExcludePatterns) {
+				return nil
+			}
+
+			// 获取相对路径
+			relPath, err := filepath.Rel(src, path)
+			if err != nil {
+				return err
+			}
+
+			// 添加到zip文件
+			return addToZip(zw, path, filepath.ToSlash(relPath), info, options.BufferSize, options.ProgressCallback)
+		})
+		if err != nil {
+			return NewCompressError("walk", src, err)
+		}
+	} else {
+		// 单个文件直接添加
+		err = addToZip(zw, src, filepath.Base(src), fi, options.BufferSize, options.ProgressCallback)
+		if err != nil {
+			return NewCompressError("add", src, err)
+		}
+	}
+
+	logDebug("压缩完成: %s -> %s", src, dest)
+	return nil
+}
+
+// Decompress 解压文件
+func (c *ZipCompressor) Decompress(src string, dest string, opts ...Option) error {
+	logDebug("开始解压: %s -> %s", src, dest)
+	options := processOptions(opts...)
+
+	// 打开源文件
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return NewCompressError("open", src, err)
+	}
+	defer zr.Close()
+
+	// 确保目标目录存在
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return NewCompressError("mkdir", dest, err)
+	}
+
+	// 遍历zip文件
+	limiter := &sizeLimiter{max: options.MaxSize}
+	for _, f := range zr.File {
+		// 检查是否匹配包含/排除模式
+		if !shouldInclude(f.Name, "", options.IncludePatterns, options.ExcludePatterns) {
+			continue
+		}
+
+		// 构建目标路径
+		target, err := safeTarget(dest, f.Name)
+		if err != nil {
+			return NewCompressError("extract", f.Name, err)
+		}
+
+		// 只解压目录和普通文件，忽略链接等其他类型
+		mode := f.Mode()
+		switch {
+		case mode.IsDir() || strings.HasSuffix(f.Name, "/"):
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return NewCompressError("mkdir", target, err)
+			}
+		case mode.IsRegular():
+			// zip读取器会校验实际解压大小不超过声明的大小
+			if err := limiter.add(int64(f.UncompressedSize64)); err != nil {
+				return NewCompressError("extract", f.Name, err)
+			}
+			if err := extractZipFile(f, target, options.BufferSize, options.ProgressCallback); err != nil {
+				return NewCompressError("extract", target, err)
+			}
+		}
+	}
+
+	logDebug("解压完成: %s -> %s", src, dest)
+	return nil
+}
+
+// addToZip 添加文件到zip
+func addToZip(zw *zip.Writer, path, relPath string, info os.FileInfo, bufSize int, callback ProgressCallback) error {
+	// 创建header
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = relPath
+	if info.IsDir() {
+		header.Name += "/"
+	} else {
+		header.Method = zip.Deflate
+	}
+
+	// 写入header
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	// 如果是普通文件，写入内容
+	if info.Mode().IsRegular() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := copyWithProgress(w, file, info.Size(), callback, bufSize); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// extractZipFile 解压zip中的单个文件
+func extractZipFile(f *zip.File, path string, bufSize int, callback ProgressCallback) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return extractFile(rc, path, int64(f.UncompressedSize64), bufSize, callback)
+}
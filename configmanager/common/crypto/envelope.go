@@ -0,0 +1,291 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"gin-server/config"
+)
+
+// EnvelopePEMType 信封加密的私钥文件PEM块类型
+const EnvelopePEMType = "ENVELOPE ENCRYPTED PRIVATE KEY"
+
+// 信封加密PEM头
+const (
+	envelopeHeaderKeyID      = "Master-Key-Id"
+	envelopeHeaderWrappedKey = "Wrapped-Key"
+)
+
+// 信封加密错误
+var (
+	ErrEnvelopeUnavailable = errors.New("私钥加密服务不可用")
+	ErrNotEnvelope         = errors.New("不是信封加密的私钥")
+	ErrMasterKeyMismatch   = errors.New("私钥不是由当前主密钥加密的")
+)
+
+// EnvelopeEncryptor 信封加密器
+// 每个文件使用随机生成的AES数据密钥加密，数据密钥再由主密钥加密后与密文保存在一起，
+// 更换主密钥时只需重新加密数据密钥
+type EnvelopeEncryptor struct {
+	master *AESEncryptor
+	keyID  string
+}
+
+// NewEnvelopeEncryptor 使用主密钥创建信封加密器
+func NewEnvelopeEncryptor(masterKey []byte) (*EnvelopeEncryptor, error) {
+	master, err := NewAESEncryptorWithKey(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("无效的主密钥: %w", err)
+	}
+	return &EnvelopeEncryptor{master: master, keyID: MasterKeyID(masterKey)}, nil
+}
+
+// MasterKeyID 计算主密钥标识，用于识别私钥文件由哪个主密钥加密
+func MasterKeyID(masterKey []byte) string {
+	sum := sha256.Sum256(masterKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeyID 返回主密钥标识
+func (e *EnvelopeEncryptor) KeyID() string {
+	return e.keyID
+}
+
+// Seal 加密数据，返回PEM编码的信封
+func (e *EnvelopeEncryptor) Seal(plaintext []byte) ([]byte, error) {
+	dataKey, err := NewAESEncryptor(256)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := dataKey.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := e.master.Encrypt(dataKey.GetKey())
+	if err != nil {
+		return nil, fmt.Errorf("加密数据密钥失败: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: EnvelopePEMType,
+		Headers: map[string]string{
+			envelopeHeaderKeyID:      e.keyID,
+			envelopeHeaderWrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+		},
+		Bytes: ciphertext,
+	}), nil
+}
+
+// Open 解密Seal生成的信封
+func (e *EnvelopeEncryptor) Open(data []byte) ([]byte, error) {
+	block, dataKey, err := e.unwrap(data)
+	if err != nil {
+		return nil, err
+	}
+	return dataKey.Decrypt(block.Bytes)
+}
+
+// Rewrap 使用新的主密钥重新加密信封中的数据密钥，密文保持不变
+func (e *EnvelopeEncryptor) Rewrap(data []byte, to *EnvelopeEncryptor) ([]byte, error) {
+	block, dataKey, err := e.unwrap(data)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := to.master.Encrypt(dataKey.GetKey())
+	if err != nil {
+		return nil, fmt.Errorf("加密数据密钥失败: %w", err)
+	}
+
+	block.Headers[envelopeHeaderKeyID] = to.keyID
+	block.Headers[envelopeHeaderWrappedKey] = base64.StdEncoding.EncodeToString(wrapped)
+	return pem.EncodeToMemory(block), nil
+}
+
+// unwrap 解析信封并解密其中的数据密钥
+func (e *EnvelopeEncryptor) unwrap(data []byte) (*pem.Block, *AESEncryptor, error) {
+	block, err := decodeEnvelope(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if block.Headers[envelopeHeaderKeyID] != e.keyID {
+		return nil, nil, ErrMasterKeyMismatch
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(block.Headers[envelopeHeaderWrappedKey])
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析数据密钥失败: %w", err)
+	}
+	key, err := e.master.Decrypt(wrapped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解密数据密钥失败: %w", err)
+	}
+	dataKey, err := NewAESEncryptorWithKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, dataKey, nil
+}
+
+// IsEnvelope 判断数据是否为信封加密的PEM
+func IsEnvelope(data []byte) bool {
+	_, err := decodeEnvelope(data)
+	return err == nil
+}
+
+// EnvelopeKeyID 返回信封使用的主密钥标识
+func EnvelopeKeyID(data []byte) (string, error) {
+	block, err := decodeEnvelope(data)
+	if err != nil {
+		return "", err
+	}
+	return block.Headers[envelopeHeaderKeyID], nil
+}
+
+// decodeEnvelope 解码信封PEM块
+func decodeEnvelope(data []byte) (*pem.Block, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil || block.Type != EnvelopePEMType {
+		return nil, ErrNotEnvelope
+	}
+	return block, nil
+}
+
+var (
+	keyEnvelope   *EnvelopeEncryptor
+	keyEnvelopeMu sync.RWMutex
+)
+
+// InitKeyEnvelope 加载主密钥并初始化全局私钥加密器
+// 启用私钥加密时主密钥不存在则自动生成；未启用时只在主密钥存在时加载，以便读取已加密的私钥
+func InitKeyEnvelope(cfg *config.Config) error {
+	km := NewKeyManager(cfg)
+	if !cfg.KeyEncryption.Enabled {
+		if _, err := os.Stat(cfg.KeyEncryption.MasterKeyPath); err != nil {
+			return nil
+		}
+	}
+
+	envelope, err := km.LoadEnvelopeEncryptor()
+	if err != nil {
+		return err
+	}
+	SetKeyEnvelope(envelope)
+
+	if cfg.DebugLevel == "true" {
+		log.Printf("私钥加密器初始化成功，主密钥标识: %s\n", envelope.KeyID())
+	}
+	return nil
+}
+
+// SetKeyEnvelope 设置全局私钥加密器
+func SetKeyEnvelope(envelope *EnvelopeEncryptor) {
+	keyEnvelopeMu.Lock()
+	defer keyEnvelopeMu.Unlock()
+	keyEnvelope = envelope
+}
+
+// GetKeyEnvelope 获取全局私钥加密器
+func GetKeyEnvelope() (*EnvelopeEncryptor, error) {
+	keyEnvelopeMu.RLock()
+	defer keyEnvelopeMu.RUnlock()
+	if keyEnvelope == nil {
+		return nil, ErrEnvelopeUnavailable
+	}
+	return keyEnvelope, nil
+}
+
+// SealKey 按配置加密待保存的私钥，未启用私钥加密时原样返回
+func SealKey(keyPEM []byte) ([]byte, error) {
+	if !config.GetConfig().KeyEncryption.Enabled {
+		return keyPEM, nil
+	}
+	envelope, err := GetKeyEnvelope()
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Seal(keyPEM)
+}
+
+// ReadKeyFile 读取私钥文件，已加密的私钥在内存中解密后返回
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEnvelope(data) {
+		return data, nil
+	}
+
+	envelope, err := GetKeyEnvelope()
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Open(data)
+}
+
+// EncryptKeyFile 加密明文私钥文件，已加密的文件不做修改
+// 返回文件是否被加密
+func EncryptKeyFile(path string, envelope *EnvelopeEncryptor) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if IsEnvelope(data) {
+		return false, nil
+	}
+
+	sealed, err := envelope.Seal(data)
+	if err != nil {
+		return false, err
+	}
+	if err := replaceKeyFile(path, sealed); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RewrapKeyFile 将由from主密钥加密的私钥文件改为由to主密钥加密
+// 已由to主密钥加密的文件不做修改，因此中断后可以重复执行；返回文件是否被修改
+func RewrapKeyFile(path string, from, to *EnvelopeEncryptor) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	keyID, err := EnvelopeKeyID(data)
+	if err != nil {
+		return false, err
+	}
+	if keyID == to.KeyID() {
+		return false, nil
+	}
+
+	rewrapped, err := from.Rewrap(data, to)
+	if err != nil {
+		return false, err
+	}
+	if err := replaceKeyFile(path, rewrapped); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// replaceKeyFile 先写入临时文件再替换私钥文件，避免中断时私钥文件损坏
+func replaceKeyFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
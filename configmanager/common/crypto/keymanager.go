@@ -1,13 +1,20 @@
 package crypto
 
 import (
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"gin-server/config"
 	"gin-server/configmanager/common/fileutil"
+	"io"
 	"os"
 	"path/filepath"
 )
 
+// MasterKeyPEMType 主密钥文件PEM块类型
+const MasterKeyPEMType = "AES MASTER KEY"
+
 // KeyManager 密钥管理器
 type KeyManager struct {
 	config *config.Config
@@ -65,3 +72,78 @@ func (km *KeyManager) EnsureKeyPair() error {
 
 	return nil
 }
+
+// LoadTokenSigner 使用系统私钥创建令牌签名器
+func (km *KeyManager) LoadTokenSigner() (*TokenSigner, error) {
+	if err := km.EnsureKeyPair(); err != nil {
+		return nil, err
+	}
+
+	signer, err := LoadTokenSigner(km.config.ConfigManager.LogManager.Encryption.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载令牌签名密钥失败: %w", err)
+	}
+	return signer, nil
+}
+
+// EnsureMasterKey 确保私钥加密使用的主密钥存在，如果不存在则生成
+func (km *KeyManager) EnsureMasterKey() error {
+	path := km.config.KeyEncryption.MasterKeyPath
+	if fileutil.IsFileExists(path) {
+		return nil
+	}
+	if err := GenerateMasterKey(path); err != nil {
+		return fmt.Errorf("生成主密钥失败: %w", err)
+	}
+	return nil
+}
+
+// LoadEnvelopeEncryptor 使用主密钥创建私钥信封加密器
+func (km *KeyManager) LoadEnvelopeEncryptor() (*EnvelopeEncryptor, error) {
+	if err := km.EnsureMasterKey(); err != nil {
+		return nil, err
+	}
+
+	masterKey, err := LoadMasterKey(km.config.KeyEncryption.MasterKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("加载主密钥失败: %w", err)
+	}
+	return NewEnvelopeEncryptor(masterKey)
+}
+
+// GenerateMasterKey 生成256位主密钥并保存到文件，文件已存在时返回错误
+func GenerateMasterKey(path string) error {
+	if err := fileutil.EnsureDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("创建主密钥目录失败: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return fmt.Errorf("生成随机密钥失败: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("创建主密钥文件失败: %w", err)
+	}
+	defer file.Close()
+
+	if err := pem.Encode(file, &pem.Block{Type: MasterKeyPEMType, Bytes: key}); err != nil {
+		return fmt.Errorf("写入主密钥文件失败: %w", err)
+	}
+	return nil
+}
+
+// LoadMasterKey 从文件加载主密钥
+func LoadMasterKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取主密钥文件失败: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != MasterKeyPEMType {
+		return nil, errors.New("无效的主密钥文件")
+	}
+	return block.Bytes, nil
+}
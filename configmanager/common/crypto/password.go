@@ -0,0 +1,227 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"gin-server/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 口令哈希算法
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+const (
+	// argon2id盐值长度（字节）
+	argon2SaltLength = 16
+	// argon2id输出长度（字节）
+	argon2KeyLength = 32
+)
+
+// ErrInvalidPasswordHash 无效的口令哈希格式
+var ErrInvalidPasswordHash = errors.New("无效的口令哈希格式")
+
+// PasswordHasher 口令哈希器
+// 生成的哈希字符串中自带算法和参数，校验时按哈希自身记录的参数计算，
+// 因此调整配置后旧哈希仍可校验，并通过needsRehash提示调用方重新哈希
+type PasswordHasher struct {
+	algorithm   string
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	bcryptCost  int
+}
+
+// NewPasswordHasher 根据配置创建口令哈希器
+func NewPasswordHasher(cfg config.PasswordConfig) (*PasswordHasher, error) {
+	h := &PasswordHasher{
+		algorithm: cfg.Algorithm,
+	}
+
+	switch cfg.Algorithm {
+	case PasswordAlgorithmArgon2id:
+		if cfg.Argon2Memory < 8*cfg.Argon2Parallelism || cfg.Argon2Memory <= 0 {
+			return nil, fmt.Errorf("无效的argon2id内存参数: %d", cfg.Argon2Memory)
+		}
+		if cfg.Argon2Iterations < 1 {
+			return nil, fmt.Errorf("无效的argon2id迭代次数: %d", cfg.Argon2Iterations)
+		}
+		if cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > 255 {
+			return nil, fmt.Errorf("无效的argon2id并行度: %d", cfg.Argon2Parallelism)
+		}
+		h.memory = uint32(cfg.Argon2Memory)
+		h.iterations = uint32(cfg.Argon2Iterations)
+		h.parallelism = uint8(cfg.Argon2Parallelism)
+	case PasswordAlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("无效的bcrypt计算代价: %d", cfg.BcryptCost)
+		}
+		h.bcryptCost = cfg.BcryptCost
+	default:
+		return nil, fmt.Errorf("不支持的口令哈希算法: %s", cfg.Algorithm)
+	}
+
+	return h, nil
+}
+
+// DefaultPasswordHasher 使用全局配置创建口令哈希器
+// 配置无效时回退到默认参数，保证口令永远不会以明文落库
+func DefaultPasswordHasher() *PasswordHasher {
+	cfg := config.GetConfig()
+	h, err := NewPasswordHasher(cfg.Password)
+	if err != nil {
+		log.Printf("警告: 口令哈希配置无效，使用默认参数: %v\n", err)
+		h, _ = NewPasswordHasher(config.DefaultConfig().Password)
+	}
+	return h
+}
+
+// Hash 计算口令哈希
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	switch h.algorithm {
+	case PasswordAlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("计算bcrypt哈希失败: %w", err)
+		}
+		return string(hash), nil
+	default:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return "", fmt.Errorf("生成盐值失败: %w", err)
+		}
+		key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.memory, h.iterations, h.parallelism,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	}
+}
+
+// Verify 校验口令
+// match: 口令是否正确
+// needsRehash: 口令正确但哈希的算法或参数与当前配置不一致（包括历史明文口令），应重新哈希后保存
+func (h *PasswordHasher) Verify(password, encoded string) (match bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, salt, key, err := decodeArgon2Hash(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+		needsRehash = h.algorithm != PasswordAlgorithmArgon2id ||
+			params.memory != h.memory ||
+			params.iterations != h.iterations ||
+			params.parallelism != h.parallelism ||
+			len(salt) != argon2SaltLength ||
+			len(key) != argon2KeyLength
+		return true, needsRehash, nil
+	case isBcryptHash(encoded):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, fmt.Errorf("%w: %v", ErrInvalidPasswordHash, err)
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return true, true, nil
+		}
+		return true, h.algorithm != PasswordAlgorithmBcrypt || cost != h.bcryptCost, nil
+	default:
+		// 兼容尚未迁移的明文口令，校验通过后必须重新哈希
+		if encoded == "" {
+			return false, false, nil
+		}
+		if subtle.ConstantTimeCompare([]byte(password), []byte(encoded)) != 1 {
+			return false, false, nil
+		}
+		return true, true, nil
+	}
+}
+
+// HashPassword 使用全局配置计算口令哈希
+func HashPassword(password string) (string, error) {
+	return DefaultPasswordHasher().Hash(password)
+}
+
+// VerifyPassword 使用全局配置校验口令
+func VerifyPassword(password, encoded string) (match bool, needsRehash bool, err error) {
+	return DefaultPasswordHasher().Verify(password, encoded)
+}
+
+// IsPasswordHash 判断字符串是否为受支持的口令哈希格式
+func IsPasswordHash(encoded string) bool {
+	if strings.HasPrefix(encoded, "$argon2id$") {
+		_, _, _, err := decodeArgon2Hash(encoded)
+		return err == nil
+	}
+	return isBcryptHash(encoded)
+}
+
+// argon2Params argon2id哈希参数
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// decodeArgon2Hash 解析argon2id哈希字符串
+// 格式: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func decodeArgon2Hash(encoded string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, ErrInvalidPasswordHash
+	}
+	if version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("%w: 不支持的argon2版本 %d", ErrInvalidPasswordHash, version)
+	}
+
+	params := &argon2Params{}
+	var parallelism int
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &parallelism); err != nil {
+		return nil, nil, nil, ErrInvalidPasswordHash
+	}
+	if params.memory == 0 || params.iterations == 0 || parallelism < 1 || parallelism > 255 {
+		return nil, nil, nil, ErrInvalidPasswordHash
+	}
+	params.parallelism = uint8(parallelism)
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return nil, nil, nil, ErrInvalidPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrInvalidPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+// isBcryptHash 判断是否为bcrypt哈希
+func isBcryptHash(encoded string) bool {
+	if len(encoded) != 60 {
+		return false
+	}
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
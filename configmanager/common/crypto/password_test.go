@@ -0,0 +1,176 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+
+	"gin-server/config"
+)
+
+// testPasswordConfig 测试用的低开销口令哈希参数
+func testPasswordConfig(algorithm string) config.PasswordConfig {
+	return config.PasswordConfig{
+		Algorithm:         algorithm,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        4,
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.PasswordConfig)
+		wantErr bool
+	}{
+		{
+			name:    "有效的argon2id配置",
+			modify:  func(cfg *config.PasswordConfig) {},
+			wantErr: false,
+		},
+		{
+			name:    "有效的bcrypt配置",
+			modify:  func(cfg *config.PasswordConfig) { cfg.Algorithm = PasswordAlgorithmBcrypt },
+			wantErr: false,
+		},
+		{
+			name:    "不支持的算法",
+			modify:  func(cfg *config.PasswordConfig) { cfg.Algorithm = "md5" },
+			wantErr: true,
+		},
+		{
+			name:    "无效的迭代次数",
+			modify:  func(cfg *config.PasswordConfig) { cfg.Argon2Iterations = 0 },
+			wantErr: true,
+		},
+		{
+			name: "无效的bcrypt代价",
+			modify: func(cfg *config.PasswordConfig) {
+				cfg.Algorithm = PasswordAlgorithmBcrypt
+				cfg.BcryptCost = 1
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testPasswordConfig(PasswordAlgorithmArgon2id)
+			tt.modify(&cfg)
+			_, err := NewPasswordHasher(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPasswordHasher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordHashAndVerify(t *testing.T) {
+	for _, algorithm := range []string{PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewPasswordHasher(testPasswordConfig(algorithm))
+			if err != nil {
+				t.Fatalf("创建口令哈希器失败: %v", err)
+			}
+
+			hash, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("计算口令哈希失败: %v", err)
+			}
+			if strings.Contains(hash, "correct horse") {
+				t.Error("哈希中不应包含明文口令")
+			}
+			if !IsPasswordHash(hash) {
+				t.Errorf("IsPasswordHash(%q) = false, want true", hash)
+			}
+
+			// 相同口令两次哈希结果应不同（随机盐）
+			hash2, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("计算口令哈希失败: %v", err)
+			}
+			if hash == hash2 {
+				t.Error("相同口令的两次哈希结果不应相同")
+			}
+
+			match, needsRehash, err := hasher.Verify("correct horse", hash)
+			if err != nil || !match || needsRehash {
+				t.Errorf("Verify(正确口令) = %v, %v, %v; want true, false, nil", match, needsRehash, err)
+			}
+
+			match, _, err = hasher.Verify("wrong horse", hash)
+			if err != nil || match {
+				t.Errorf("Verify(错误口令) = %v, %v; want false, nil", match, err)
+			}
+		})
+	}
+}
+
+func TestPasswordVerifyNeedsRehash(t *testing.T) {
+	oldCfg := testPasswordConfig(PasswordAlgorithmArgon2id)
+	oldHasher, err := NewPasswordHasher(oldCfg)
+	if err != nil {
+		t.Fatalf("创建口令哈希器失败: %v", err)
+	}
+	hash, err := oldHasher.Hash("secret123")
+	if err != nil {
+		t.Fatalf("计算口令哈希失败: %v", err)
+	}
+
+	// 参数调整后旧哈希仍可校验，但需要重新哈希
+	newCfg := oldCfg
+	newCfg.Argon2Iterations = 2
+	newHasher, err := NewPasswordHasher(newCfg)
+	if err != nil {
+		t.Fatalf("创建口令哈希器失败: %v", err)
+	}
+	match, needsRehash, err := newHasher.Verify("secret123", hash)
+	if err != nil || !match || !needsRehash {
+		t.Errorf("Verify(旧参数) = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+
+	// 切换算法后同样需要重新哈希
+	bcryptHasher, err := NewPasswordHasher(testPasswordConfig(PasswordAlgorithmBcrypt))
+	if err != nil {
+		t.Fatalf("创建口令哈希器失败: %v", err)
+	}
+	match, needsRehash, err = bcryptHasher.Verify("secret123", hash)
+	if err != nil || !match || !needsRehash {
+		t.Errorf("Verify(切换算法) = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+}
+
+func TestPasswordVerifyPlaintext(t *testing.T) {
+	hasher, err := NewPasswordHasher(testPasswordConfig(PasswordAlgorithmArgon2id))
+	if err != nil {
+		t.Fatalf("创建口令哈希器失败: %v", err)
+	}
+
+	// 历史明文口令校验通过后必须重新哈希
+	match, needsRehash, err := hasher.Verify("admin123456", "admin123456")
+	if err != nil || !match || !needsRehash {
+		t.Errorf("Verify(明文) = %v, %v, %v; want true, true, nil", match, needsRehash, err)
+	}
+
+	match, _, _ = hasher.Verify("", "")
+	if match {
+		t.Error("空口令不应校验通过")
+	}
+
+	if IsPasswordHash("admin123456") {
+		t.Error("明文口令不应被识别为哈希")
+	}
+}
+
+func TestPasswordVerifyMalformedHash(t *testing.T) {
+	hasher, err := NewPasswordHasher(testPasswordConfig(PasswordAlgorithmArgon2id))
+	if err != nil {
+		t.Fatalf("创建口令哈希器失败: %v", err)
+	}
+
+	_, _, err = hasher.Verify("secret", "$argon2id$v=19$m=bad$salt$hash")
+	if err == nil {
+		t.Error("格式错误的哈希应返回错误")
+	}
+}
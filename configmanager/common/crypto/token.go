@@ -0,0 +1,298 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// 令牌类型
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	TokenTypeDevice  = "device"
+)
+
+// 令牌校验错误
+var (
+	ErrTokenMalformed = errors.New("令牌格式错误")
+	ErrTokenSignature = errors.New("令牌签名无效")
+	ErrTokenExpired   = errors.New("令牌已过期")
+)
+
+// TokenClaims 令牌声明
+// 字段名与JWT标准声明保持一致，签发的令牌可被标准JWT库解析
+type TokenClaims struct {
+	ID        string `json:"jti"`            // 令牌唯一标识，用于吊销
+	Subject   string `json:"sub"`            // 令牌主体（用户或设备唯一标识）
+	Issuer    string `json:"iss,omitempty"`  // 签发者
+	Type      string `json:"typ"`            // 令牌类型，access、refresh或device
+	Name      string `json:"name,omitempty"` // 用户名
+	Mask      string `json:"perm,omitempty"` // 权限位掩码
+	IssuedAt  int64  `json:"iat"`            // 签发时间（Unix秒）
+	ExpiresAt int64  `json:"exp"`            // 过期时间（Unix秒）
+}
+
+// ExpiresTime 返回过期时间
+func (c *TokenClaims) ExpiresTime() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// tokenHeader 令牌头部
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+// TokenSigner 令牌签名器
+// 使用系统密钥对签发和校验令牌，签名算法由私钥类型决定：
+// RSA使用RS256，ECDSA按曲线使用ES256/ES384/ES512，ED25519使用EdDSA
+type TokenSigner struct {
+	algorithm  string
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+	now        func() time.Time
+}
+
+// NewTokenSigner 使用私钥创建令牌签名器
+func NewTokenSigner(privateKey crypto.Signer) (*TokenSigner, error) {
+	s := &TokenSigner{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public(),
+		now:        time.Now,
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		s.algorithm = "RS256"
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			s.algorithm = "ES256"
+		case 384:
+			s.algorithm = "ES384"
+		case 521:
+			s.algorithm = "ES512"
+		default:
+			return nil, fmt.Errorf("不支持的ECDSA曲线: %s", key.Curve.Params().Name)
+		}
+	case ed25519.PrivateKey:
+		s.algorithm = "EdDSA"
+	default:
+		return nil, fmt.Errorf("不支持的私钥类型: %T", privateKey)
+	}
+
+	return s, nil
+}
+
+// LoadTokenSigner 从私钥文件加载令牌签名器
+// 支持KeyManager生成的RSA、ECDSA和ED25519私钥文件
+func LoadTokenSigner(privateKeyPath string) (*TokenSigner, error) {
+	data, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("无效的私钥文件")
+	}
+
+	var privateKey crypto.Signer
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "ECDSA PRIVATE KEY", "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "ED25519 PRIVATE KEY":
+		if len(block.Bytes) != ed25519.PrivateKeySize {
+			return nil, errors.New("无效的ED25519私钥长度")
+		}
+		privateKey = ed25519.PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("不支持的私钥类型: %s", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+
+	return NewTokenSigner(privateKey)
+}
+
+// Algorithm 返回签名算法名称
+func (s *TokenSigner) Algorithm() string {
+	return s.algorithm
+}
+
+// Issue 签发令牌
+// 自动填充令牌唯一标识、签发时间和过期时间
+func (s *TokenSigner) Issue(claims TokenClaims, ttl time.Duration) (string, *TokenClaims, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := s.now()
+	claims.ID = id
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	token, err := s.Sign(&claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
+}
+
+// Sign 对令牌声明签名，返回紧凑格式的令牌字符串
+func (s *TokenSigner) Sign(claims *TokenClaims) (string, error) {
+	headerJSON, err := json.Marshal(tokenHeader{Algorithm: s.algorithm, Type: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("序列化令牌头部失败: %w", err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("序列化令牌声明失败: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	signature, err := s.sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("令牌签名失败: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify 校验令牌签名和有效期，返回令牌声明
+func (s *TokenSigner) Verify(token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var header tokenHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	// 只接受签名器自身的算法，防止算法替换攻击
+	if subtle.ConstantTimeCompare([]byte(header.Algorithm), []byte(s.algorithm)) != 1 {
+		return nil, ErrTokenSignature
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !s.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrTokenSignature
+	}
+
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	var claims TokenClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// sign 按签名算法计算签名
+func (s *TokenSigner) sign(input []byte) ([]byte, error) {
+	switch key := s.privateKey.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		digest := ecdsaDigest(s.algorithm, input)
+		r, ss, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS要求ECDSA签名为定长的R||S
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		ss.FillBytes(signature[size:])
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, input), nil
+	default:
+		return nil, fmt.Errorf("不支持的私钥类型: %T", s.privateKey)
+	}
+}
+
+// verify 按签名算法校验签名
+func (s *TokenSigner) verify(input, signature []byte) bool {
+	switch key := s.publicKey.(type) {
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(input)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		ss := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, ecdsaDigest(s.algorithm, input), r, ss)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, input, signature)
+	default:
+		return false
+	}
+}
+
+// ecdsaDigest 计算ECDSA签名使用的摘要
+func ecdsaDigest(algorithm string, input []byte) []byte {
+	switch algorithm {
+	case "ES384":
+		digest := sha512.Sum384(input)
+		return digest[:]
+	case "ES512":
+		digest := sha512.Sum512(input)
+		return digest[:]
+	default:
+		digest := sha256.Sum256(input)
+		return digest[:]
+	}
+}
+
+// newTokenID 生成随机令牌唯一标识
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", fmt.Errorf("生成令牌标识失败: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
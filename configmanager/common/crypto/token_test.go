@@ -0,0 +1,156 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// generateTestSigners 生成各算法的测试私钥
+func generateTestSigners(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成RSA密钥失败: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成ECDSA密钥失败: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成ED25519密钥失败: %v", err)
+	}
+
+	return map[string]crypto.Signer{
+		"RS256": rsaKey,
+		"ES384": ecKey,
+		"EdDSA": edKey,
+	}
+}
+
+func TestTokenSignAndVerify(t *testing.T) {
+	for algorithm, key := range generateTestSigners(t) {
+		t.Run(algorithm, func(t *testing.T) {
+			signer, err := NewTokenSigner(key)
+			if err != nil {
+				t.Fatalf("创建令牌签名器失败: %v", err)
+			}
+			if signer.Algorithm() != algorithm {
+				t.Errorf("Algorithm() = %s, want %s", signer.Algorithm(), algorithm)
+			}
+
+			token, issued, err := signer.Issue(TokenClaims{Subject: "10001", Type: TokenTypeAccess}, time.Minute)
+			if err != nil {
+				t.Fatalf("签发令牌失败: %v", err)
+			}
+			if issued.ID == "" {
+				t.Error("令牌唯一标识不应为空")
+			}
+
+			claims, err := signer.Verify(token)
+			if err != nil {
+				t.Fatalf("校验令牌失败: %v", err)
+			}
+			if claims.Subject != "10001" || claims.Type != TokenTypeAccess || claims.ID != issued.ID {
+				t.Errorf("令牌声明不一致: %+v", claims)
+			}
+
+			// 篡改声明后签名应失效
+			parts := strings.Split(token, ".")
+			tampered, _ := signer.Sign(&TokenClaims{Subject: "1", Type: TokenTypeAccess, ExpiresAt: issued.ExpiresAt})
+			forged := parts[0] + "." + strings.Split(tampered, ".")[1] + "." + parts[2]
+			if _, err := signer.Verify(forged); !errors.Is(err, ErrTokenSignature) {
+				t.Errorf("Verify(篡改令牌) error = %v, want %v", err, ErrTokenSignature)
+			}
+		})
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成ECDSA密钥失败: %v", err)
+	}
+	signer, err := NewTokenSigner(key)
+	if err != nil {
+		t.Fatalf("创建令牌签名器失败: %v", err)
+	}
+
+	token, _, err := signer.Issue(TokenClaims{Subject: "10001", Type: TokenTypeAccess}, time.Minute)
+	if err != nil {
+		t.Fatalf("签发令牌失败: %v", err)
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := signer.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Verify(过期令牌) error = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+func TestTokenRejectsOtherKey(t *testing.T) {
+	signers := generateTestSigners(t)
+	signer, _ := NewTokenSigner(signers["RS256"])
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherSigner, _ := NewTokenSigner(other)
+
+	token, _, err := otherSigner.Issue(TokenClaims{Subject: "10001", Type: TokenTypeAccess}, time.Minute)
+	if err != nil {
+		t.Fatalf("签发令牌失败: %v", err)
+	}
+	if _, err := signer.Verify(token); !errors.Is(err, ErrTokenSignature) {
+		t.Errorf("Verify(其他密钥签发) error = %v, want %v", err, ErrTokenSignature)
+	}
+
+	if _, err := signer.Verify("not-a-token"); !errors.Is(err, ErrTokenMalformed) {
+		t.Errorf("Verify(格式错误) error = %v, want %v", err, ErrTokenMalformed)
+	}
+}
+
+func TestLoadTokenSigner(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		algorithm string
+		keyLength int
+		want      string
+	}{
+		{name: "RSA私钥", algorithm: "RSA", keyLength: 2048, want: "RS256"},
+		{name: "ECDSA私钥", algorithm: "ECDSA", keyLength: 256, want: "ES256"},
+		{name: "ED25519私钥", algorithm: "ED25519", want: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptor, err := CreateAsymmetricEncryptor(tt.algorithm, tt.keyLength)
+			if err != nil {
+				t.Fatalf("创建非对称加密器失败: %v", err)
+			}
+			if err := encryptor.GenerateKeyPair(); err != nil {
+				t.Fatalf("生成密钥对失败: %v", err)
+			}
+			privateKeyPath := filepath.Join(tempDir, tt.algorithm+"_private.pem")
+			if err := encryptor.SavePrivateKey(privateKeyPath); err != nil {
+				t.Fatalf("保存私钥失败: %v", err)
+			}
+
+			signer, err := LoadTokenSigner(privateKeyPath)
+			if err != nil {
+				t.Fatalf("LoadTokenSigner() error = %v", err)
+			}
+			if signer.Algorithm() != tt.want {
+				t.Errorf("Algorithm() = %s, want %s", signer.Algorithm(), tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"time"
 
+	"gin-server/auth/middleware"
+	"gin-server/auth/permission"
 	"gin-server/database/models"
 
 	"github.com/gin-gonic/gin"
@@ -14,9 +16,15 @@ import (
 func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 	// 直接使用 "/logs" 作为路由组前缀
 	logGroup := router.Group("/logs")
+
+	// 各路由所需的权限
+	logRead := middleware.RequirePermission(permission.LogRead)
+	logGenerate := middleware.RequirePermission(permission.LogGenerate)
+	// 事件和用户行为由设备上报，也允许拥有事件上报权限的用户写入
+	eventWrite := middleware.RequireDeviceOrPermission(permission.EventWrite)
 	{
 		// 最终路径将是 "/logs/latest"
-		logGroup.GET("/latest", func(c *gin.Context) {
+		logGroup.GET("/latest", logRead, func(c *gin.Context) {
 			content, err := logManager.GetLatestLogContent()
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -28,7 +36,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 生成日志 "/logs/generate"
-		logGroup.POST("/generate", func(c *gin.Context) {
+		logGroup.POST("/generate", logGenerate, func(c *gin.Context) {
 			if err := logManager.GenerateLog(); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error": err.Error(),
@@ -41,7 +49,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 获取远程日志文件列表 "/logs/files"
-		logGroup.GET("/files", func(c *gin.Context) {
+		logGroup.GET("/files", logRead, func(c *gin.Context) {
 			files, err := logManager.ListRemoteLogFiles()
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
@@ -53,7 +61,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 根据时间范围查询日志文件 "/logs/files/search"
-		logGroup.GET("/files/search", func(c *gin.Context) {
+		logGroup.GET("/files/search", logRead, func(c *gin.Context) {
 			startTimeStr := c.Query("start_time")
 			endTimeStr := c.Query("end_time")
 
@@ -101,7 +109,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 创建事件 "/logs/events"
-		logGroup.POST("/events", func(c *gin.Context) {
+		logGroup.POST("/events", eventWrite, func(c *gin.Context) {
 			type EventRequest struct {
 				EventCode string `json:"event_code" binding:"required"`
 				EventDesc string `json:"event_desc" binding:"required"`
@@ -136,7 +144,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 查询事件 "/logs/events/search"
-		logGroup.GET("/events/search", func(c *gin.Context) {
+		logGroup.GET("/events/search", logRead, func(c *gin.Context) {
 			startTimeStr := c.Query("start_time")
 			endTimeStr := c.Query("end_time")
 
@@ -184,7 +192,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 记录用户行为 "/logs/behaviors"
-		logGroup.POST("/behaviors", func(c *gin.Context) {
+		logGroup.POST("/behaviors", eventWrite, func(c *gin.Context) {
 			type BehaviorRequest struct {
 				UserID       int   `json:"user_id" binding:"required"`
 				BehaviorType int   `json:"behavior_type" binding:"required"`
@@ -216,7 +224,7 @@ func RegisterRoutes(router *gin.Engine, logManager *LogManager) {
 		})
 
 		// 查询用户行为 "/logs/behaviors/:user_id"
-		logGroup.GET("/behaviors/:user_id", func(c *gin.Context) {
+		logGroup.GET("/behaviors/:user_id", logRead, func(c *gin.Context) {
 			userIDStr := c.Param("user_id")
 			userID, err := strconv.Atoi(userIDStr)
 			if err != nil {
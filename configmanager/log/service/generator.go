@@ -6,8 +6,10 @@ import (
 	"log"
 	"time"
 
+	"gin-server/config"
 	"gin-server/configmanager/common/alert"
 	"gin-server/configmanager/common/fileutil"
+	"gin-server/database/metrics"
 	"gin-server/database/models"
 	"gin-server/database/repositories"
 
@@ -30,6 +32,7 @@ type Generator struct {
 	deviceRepository   repositories.DeviceRepository
 	userRepository     repositories.UserRepository
 	behaviorRepository repositories.UserBehaviorRepository
+	metricRepository   repositories.DeviceMetricRepository
 }
 
 // NewGenerator 创建日志生成器实例
@@ -42,6 +45,7 @@ func NewGenerator(db *gorm.DB, alerter alert.Alerter) *Generator {
 		deviceRepository:   repoFactory.GetDeviceRepository(),
 		userRepository:     repoFactory.GetUserRepository(),
 		behaviorRepository: repoFactory.GetUserBehaviorRepository(),
+		metricRepository:   repoFactory.GetDeviceMetricRepository(),
 	}
 }
 
@@ -232,10 +236,11 @@ func (g *Generator) getSecurityDevices(startTime, endTime time.Time) ([]models.S
 		}
 
 		// 创建安全设备对象
+		cpuUsage, memoryUsage := g.getDevicePeaks(&device, startTime, endTime)
 		securityDevice := models.SecurityDevice{
 			DeviceID:       device.DeviceID,
-			CPUUsage:       device.PeakCPUUsage,
-			MemoryUsage:    device.PeakMemoryUsage,
+			CPUUsage:       cpuUsage,
+			MemoryUsage:    memoryUsage,
 			OnlineDuration: device.OnlineDuration,
 			Status:         device.DeviceStatus,
 			GatewayDevices: gatewayDevices,
@@ -268,10 +273,11 @@ func (g *Generator) getGatewayDevices(securityDeviceID int, startTime, endTime t
 		}
 
 		// 创建网关设备对象
+		cpuUsage, memoryUsage := g.getDevicePeaks(&device, startTime, endTime)
 		gatewayDevice := models.GatewayDevice{
 			DeviceID:       device.DeviceID,
-			CPUUsage:       device.PeakCPUUsage,
-			MemoryUsage:    device.PeakMemoryUsage,
+			CPUUsage:       cpuUsage,
+			MemoryUsage:    memoryUsage,
 			OnlineDuration: device.OnlineDuration,
 			Status:         device.DeviceStatus,
 			Users:          users,
@@ -283,6 +289,21 @@ func (g *Generator) getGatewayDevices(securityDeviceID int, startTime, endTime t
 	return gatewayDevices, nil
 }
 
+// getDevicePeaks 获取设备在时间范围内的CPU和内存使用率峰值
+// 时间范围内没有性能数据或查询失败时使用设备表中维护的峰值
+func (g *Generator) getDevicePeaks(device *models.Device, startTime, endTime time.Time) (int, int) {
+	cpuUsage, memoryUsage, found, err := metrics.DeviceMetricPeak(g.metricRepository,
+		&config.GetConfig().Metrics, device.DeviceID, startTime, endTime)
+	if err != nil {
+		log.Printf("查询设备 %d 的性能数据失败，使用设备表中的峰值: %v\n", device.DeviceID, err)
+		return device.PeakCPUUsage, device.PeakMemoryUsage
+	}
+	if !found {
+		return device.PeakCPUUsage, device.PeakMemoryUsage
+	}
+	return cpuUsage, memoryUsage
+}
+
 // getUsers 获取用户
 func (g *Generator) getUsers(gatewayDeviceID int, startTime, endTime time.Time) ([]models.UserInfo, error) {
 	var userInfos []models.UserInfo
@@ -0,0 +1,297 @@
+// Package metrics 提供设备性能数据的查询和聚合，供注册管理和日志生成共用
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+)
+
+const (
+	// maxMetricPoints 单次查询允许返回的最大数据点数
+	maxMetricPoints = 10000
+	// maxMetricSamples 单次查询允许读取的最大原始采样数，按步长聚合原始采样时也不能超过
+	maxMetricSamples = 100000
+)
+
+// 性能数据查询错误
+var (
+	ErrInvalidMetricRange   = errors.New("结束时间必须晚于开始时间")
+	ErrTooManyMetricPoints  = errors.New("查询的数据点过多，请缩小时间范围或增大步长")
+	ErrTooManyMetricSamples = errors.New("查询范围内的原始采样过多，请缩小时间范围")
+)
+
+// MetricPoint 性能数据点，原始采样的最小值、最大值和平均值相同
+type MetricPoint struct {
+	Time        time.Time `json:"time"`         // 区间起始时间或采样时间
+	SampleCount int       `json:"sample_count"` // 区间内的采样数
+	CPUMin      int       `json:"cpu_min"`      // CPU使用率最小值
+	CPUMax      int       `json:"cpu_max"`      // CPU使用率最大值
+	CPUAvg      float64   `json:"cpu_avg"`      // CPU使用率平均值
+	MemoryMin   int       `json:"memory_min"`   // 内存使用率最小值
+	MemoryMax   int       `json:"memory_max"`   // 内存使用率最大值
+	MemoryAvg   float64   `json:"memory_avg"`   // 内存使用率平均值
+}
+
+// MetricSeries 设备性能数据查询结果
+type MetricSeries struct {
+	DeviceID   int           `json:"device_id"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	Step       int           `json:"step"`       // 数据点的间隔（秒），0表示返回原始采样
+	Resolution int           `json:"resolution"` // 数据来源的精度（秒），0表示原始采样
+	Points     []MetricPoint `json:"points"`
+}
+
+// MetricSource 选择查询使用的数据来源精度，0表示原始采样
+// 使用保留时长覆盖查询起始时间的最细精度；未开启聚合时只有原始采样
+func MetricSource(cfg *config.MetricsConfig, from, now time.Time) int {
+	if cfg.RollupInterval <= 0 || !from.Before(RetentionStart(cfg.RawRetention, now)) {
+		return 0
+	}
+	if !from.Before(RetentionStart(cfg.MinuteRetention, now)) {
+		return models.MetricResolutionMinute
+	}
+	return models.MetricResolutionHour
+}
+
+// MetricStep 将请求的步长（秒）调整为数据来源精度的整数倍
+// 步长为0时，原始采样逐条返回，聚合数据按来源精度返回
+func MetricStep(resolution, step int) int {
+	if step <= 0 {
+		return resolution
+	}
+	if resolution > 0 && step%resolution != 0 {
+		step += resolution - step%resolution
+	}
+	return step
+}
+
+// QueryDeviceMetrics 查询设备在[from, to)内的性能数据，step为数据点的间隔（秒）
+func QueryDeviceMetrics(metricRepo repositories.DeviceMetricRepository, cfg *config.MetricsConfig, deviceID int, from, to time.Time, step int) (*MetricSeries, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidMetricRange
+	}
+
+	resolution := MetricSource(cfg, from, time.Now())
+	step = MetricStep(resolution, step)
+	if step > 0 && to.Sub(from)/(time.Duration(step)*time.Second) > maxMetricPoints {
+		return nil, ErrTooManyMetricPoints
+	}
+
+	series := &MetricSeries{
+		DeviceID:   deviceID,
+		From:       from,
+		To:         to,
+		Step:       step,
+		Resolution: resolution,
+		Points:     []MetricPoint{},
+	}
+
+	if resolution == 0 {
+		// 逐条返回时采样数即数据点数
+		limit, limitErr := maxMetricSamples, ErrTooManyMetricSamples
+		if step == 0 {
+			limit, limitErr = maxMetricPoints, ErrTooManyMetricPoints
+		}
+		samples, err := findSamples(metricRepo, deviceID, from, to, limit, limitErr)
+		if err != nil {
+			return nil, err
+		}
+		if step == 0 {
+			for _, sample := range samples {
+				series.Points = append(series.Points, samplePoint(sample))
+			}
+			return series, nil
+		}
+		series.Points = rollupPoints(AggregateSamples(samples, step))
+		return series, nil
+	}
+
+	bucketFrom := from.Truncate(time.Duration(resolution) * time.Second)
+	rollups, err := findRollups(metricRepo, deviceID, resolution, bucketFrom, to)
+	if err != nil {
+		return nil, err
+	}
+	if step != resolution {
+		rollups = MergeRollups(rollups, step)
+	}
+	series.Points = rollupPoints(rollups)
+	return series, nil
+}
+
+// findRollups 查找[from, to)内resolution精度的聚合数据
+// 最新一个聚合区间之后的数据尚未聚合，由更细精度的数据补齐：小时数据由分钟数据补齐，分钟数据由原始采样补齐
+func findRollups(metricRepo repositories.DeviceMetricRepository, deviceID, resolution int, from, to time.Time) ([]models.DeviceMetricRollup, error) {
+	rollups, err := metricRepo.FindRollups(deviceID, resolution, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	tailFrom := from
+	latest, err := metricRepo.LatestRollupStart(resolution)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		if end := latest.Add(time.Duration(resolution) * time.Second); end.After(tailFrom) {
+			tailFrom = end
+		}
+	}
+	if !tailFrom.Before(to) {
+		return rollups, nil
+	}
+
+	var tail []models.DeviceMetricRollup
+	if resolution == models.MetricResolutionHour {
+		minutes, err := findRollups(metricRepo, deviceID, models.MetricResolutionMinute, tailFrom, to)
+		if err != nil {
+			return nil, err
+		}
+		tail = MergeRollups(minutes, resolution)
+	} else {
+		samples, err := findSamples(metricRepo, deviceID, tailFrom, to, maxMetricSamples, ErrTooManyMetricSamples)
+		if err != nil {
+			return nil, err
+		}
+		tail = AggregateSamples(samples, resolution)
+	}
+	return append(rollups, tail...), nil
+}
+
+// findSamples 查找[from, to)内的原始采样，超过limit条时返回limitErr
+func findSamples(metricRepo repositories.DeviceMetricRepository, deviceID int, from, to time.Time, limit int, limitErr error) ([]models.DeviceMetric, error) {
+	samples, err := metricRepo.FindSamples(deviceID, from, to, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) > limit {
+		return nil, limitErr
+	}
+	return samples, nil
+}
+
+// DeviceMetricPeak 返回设备在[from, to)内的CPU和内存使用率峰值，没有性能数据时found为false
+func DeviceMetricPeak(metricRepo repositories.DeviceMetricRepository, cfg *config.MetricsConfig, deviceID int, from, to time.Time) (cpu, memory int, found bool, err error) {
+	// 整个范围聚合为一个数据点，原始采样较多时也不会超过返回的数据点数上限
+	step := int((to.Sub(from) + time.Second - 1) / time.Second)
+	series, err := QueryDeviceMetrics(metricRepo, cfg, deviceID, from, to, max(step, 1))
+	if err != nil {
+		return 0, 0, false, err
+	}
+	for _, point := range series.Points {
+		cpu = max(cpu, point.CPUMax)
+		memory = max(memory, point.MemoryMax)
+	}
+	return cpu, memory, len(series.Points) > 0, nil
+}
+
+// AggregateSamples 将性能采样按设备和resolution秒对齐的区间聚合，结果按区间起始时间和设备排序
+func AggregateSamples(samples []models.DeviceMetric, resolution int) []models.DeviceMetricRollup {
+	rollups := make([]models.DeviceMetricRollup, 0, len(samples))
+	for _, sample := range samples {
+		rollups = append(rollups, models.DeviceMetricRollup{
+			DeviceID:    sample.DeviceID,
+			BucketStart: sample.SampledAt,
+			SampleCount: 1,
+			CPUMin:      sample.CPUUsage,
+			CPUMax:      sample.CPUUsage,
+			CPUAvg:      float64(sample.CPUUsage),
+			MemoryMin:   sample.MemoryUsage,
+			MemoryMax:   sample.MemoryUsage,
+			MemoryAvg:   float64(sample.MemoryUsage),
+		})
+	}
+	return MergeRollups(rollups, resolution)
+}
+
+// MergeRollups 将聚合数据按设备和resolution秒对齐的区间合并为更粗精度的聚合数据
+// 平均值按采样数加权，结果按区间起始时间和设备排序
+func MergeRollups(rollups []models.DeviceMetricRollup, resolution int) []models.DeviceMetricRollup {
+	type bucketKey struct {
+		deviceID int
+		start    time.Time
+	}
+
+	width := time.Duration(resolution) * time.Second
+	buckets := make(map[bucketKey]*models.DeviceMetricRollup)
+	for _, rollup := range rollups {
+		if rollup.SampleCount <= 0 {
+			continue
+		}
+		key := bucketKey{deviceID: rollup.DeviceID, start: rollup.BucketStart.Truncate(width)}
+		bucket, ok := buckets[key]
+		if !ok {
+			merged := rollup
+			merged.ID = 0
+			merged.Resolution = resolution
+			merged.BucketStart = key.start
+			buckets[key] = &merged
+			continue
+		}
+		count := float64(bucket.SampleCount + rollup.SampleCount)
+		bucket.CPUAvg = (bucket.CPUAvg*float64(bucket.SampleCount) + rollup.CPUAvg*float64(rollup.SampleCount)) / count
+		bucket.MemoryAvg = (bucket.MemoryAvg*float64(bucket.SampleCount) + rollup.MemoryAvg*float64(rollup.SampleCount)) / count
+		bucket.SampleCount += rollup.SampleCount
+		bucket.CPUMin = min(bucket.CPUMin, rollup.CPUMin)
+		bucket.CPUMax = max(bucket.CPUMax, rollup.CPUMax)
+		bucket.MemoryMin = min(bucket.MemoryMin, rollup.MemoryMin)
+		bucket.MemoryMax = max(bucket.MemoryMax, rollup.MemoryMax)
+	}
+
+	merged := make([]models.DeviceMetricRollup, 0, len(buckets))
+	for _, bucket := range buckets {
+		merged = append(merged, *bucket)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].BucketStart.Equal(merged[j].BucketStart) {
+			return merged[i].BucketStart.Before(merged[j].BucketStart)
+		}
+		return merged[i].DeviceID < merged[j].DeviceID
+	})
+	return merged
+}
+
+// samplePoint 将原始采样转换为数据点
+func samplePoint(sample models.DeviceMetric) MetricPoint {
+	return MetricPoint{
+		Time:        sample.SampledAt,
+		SampleCount: 1,
+		CPUMin:      sample.CPUUsage,
+		CPUMax:      sample.CPUUsage,
+		CPUAvg:      float64(sample.CPUUsage),
+		MemoryMin:   sample.MemoryUsage,
+		MemoryMax:   sample.MemoryUsage,
+		MemoryAvg:   float64(sample.MemoryUsage),
+	}
+}
+
+// rollupPoints 将聚合数据转换为数据点
+func rollupPoints(rollups []models.DeviceMetricRollup) []MetricPoint {
+	points := make([]MetricPoint, 0, len(rollups))
+	for _, rollup := range rollups {
+		points = append(points, MetricPoint{
+			Time:        rollup.BucketStart,
+			SampleCount: rollup.SampleCount,
+			CPUMin:      rollup.CPUMin,
+			CPUMax:      rollup.CPUMax,
+			CPUAvg:      rollup.CPUAvg,
+			MemoryMin:   rollup.MemoryMin,
+			MemoryMax:   rollup.MemoryMax,
+			MemoryAvg:   rollup.MemoryAvg,
+		})
+	}
+	return points
+}
+
+// RetentionStart 返回保留时长（小时）对应的最早保留时间，保留时长不大于0表示永久保留
+func RetentionStart(hours int, now time.Time) time.Time {
+	if hours <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-time.Duration(hours) * time.Hour)
+}
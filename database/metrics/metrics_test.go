@@ -0,0 +1,259 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+)
+
+// fakeMetricRepo 内存中的性能数据仓库
+type fakeMetricRepo struct {
+	repositories.DeviceMetricRepository
+	samples []models.DeviceMetric
+	rollups []models.DeviceMetricRollup
+}
+
+func (r *fakeMetricRepo) FindSamples(deviceID int, from, to time.Time, limit int) ([]models.DeviceMetric, error) {
+	var samples []models.DeviceMetric
+	for _, sample := range r.samples {
+		if limit > 0 && len(samples) == limit {
+			break
+		}
+		if (deviceID == 0 || sample.DeviceID == deviceID) && !sample.SampledAt.Before(from) && sample.SampledAt.Before(to) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
+func (r *fakeMetricRepo) FindRollups(deviceID int, resolution int, from, to time.Time) ([]models.DeviceMetricRollup, error) {
+	var rollups []models.DeviceMetricRollup
+	for _, rollup := range r.rollups {
+		if (deviceID == 0 || rollup.DeviceID == deviceID) && rollup.Resolution == resolution &&
+			!rollup.BucketStart.Before(from) && rollup.BucketStart.Before(to) {
+			rollups = append(rollups, rollup)
+		}
+	}
+	return rollups, nil
+}
+
+func (r *fakeMetricRepo) LatestRollupStart(resolution int) (*time.Time, error) {
+	var latest *time.Time
+	for i := range r.rollups {
+		rollup := &r.rollups[i]
+		if rollup.Resolution == resolution && (latest == nil || rollup.BucketStart.After(*latest)) {
+			latest = &rollup.BucketStart
+		}
+	}
+	return latest, nil
+}
+
+// testRollup 创建CPU和内存使用率均为value的聚合数据
+func testRollup(resolution int, start time.Time, count, value int) models.DeviceMetricRollup {
+	return models.DeviceMetricRollup{
+		DeviceID: 1, Resolution: resolution, BucketStart: start, SampleCount: count,
+		CPUMin: value, CPUMax: value, CPUAvg: float64(value),
+		MemoryMin: value, MemoryMax: value, MemoryAvg: float64(value),
+	}
+}
+
+func TestAggregateSamples(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	samples := []models.DeviceMetric{
+		{DeviceID: 1, SampledAt: base.Add(5 * time.Second), CPUUsage: 10, MemoryUsage: 40},
+		{DeviceID: 1, SampledAt: base.Add(35 * time.Second), CPUUsage: 30, MemoryUsage: 20},
+		{DeviceID: 2, SampledAt: base.Add(20 * time.Second), CPUUsage: 50, MemoryUsage: 60},
+		{DeviceID: 1, SampledAt: base.Add(65 * time.Second), CPUUsage: 70, MemoryUsage: 80},
+	}
+
+	rollups := AggregateSamples(samples, models.MetricResolutionMinute)
+	if len(rollups) != 3 {
+		t.Fatalf("AggregateSamples() 返回 %d 条, want 3", len(rollups))
+	}
+
+	first := rollups[0]
+	if first.DeviceID != 1 || !first.BucketStart.Equal(base) || first.Resolution != models.MetricResolutionMinute {
+		t.Errorf("第一条聚合数据 = %+v, want 设备1 %v 的分钟数据", first, base)
+	}
+	if first.SampleCount != 2 || first.CPUMin != 10 || first.CPUMax != 30 || first.CPUAvg != 20 {
+		t.Errorf("设备1 CPU聚合 = count %d min %d max %d avg %v, want 2 10 30 20",
+			first.SampleCount, first.CPUMin, first.CPUMax, first.CPUAvg)
+	}
+	if first.MemoryMin != 20 || first.MemoryMax != 40 || first.MemoryAvg != 30 {
+		t.Errorf("设备1 内存聚合 = min %d max %d avg %v, want 20 40 30", first.MemoryMin, first.MemoryMax, first.MemoryAvg)
+	}
+	if rollups[1].DeviceID != 2 || !rollups[1].BucketStart.Equal(base) {
+		t.Errorf("第二条聚合数据 = %+v, want 设备2 %v", rollups[1], base)
+	}
+	if rollups[2].DeviceID != 1 || !rollups[2].BucketStart.Equal(base.Add(time.Minute)) || rollups[2].SampleCount != 1 {
+		t.Errorf("第三条聚合数据 = %+v, want 设备1 %v 的1条采样", rollups[2], base.Add(time.Minute))
+	}
+}
+
+func TestMergeRollupsWeightedAverage(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	rollups := []models.DeviceMetricRollup{
+		{DeviceID: 1, Resolution: 60, BucketStart: base, SampleCount: 1, CPUMin: 90, CPUMax: 90, CPUAvg: 90, MemoryMin: 10, MemoryMax: 10, MemoryAvg: 10},
+		{DeviceID: 1, Resolution: 60, BucketStart: base.Add(59 * time.Minute), SampleCount: 3, CPUMin: 10, CPUMax: 30, CPUAvg: 20, MemoryMin: 5, MemoryMax: 50, MemoryAvg: 30},
+		{DeviceID: 1, Resolution: 60, BucketStart: base.Add(time.Hour), SampleCount: 2, CPUMin: 1, CPUMax: 2, CPUAvg: 1.5, MemoryMin: 1, MemoryMax: 2, MemoryAvg: 1.5},
+	}
+
+	merged := MergeRollups(rollups, models.MetricResolutionHour)
+	if len(merged) != 2 {
+		t.Fatalf("MergeRollups() 返回 %d 条, want 2", len(merged))
+	}
+
+	hour := merged[0]
+	if hour.Resolution != models.MetricResolutionHour || !hour.BucketStart.Equal(base) || hour.SampleCount != 4 {
+		t.Errorf("小时聚合 = %+v, want %v 的4条采样", hour, base)
+	}
+	if hour.CPUMin != 10 || hour.CPUMax != 90 || math.Abs(hour.CPUAvg-37.5) > 1e-9 {
+		t.Errorf("CPU聚合 = min %d max %d avg %v, want 10 90 37.5", hour.CPUMin, hour.CPUMax, hour.CPUAvg)
+	}
+	if hour.MemoryMin != 5 || hour.MemoryMax != 50 || math.Abs(hour.MemoryAvg-25) > 1e-9 {
+		t.Errorf("内存聚合 = min %d max %d avg %v, want 5 50 25", hour.MemoryMin, hour.MemoryMax, hour.MemoryAvg)
+	}
+	if !merged[1].BucketStart.Equal(base.Add(time.Hour)) || merged[1].SampleCount != 2 {
+		t.Errorf("第二个小时的聚合 = %+v", merged[1])
+	}
+}
+
+func TestMetricSource(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	cfg := &config.MetricsConfig{RollupInterval: 60, RawRetention: 24, MinuteRetention: 7 * 24, HourRetention: 90 * 24}
+
+	tests := []struct {
+		name string
+		cfg  *config.MetricsConfig
+		from time.Time
+		want int
+	}{
+		{"原始采样保留期内", cfg, now.Add(-23 * time.Hour), 0},
+		{"分钟数据保留期内", cfg, now.Add(-2 * 24 * time.Hour), models.MetricResolutionMinute},
+		{"超过分钟数据保留期", cfg, now.Add(-30 * 24 * time.Hour), models.MetricResolutionHour},
+		{"未开启聚合", &config.MetricsConfig{RawRetention: 24}, now.Add(-30 * 24 * time.Hour), 0},
+		{"原始采样永久保留", &config.MetricsConfig{RollupInterval: 60}, now.Add(-30 * 24 * time.Hour), 0},
+	}
+	for _, tt := range tests {
+		if got := MetricSource(tt.cfg, tt.from, now); got != tt.want {
+			t.Errorf("%s: MetricSource() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMetricStep(t *testing.T) {
+	tests := []struct {
+		resolution, step, want int
+	}{
+		{0, 0, 0},
+		{0, 45, 45},
+		{60, 0, 60},
+		{60, 30, 60},
+		{60, 300, 300},
+		{60, 90, 120},
+		{3600, 600, 3600},
+	}
+	for _, tt := range tests {
+		if got := MetricStep(tt.resolution, tt.step); got != tt.want {
+			t.Errorf("MetricStep(%d, %d) = %d, want %d", tt.resolution, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestQueryDeviceMetricsFillsTail(t *testing.T) {
+	hour := time.Now().Truncate(time.Hour).Add(-time.Hour)
+	cfg := &config.MetricsConfig{RollupInterval: 60, RawRetention: 1, MinuteRetention: 2}
+	repo := &fakeMetricRepo{
+		rollups: []models.DeviceMetricRollup{
+			testRollup(models.MetricResolutionHour, hour.Add(-2*time.Hour), 60, 10),
+			testRollup(models.MetricResolutionHour, hour.Add(-time.Hour), 60, 20),
+			// 最新的小时数据之后只聚合到分钟数据
+			testRollup(models.MetricResolutionMinute, hour.Add(10*time.Minute), 2, 30),
+			testRollup(models.MetricResolutionMinute, hour.Add(30*time.Minute), 2, 40),
+		},
+		samples: []models.DeviceMetric{
+			// 已聚合为分钟数据的采样不重复统计
+			{DeviceID: 1, SampledAt: hour.Add(30*time.Minute + 5*time.Second), CPUUsage: 99, MemoryUsage: 99},
+			// 最新的分钟数据之后只有原始采样
+			{DeviceID: 1, SampledAt: hour.Add(45 * time.Minute), CPUUsage: 70, MemoryUsage: 50},
+			{DeviceID: 1, SampledAt: hour.Add(50 * time.Minute), CPUUsage: 80, MemoryUsage: 60},
+			{DeviceID: 2, SampledAt: hour.Add(50 * time.Minute), CPUUsage: 90, MemoryUsage: 90},
+		},
+	}
+
+	series, err := QueryDeviceMetrics(repo, cfg, 1, hour.Add(-150*time.Minute), hour.Add(time.Hour), 0)
+	if err != nil {
+		t.Fatalf("QueryDeviceMetrics() error = %v", err)
+	}
+	if series.Resolution != models.MetricResolutionHour || len(series.Points) != 3 {
+		t.Fatalf("QueryDeviceMetrics() 精度 = %d，返回 %d 个数据点, want 小时数据3个", series.Resolution, len(series.Points))
+	}
+	tail := series.Points[2]
+	if !tail.Time.Equal(hour) || tail.SampleCount != 6 || tail.CPUMin != 30 || tail.CPUMax != 80 || tail.MemoryMax != 60 {
+		t.Errorf("补齐的小时数据 = %+v, want %v 的6条采样，CPU 30-80，内存最大60", tail, hour)
+	}
+	if math.Abs(tail.CPUAvg-(30*2+40*2+70+80)/6.0) > 1e-9 {
+		t.Errorf("补齐的小时数据CPU平均值 = %v", tail.CPUAvg)
+	}
+
+	cpu, memory, found, err := DeviceMetricPeak(repo, cfg, 1, hour.Add(-150*time.Minute), hour.Add(time.Hour))
+	if err != nil || !found || cpu != 80 || memory != 60 {
+		t.Errorf("DeviceMetricPeak() = %d, %d, %v, %v, want 80, 60, true", cpu, memory, found, err)
+	}
+}
+
+func TestFindRollupsWithoutRollups(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	repo := &fakeMetricRepo{samples: []models.DeviceMetric{
+		{DeviceID: 1, SampledAt: base.Add(10 * time.Second), CPUUsage: 10, MemoryUsage: 10},
+		{DeviceID: 1, SampledAt: base.Add(90 * time.Second), CPUUsage: 20, MemoryUsage: 20},
+	}}
+
+	// 尚未聚合过的数据全部由原始采样计算
+	rollups, err := findRollups(repo, 1, models.MetricResolutionMinute, base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("findRollups() error = %v", err)
+	}
+	if len(rollups) != 2 || !rollups[1].BucketStart.Equal(base.Add(time.Minute)) || rollups[1].CPUMax != 20 {
+		t.Errorf("findRollups() = %+v, want 由原始采样计算的2条分钟数据", rollups)
+	}
+}
+
+func TestQueryDeviceMetricsSampleLimit(t *testing.T) {
+	// 未开启聚合时任何范围都查询原始采样
+	cfg := &config.MetricsConfig{}
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeMetricRepo{}
+	for i := 0; i <= maxMetricSamples; i++ {
+		repo.samples = append(repo.samples, models.DeviceMetric{DeviceID: 1, SampledAt: from.Add(time.Duration(i) * time.Second), CPUUsage: i % 100})
+	}
+	to := from.Add(time.Duration(maxMetricSamples+1) * time.Second)
+
+	tests := []struct {
+		name string
+		to   time.Time
+		step int
+		want error
+	}{
+		{"逐条返回超过数据点上限", from.Add(time.Duration(maxMetricPoints+1) * time.Second), 0, ErrTooManyMetricPoints},
+		{"逐条返回未超过数据点上限", from.Add(time.Duration(maxMetricPoints) * time.Second), 0, nil},
+		{"按步长聚合超过采样上限", to, 3600, ErrTooManyMetricSamples},
+		{"按步长聚合未超过采样上限", to.Add(-time.Second), 3600, nil},
+	}
+	for _, tt := range tests {
+		if _, err := QueryDeviceMetrics(repo, cfg, 1, from, tt.to, tt.step); !errors.Is(err, tt.want) {
+			t.Errorf("%s: QueryDeviceMetrics() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	// 峰值按整个范围聚合，不受数据点上限影响
+	cpu, _, found, err := DeviceMetricPeak(repo, cfg, 1, from, from.Add(time.Duration(maxMetricPoints*2)*time.Second))
+	if err != nil || !found || cpu != 99 {
+		t.Errorf("DeviceMetricPeak() = %d, %v, %v, want 99, true, nil", cpu, found, err)
+	}
+}
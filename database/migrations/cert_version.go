@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"log"
+
+	"gin-server/config"
+
+	"gorm.io/gorm"
+)
+
+// versionLegacyCerts 为引入证书版本之前的证书记录设置版本号
+// 历史记录每个实体只有一条，设为版本1并作为当前使用的版本，可以重复执行
+func versionLegacyCerts(db *gorm.DB) error {
+	cfg := config.GetConfig()
+
+	result := db.Table("certs").Where("version = 0").Updates(map[string]interface{}{
+		"version": 1,
+		"active":  true,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if cfg.DebugLevel == "true" && result.RowsAffected > 0 {
+		log.Printf("已为 %d 条历史证书记录设置版本号\n", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"log"
+	"os"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+
+	"gorm.io/gorm"
+)
+
+// encryptKeyFiles 使用主密钥加密certs表中引用的明文私钥文件及保存在数据库中的明文私钥
+// 已加密的私钥会被跳过，因此可以重复执行；单个私钥失败只记录日志
+func encryptKeyFiles(db *gorm.DB) error {
+	cfg := config.GetConfig()
+	if !cfg.KeyEncryption.Enabled {
+		return nil
+	}
+
+	envelope, err := crypto.GetKeyEnvelope()
+	if err != nil {
+		log.Printf("警告: 私钥加密器不可用，跳过私钥文件加密: %v\n", err)
+		return nil
+	}
+
+	// 使用Table查询不带软删除条件，所有版本的私钥都需要加密
+	var paths []string
+	if err := db.Table("certs").Where("key_path <> '' AND key_path NOT LIKE 'db:%'").Distinct().Pluck("key_path", &paths).Error; err != nil {
+		return err
+	}
+
+	encrypted := 0
+	for _, path := range paths {
+		changed, err := crypto.EncryptKeyFile(path, envelope)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if cfg.DebugLevel == "true" {
+					log.Printf("私钥文件 %s 不存在，跳过加密\n", path)
+				}
+			} else {
+				log.Printf("警告: 加密私钥文件 %s 失败: %v\n", path, err)
+			}
+			continue
+		}
+		if changed {
+			encrypted++
+		}
+	}
+
+	if encrypted > 0 {
+		log.Printf("已加密 %d 个明文私钥文件\n", encrypted)
+	}
+	return encryptKeyBlobs(db, envelope)
+}
+
+// encryptKeyBlobs 加密使用数据库存储的明文私钥
+func encryptKeyBlobs(db *gorm.DB, envelope *crypto.EnvelopeEncryptor) error {
+	var rows []struct {
+		ID     uint
+		KeyPEM []byte
+	}
+	if err := db.Table("certs").Select("id, key_pem").Where("key_pem IS NOT NULL").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	encrypted := 0
+	for _, row := range rows {
+		if len(row.KeyPEM) == 0 || crypto.IsEnvelope(row.KeyPEM) {
+			continue
+		}
+		sealed, err := envelope.Seal(row.KeyPEM)
+		if err != nil {
+			log.Printf("警告: 加密证书记录 %d 的私钥失败: %v\n", row.ID, err)
+			continue
+		}
+		if err := db.Table("certs").Where("id = ?", row.ID).Update("key_pem", sealed).Error; err != nil {
+			log.Printf("警告: 保存证书记录 %d 的加密私钥失败: %v\n", row.ID, err)
+			continue
+		}
+		encrypted++
+	}
+
+	if encrypted > 0 {
+		log.Printf("已加密 %d 个数据库中的明文私钥\n", encrypted)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+
+	"gin-server/config"
+
+	"gorm.io/gorm"
+)
+
+// createdAtIndexTables 需要按创建时间过滤和排序的表
+// created_at来自gorm.Model，无法通过结构体标签添加索引
+var createdAtIndexTables = []string{"users", "devices"}
+
+// createListIndexes 为列表查询使用的created_at列创建索引，已存在时跳过
+func createListIndexes(db *gorm.DB) error {
+	cfg := config.GetConfig()
+
+	for _, table := range createdAtIndexTables {
+		name := fmt.Sprintf("idx_%s_created_at", table)
+		if db.Migrator().HasIndex(table, name) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (created_at)", name, table)).Error; err != nil {
+			return fmt.Errorf("创建索引%s失败: %w", name, err)
+		}
+		if cfg.DebugLevel == "true" {
+			log.Printf("已创建索引 %s\n", name)
+		}
+	}
+	return nil
+}
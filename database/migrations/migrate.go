@@ -50,6 +50,13 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.UserBehavior{},
 		&models.LogFile{},
 		&models.Cert{},
+		&models.RevokedToken{},
+		&models.CertRevocation{},
+		&models.KeyExportAudit{},
+		&models.UserStatusHistory{},
+		&models.DeviceMetric{},
+		&models.DeviceMetricRollup{},
+		&models.ScanCursor{},
 	}
 
 	// 执行主数据库迁移
@@ -63,6 +70,26 @@ func AutoMigrate(db *gorm.DB) error {
 		}
 	}
 
+	// 创建列表查询使用的索引
+	if err := createListIndexes(db); err != nil {
+		return fmt.Errorf("创建列表索引失败: %w", err)
+	}
+
+	// 将历史明文口令替换为口令哈希
+	if err := hashPlaintextPasswords(db); err != nil {
+		return fmt.Errorf("迁移明文口令失败: %w", err)
+	}
+
+	// 为历史证书记录设置版本号
+	if err := versionLegacyCerts(db); err != nil {
+		return fmt.Errorf("迁移证书版本失败: %w", err)
+	}
+
+	// 加密历史明文私钥文件
+	if err := encryptKeyFiles(db); err != nil {
+		return fmt.Errorf("加密私钥文件失败: %w", err)
+	}
+
 	// 迁移旧表（如果有）
 	if err := migrateOldTables(db); err != nil {
 		return fmt.Errorf("迁移旧表结构失败: %w", err)
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"fmt"
+	"log"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+
+	"gorm.io/gorm"
+)
+
+// passwordRecord 口令迁移使用的精简记录
+type passwordRecord struct {
+	ID       uint
+	Password string `gorm:"column:pass_wd"`
+}
+
+// hashPlaintextPasswords 将users和devices表中的明文口令替换为口令哈希
+// 已经是哈希格式的记录会被跳过，因此可以重复执行
+func hashPlaintextPasswords(db *gorm.DB) error {
+	for _, table := range []string{"users", "devices"} {
+		if err := hashTablePasswords(db, table); err != nil {
+			return fmt.Errorf("迁移%s表口令失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// hashTablePasswords 迁移单张表的明文口令
+func hashTablePasswords(db *gorm.DB, table string) error {
+	cfg := config.GetConfig()
+	hasher := crypto.DefaultPasswordHasher()
+
+	// 使用Table查询不带软删除条件，已删除的记录同样需要迁移
+	var records []passwordRecord
+	if err := db.Table(table).Select("id, pass_wd").Find(&records).Error; err != nil {
+		return err
+	}
+
+	migrated := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, record := range records {
+			if record.Password == "" || crypto.IsPasswordHash(record.Password) {
+				continue
+			}
+
+			hash, err := hasher.Hash(record.Password)
+			if err != nil {
+				return err
+			}
+			if err := tx.Table(table).Where("id = ?", record.ID).Update("pass_wd", hash).Error; err != nil {
+				return err
+			}
+			migrated++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if migrated > 0 {
+		log.Printf("[数据库迁移] %s表中 %d 条明文口令已替换为口令哈希", table, migrated)
+	} else if cfg.DebugLevel == "true" {
+		log.Printf("%s表中没有需要迁移的明文口令", table)
+	}
+	return nil
+}
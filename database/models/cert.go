@@ -6,14 +6,53 @@ import (
 	"gorm.io/gorm"
 )
 
+// 证书过期告警状态
+const (
+	CertExpiryAlertNone    = 0  // 尚未告警
+	CertExpiryAlertExpired = -1 // 已产生过期告警
+)
+
+// 证书来源
+const (
+	CertSourceUpload = "upload" // 上传绑定
+	CertSourceCA     = "ca"     // 内置CA签发
+)
+
 // Cert 证书信息
+// 每次绑定或签发证书都会新增一个版本，同一实体只有一个当前使用的版本（Active），
+// 切换版本时上一个版本可在重叠期（OverlapUntil）内继续用于认证；同一实体的版本号唯一，包括已归档的版本
 type Cert struct {
 	gorm.Model
-	EntityType string    `json:"entity_type" gorm:"column:entity_type;not null;index:idx_entity;type:varchar(32)"` // 实体类型：user或device
-	EntityID   string    `json:"entity_id" gorm:"column:entity_id;not null;index:idx_entity;type:varchar(128)"`    // 用户ID或设备ID
-	CertPath   string    `json:"cert_path" gorm:"column:cert_path;type:varchar(255)"`                              // 证书文件路径
-	KeyPath    string    `json:"key_path" gorm:"column:key_path;type:varchar(255)"`                                // 密钥文件路径
-	UploadTime time.Time `json:"upload_time" gorm:"column:upload_time;not null"`                                   // 上传时间
+	EntityType string    `json:"entity_type" gorm:"column:entity_type;not null;index:idx_entity;uniqueIndex:idx_entity_version,priority:1;type:varchar(32)"` // 实体类型：user或device
+	EntityID   string    `json:"entity_id" gorm:"column:entity_id;not null;index:idx_entity;uniqueIndex:idx_entity_version,priority:2;type:varchar(128)"`    // 用户ID或设备ID
+	CertPath   string    `json:"cert_path" gorm:"column:cert_path;type:varchar(255)"`                                                                        // 证书文件路径或数据库存储位置
+	KeyPath    string    `json:"key_path" gorm:"column:key_path;type:varchar(255)"`                                                                          // 密钥文件路径
+	UploadTime time.Time `json:"upload_time" gorm:"column:upload_time;not null"`                                                                             // 上传时间
+
+	// 使用数据库存储时证书和私钥内容保存在以下字段中，CertPath和KeyPath为以db:开头的逻辑位置
+	CertPEM []byte `json:"-" gorm:"column:cert_pem;type:mediumblob"` // 证书PEM
+	KeyPEM  []byte `json:"-" gorm:"column:key_pem;type:mediumblob"`  // 私钥PEM，启用私钥加密时为加密后的内容
+
+	// 以下字段在绑定证书时从X.509证书中解析
+	Subject      string     `json:"subject" gorm:"column:subject;type:varchar(512)"`              // 证书主题
+	Issuer       string     `json:"issuer" gorm:"column:issuer;type:varchar(512)"`                // 签发者
+	SerialNumber string     `json:"serial_number" gorm:"column:serial_number;type:varchar(128)"`  // 序列号（十六进制）
+	Fingerprint  string     `json:"fingerprint" gorm:"column:fingerprint;type:varchar(64);index"` // SHA-256指纹（十六进制）
+	NotBefore    *time.Time `json:"not_before" gorm:"column:not_before"`                          // 生效时间
+	NotAfter     *time.Time `json:"not_after" gorm:"column:not_after"`                            // 过期时间
+	CertKeyType  string     `json:"cert_key_type" gorm:"column:cert_key_type;type:varchar(32)"`   // 证书公钥类型
+	KeyType      string     `json:"key_type" gorm:"column:key_type;type:varchar(32)"`             // 绑定的私钥类型
+	ChainLength  int        `json:"chain_length" gorm:"column:chain_length;default:0"`            // 证书链长度
+	Source       string     `json:"source" gorm:"column:source;type:varchar(16)"`                 // 证书来源：upload或ca
+
+	// 以下字段用于证书版本管理
+	Version      int        `json:"version" gorm:"column:version;not null;default:0;uniqueIndex:idx_entity_version,priority:3"` // 版本号，同一实体从1开始递增
+	Active       bool       `json:"active" gorm:"column:active;not null;default:false;index"`                                   // 是否为当前使用的版本
+	OverlapUntil *time.Time `json:"overlap_until" gorm:"column:overlap_until"`                                                  // 被替换后仍可用于认证的截止时间
+
+	// ExpiryAlertDays 最近一次过期告警的阈值（天），0表示尚未告警，-1表示已产生过期告警
+	// 每个证书版本单独记录，绑定新版本时从0开始
+	ExpiryAlertDays int `json:"expiry_alert_days" gorm:"column:expiry_alert_days;default:0"`
 }
 
 // TableName 指定表名
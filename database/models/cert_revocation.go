@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CertRevocation 证书吊销记录
+// 按证书指纹唯一，证书记录被替换或删除后吊销记录仍然保留
+type CertRevocation struct {
+	gorm.Model
+	CertID       uint       `json:"cert_id" gorm:"column:cert_id;index"`                                                         // 吊销时certs表中的记录ID
+	EntityType   string     `json:"entity_type" gorm:"column:entity_type;not null;index:idx_revocation_entity;type:varchar(32)"` // 实体类型：user或device
+	EntityID     string     `json:"entity_id" gorm:"column:entity_id;not null;index:idx_revocation_entity;type:varchar(128)"`    // 用户ID或设备ID
+	SerialNumber string     `json:"serial_number" gorm:"column:serial_number;not null;type:varchar(128)"`                        // 序列号（十六进制）
+	Issuer       string     `json:"issuer" gorm:"column:issuer;type:varchar(512)"`                                               // 签发者
+	Fingerprint  string     `json:"fingerprint" gorm:"column:fingerprint;not null;uniqueIndex;type:varchar(64)"`                 // SHA-256指纹（十六进制）
+	NotAfter     *time.Time `json:"not_after" gorm:"column:not_after"`                                                           // 证书过期时间
+	ReasonCode   int        `json:"reason_code" gorm:"column:reason_code;not null;default:0"`                                    // 吊销原因代码（RFC 5280）
+	Comment      string     `json:"comment" gorm:"column:comment;type:varchar(255)"`                                             // 吊销说明
+	RevokedBy    string     `json:"revoked_by" gorm:"column:revoked_by;type:varchar(64)"`                                        // 操作人
+	RevokedAt    time.Time  `json:"revoked_at" gorm:"column:revoked_at;not null;index"`                                          // 吊销时间
+}
+
+// TableName 指定表名
+func (CertRevocation) TableName() string {
+	return "cert_revocations"
+}
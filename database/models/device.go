@@ -1,30 +1,61 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// 设备状态
+const (
+	DeviceStatusOnline    = 1 // 在线
+	DeviceStatusOffline   = 2 // 离线
+	DeviceStatusFrozen    = 3 // 冻结
+	DeviceStatusCancelled = 4 // 注销
+)
+
+// 设备类型
+const (
+	DeviceTypeGatewayA     = 1 // 网关设备A型
+	DeviceTypeGatewayB     = 2 // 网关设备B型
+	DeviceTypeGatewayC     = 3 // 网关设备C型
+	DeviceTypeSecurityMgmt = 4 // 安全接入管理设备
+)
+
+// IsGatewayType 判断设备类型是否为网关设备
+func IsGatewayType(deviceType int) bool {
+	return deviceType >= DeviceTypeGatewayA && deviceType <= DeviceTypeGatewayC
+}
+
 // Device 设备信息
 type Device struct {
 	gorm.Model
-	DeviceName          string `json:"device_name" gorm:"column:device_name;not null;type:varchar(128)"`
-	DeviceType          int    `json:"device_type" gorm:"column:device_type;not null"`
-	Password            string `json:"password" gorm:"column:pass_wd;not null;type:varchar(128)"`
-	DeviceID            int    `json:"device_id" gorm:"column:device_id;uniqueIndex;not null"`
-	SuperiorDeviceID    int    `json:"superior_device_id" gorm:"column:superior_device_id"`
-	DeviceStatus        int    `json:"device_status" gorm:"column:device_status;default:2"` // 默认离线状态
-	PeakCPUUsage        int    `json:"peak_cpu_usage" gorm:"column:peak_cpu_usage;default:0"`
-	PeakMemoryUsage     int    `json:"peak_memory_usage" gorm:"column:peak_memory_usage;default:0"`
-	OnlineDuration      int    `json:"online_duration" gorm:"column:online_duration;default:0"`
-	CertID              string `json:"cert_id" gorm:"column:cert_id;type:varchar(255)"`
-	KeyID               string `json:"key_id" gorm:"column:key_id;type:varchar(255)"`
-	RegisterIP          string `json:"register_ip" gorm:"column:register_ip;type:varchar(64)"`
-	Email               string `json:"email" gorm:"column:email;type:varchar(128)"`
-	HardwareFingerprint string `json:"hardware_fingerprint" gorm:"column:hardware_fingerprint;type:varchar(255)"`
-	AnonymousUser       string `json:"anonymous_user" gorm:"column:anonymous_user;type:varchar(128)"`
-	LongAddress         string `json:"long_address" gorm:"column:long_address;type:varchar(255)"`
-	ShortAddress        string `json:"short_address" gorm:"column:short_address;type:varchar(255)"`
-	SESKey              string `json:"ses_key" gorm:"column:ses_key;type:varchar(255)"`
+	DeviceName          string     `json:"device_name" gorm:"column:device_name;not null;type:varchar(128);index"`
+	DeviceType          int        `json:"device_type" gorm:"column:device_type;not null;index"`
+	Password            string     `json:"-" gorm:"column:pass_wd;not null;type:varchar(128)"`
+	DeviceID            int        `json:"device_id" gorm:"column:device_id;uniqueIndex;not null"`
+	SuperiorDeviceID    int        `json:"superior_device_id" gorm:"column:superior_device_id;index"`
+	DeviceStatus        int        `json:"device_status" gorm:"column:device_status;default:2;index"` // 默认离线状态
+	PeakCPUUsage        int        `json:"peak_cpu_usage" gorm:"column:peak_cpu_usage;default:0"`
+	PeakMemoryUsage     int        `json:"peak_memory_usage" gorm:"column:peak_memory_usage;default:0"`
+	OnlineDuration      int        `json:"online_duration" gorm:"column:online_duration;default:0"`
+	CertID              string     `json:"cert_id" gorm:"column:cert_id;type:varchar(255)"`
+	KeyID               string     `json:"key_id" gorm:"column:key_id;type:varchar(255)"`
+	RegisterIP          string     `json:"register_ip" gorm:"column:register_ip;type:varchar(64)"`
+	Email               string     `json:"email" gorm:"column:email;type:varchar(128)"`
+	HardwareFingerprint string     `json:"hardware_fingerprint" gorm:"column:hardware_fingerprint;type:varchar(255)"`
+	AnonymousUser       string     `json:"anonymous_user" gorm:"column:anonymous_user;type:varchar(128)"`
+	LongAddress         string     `json:"long_address" gorm:"column:long_address;type:varchar(255)"`
+	ShortAddress        string     `json:"short_address" gorm:"column:short_address;type:varchar(255)"`
+	SESKey              string     `json:"ses_key" gorm:"column:ses_key;type:varchar(255)"`
+	LoginFailures       int        `json:"login_failures" gorm:"column:login_failures;default:0"`      // 连续登录失败次数
+	LockedUntil         *time.Time `json:"locked_until" gorm:"column:locked_until"`                    // 登录锁定截止时间
+	LastLoginTime       *time.Time `json:"last_login_time" gorm:"column:last_login_time"`              // 最后登录时间
+	LastLoginIP         string     `json:"last_login_ip" gorm:"column:last_login_ip;type:varchar(64)"` // 最后登录IP
+	LastHeartbeatAt     *time.Time `json:"last_heartbeat_at" gorm:"column:last_heartbeat_at"`          // 最后一次心跳时间
+	OffLineTimeStamp    *time.Time `json:"offline_timestamp" gorm:"column:off_line_time_stamp"`        // 最近一次因心跳超时判定离线的时间
+	Uptime              int        `json:"uptime" gorm:"column:uptime;default:0"`                      // 最后一次心跳上报的设备运行时长（秒）
+	PeakWindowStart     *time.Time `json:"peak_window_start" gorm:"column:peak_window_start"`          // 当前峰值统计窗口的起始时间
 }
 
 // TableName 指定表名
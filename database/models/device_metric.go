@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// 设备性能聚合数据的精度（秒）
+const (
+	MetricResolutionMinute = 60   // 分钟聚合
+	MetricResolutionHour   = 3600 // 小时聚合
+)
+
+// DeviceMetric 设备性能采样，每次心跳记录一条
+// 超过METRICS_RAW_RETENTION的采样在聚合后清理
+type DeviceMetric struct {
+	ID          uint      `json:"-" gorm:"primarykey"`
+	DeviceID    int       `json:"device_id" gorm:"column:device_id;not null;index:idx_device_metrics_device_time,priority:1"`         // 设备唯一标识
+	SampledAt   time.Time `json:"sampled_at" gorm:"column:sampled_at;not null;index:idx_device_metrics_device_time,priority:2;index"` // 采样时间
+	CPUUsage    int       `json:"cpu_usage" gorm:"column:cpu_usage;not null"`                                                         // CPU使用率（%）
+	MemoryUsage int       `json:"memory_usage" gorm:"column:memory_usage;not null"`                                                   // 内存使用率（%）
+}
+
+// TableName 指定表名
+func (DeviceMetric) TableName() string {
+	return "device_metrics"
+}
+
+// DeviceMetricRollup 设备性能聚合数据
+// 分钟数据由采样聚合而来，小时数据由分钟数据聚合而来
+type DeviceMetricRollup struct {
+	ID          uint      `json:"-" gorm:"primarykey"`
+	DeviceID    int       `json:"device_id" gorm:"column:device_id;not null;uniqueIndex:idx_device_metric_rollups_bucket,priority:1"`             // 设备唯一标识
+	Resolution  int       `json:"resolution" gorm:"column:resolution;not null;uniqueIndex:idx_device_metric_rollups_bucket,priority:2"`           // 聚合精度（秒）
+	BucketStart time.Time `json:"bucket_start" gorm:"column:bucket_start;not null;uniqueIndex:idx_device_metric_rollups_bucket,priority:3;index"` // 聚合区间的起始时间
+	SampleCount int       `json:"sample_count" gorm:"column:sample_count;not null"`                                                               // 区间内的采样数
+	CPUMin      int       `json:"cpu_min" gorm:"column:cpu_min;not null"`                                                                         // CPU使用率最小值
+	CPUMax      int       `json:"cpu_max" gorm:"column:cpu_max;not null"`                                                                         // CPU使用率最大值
+	CPUAvg      float64   `json:"cpu_avg" gorm:"column:cpu_avg;not null"`                                                                         // CPU使用率平均值
+	MemoryMin   int       `json:"memory_min" gorm:"column:memory_min;not null"`                                                                   // 内存使用率最小值
+	MemoryMax   int       `json:"memory_max" gorm:"column:memory_max;not null"`                                                                   // 内存使用率最大值
+	MemoryAvg   float64   `json:"memory_avg" gorm:"column:memory_avg;not null"`                                                                   // 内存使用率平均值
+}
+
+// TableName 指定表名
+func (DeviceMetricRollup) TableName() string {
+	return "device_metric_rollups"
+}
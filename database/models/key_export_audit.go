@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KeyExportAudit 私钥导出审计记录
+// 每次通过私钥导出接口读取私钥都会记录一条，包括读取失败的尝试
+type KeyExportAudit struct {
+	gorm.Model
+	CertID      uint      `json:"cert_id" gorm:"column:cert_id;index"`                                                         // 导出时certs表中的记录ID
+	EntityType  string    `json:"entity_type" gorm:"column:entity_type;not null;index:idx_key_export_entity;type:varchar(32)"` // 实体类型：user或device
+	EntityID    string    `json:"entity_id" gorm:"column:entity_id;not null;index:idx_key_export_entity;type:varchar(128)"`    // 用户ID或设备ID
+	Version     int       `json:"version" gorm:"column:version;not null"`                                                      // 证书版本号
+	Fingerprint string    `json:"fingerprint" gorm:"column:fingerprint;type:varchar(64)"`                                      // 对应证书的SHA-256指纹
+	KeyType     string    `json:"key_type" gorm:"column:key_type;type:varchar(32)"`                                            // 私钥类型
+	ExportedBy  string    `json:"exported_by" gorm:"column:exported_by;type:varchar(64)"`                                      // 操作人用户名
+	OperatorID  string    `json:"operator_id" gorm:"column:operator_id;index;type:varchar(64)"`                                // 操作人用户ID
+	ClientIP    string    `json:"client_ip" gorm:"column:client_ip;type:varchar(64)"`                                          // 请求来源IP
+	Reason      string    `json:"reason" gorm:"column:reason;type:varchar(255)"`                                               // 导出原因
+	Success     bool      `json:"success" gorm:"column:success;not null;default:false"`                                        // 是否导出成功
+	ExportedAt  time.Time `json:"exported_at" gorm:"column:exported_at;not null;index"`                                        // 导出时间
+}
+
+// TableName 指定表名
+func (KeyExportAudit) TableName() string {
+	return "key_export_audits"
+}
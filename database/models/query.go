@@ -0,0 +1,31 @@
+package models
+
+// ListQuery 列表查询的分页、排序和创建时间范围参数
+// 指定cursor时按游标翻页并忽略page
+type ListQuery struct {
+	Page        int    `form:"page,default=1"`
+	PageSize    int    `form:"page_size,default=10"`
+	Cursor      string `form:"cursor"`
+	Sort        string `form:"sort"`
+	Order       string `form:"order"`
+	CreatedFrom string `form:"created_from"` // 创建时间下限，格式为2006-01-02或RFC3339
+	CreatedTo   string `form:"created_to"`   // 创建时间上限，只有日期时包含当天
+}
+
+// UserQuery 用户列表查询条件
+type UserQuery struct {
+	ListQuery
+	Status          *int   `form:"status"`
+	UserType        *int   `form:"type"`
+	GatewayDeviceID *int   `form:"gateway_device_id"`
+	Name            string `form:"name"` // 用户名前缀
+}
+
+// DeviceQuery 设备列表查询条件
+type DeviceQuery struct {
+	ListQuery
+	Status           *int   `form:"status"`
+	DeviceType       *int   `form:"type"`
+	SuperiorDeviceID *int   `form:"superior_device_id"`
+	Name             string `form:"name"` // 设备名前缀
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RevokedToken 已吊销的令牌
+// 仅需保留到令牌自身过期为止，过期后的记录可以清理
+type RevokedToken struct {
+	gorm.Model
+	TokenID   string    `json:"token_id" gorm:"column:token_id;not null;uniqueIndex;type:varchar(64)"` // 令牌唯一标识(jti)
+	TokenType string    `json:"token_type" gorm:"column:token_type;not null;type:varchar(16)"`         // 令牌类型，access或refresh
+	Subject   string    `json:"subject" gorm:"column:subject;not null;index;type:varchar(64)"`         // 令牌主体
+	ExpiresAt time.Time `json:"expires_at" gorm:"column:expires_at;not null;index"`                    // 令牌过期时间
+}
+
+// TableName 指定表名
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,16 @@
+package models
+
+import "gorm.io/gorm"
+
+// ScanCursor 后台扫描任务的处理进度
+// 服务重启后从上次处理到的位置继续扫描，停机期间新增的记录不会遗漏
+type ScanCursor struct {
+	gorm.Model
+	Name     string `json:"name" gorm:"column:name;not null;uniqueIndex;type:varchar(64)"` // 扫描任务名称
+	Position int64  `json:"position" gorm:"column:position;not null"`                      // 最后处理的记录ID
+}
+
+// TableName 指定表名
+func (ScanCursor) TableName() string {
+	return "scan_cursors"
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserStatusHistory 用户状态变更记录
+// 冻结、解冻、注销、重新激活等每次状态变更都会记录一条，包括系统自动执行的变更
+type UserStatusHistory struct {
+	gorm.Model
+	UserID     int       `json:"user_id" gorm:"column:user_id;not null;index"`       // 用户唯一标识
+	FromStatus int       `json:"from_status" gorm:"column:from_status;not null"`     // 变更前状态
+	ToStatus   int       `json:"to_status" gorm:"column:to_status;not null"`         // 变更后状态
+	Reason     string    `json:"reason" gorm:"column:reason;type:varchar(255)"`      // 变更原因
+	Actor      string    `json:"actor" gorm:"column:actor;type:varchar(64)"`         // 操作人用户名，系统自动变更时为system
+	ChangedAt  time.Time `json:"changed_at" gorm:"column:changed_at;not null;index"` // 变更时间
+}
+
+// TableName 指定表名
+func (UserStatusHistory) TableName() string {
+	return "user_status_histories"
+}
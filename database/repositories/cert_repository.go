@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CertRepository 证书仓库接口
@@ -12,20 +13,50 @@ type CertRepository interface {
 	Repository
 	// FindByID 根据ID查找证书
 	FindByID(id uint) (*models.Cert, error)
-	// FindByEntity 根据实体类型和ID查找证书
+	// FindByEntity 根据实体类型和ID查找当前使用的证书版本
 	FindByEntity(entityType, entityID string) (*models.Cert, error)
 	// FindAll 查找所有证书
 	FindAll() ([]models.Cert, error)
+	// FindByEntityType 查找指定实体类型的所有证书
+	FindByEntityType(entityType string) ([]models.Cert, error)
 	// Create 创建证书
 	Create(cert *models.Cert) error
 	// Update 更新证书
 	Update(cert *models.Cert) error
 	// Delete 删除证书
 	Delete(id uint) error
-	// UpdateCertPath 更新证书路径
+	// UpdateCertPath 更新当前版本的证书路径
 	UpdateCertPath(entityType, entityID, certPath string) error
-	// UpdateKeyPath 更新密钥路径
+	// UpdateKeyPath 更新当前版本的密钥路径
 	UpdateKeyPath(entityType, entityID, keyPath string) error
+	// BindCert 绑定证书，创建新的证书版本并设为当前版本
+	// 版本号在事务中分配后调用write保存证书和私钥文件，write返回错误时不创建版本；
+	// 未指定密钥时沿用上一版本的密钥；上一版本在overlapUntil之前仍可用于认证，为nil表示立即停用
+	BindCert(cert *models.Cert, overlapUntil *time.Time, write func(cert *models.Cert) error) error
+	// BindKey 绑定密钥，使用key中的实体、密钥位置、私钥内容及私钥类型更新当前版本，没有证书记录时创建
+	// 密钥位置为空时解除当前版本的密钥绑定
+	BindKey(key *models.Cert) error
+	// FindByFingerprint 根据证书指纹查找证书，同一证书存在多个版本时优先返回当前版本
+	FindByFingerprint(entityType, fingerprint string) (*models.Cert, error)
+	// NextVersion 返回实体下一次绑定证书将使用的版本号
+	NextVersion(entityType, entityID string) (int, error)
+	// FindVersion 查找实体的指定证书版本
+	FindVersion(entityType, entityID string, version int) (*models.Cert, error)
+	// FindHistory 查找实体的所有证书版本，按版本号倒序
+	FindHistory(entityType, entityID string) ([]models.Cert, error)
+	// Activate 将指定版本设为当前版本，原当前版本在overlapUntil之前仍可用于认证
+	Activate(entityType, entityID string, version int, overlapUntil *time.Time) (*models.Cert, error)
+	// FindExpiringBefore 查找当前版本中在指定时间之前过期的证书，按过期时间排序
+	// entityType为空时查找所有实体类型
+	FindExpiringBefore(entityType string, before time.Time) ([]models.Cert, error)
+	// FindWithoutExpiry 查找已绑定但未记录过期时间的证书
+	FindWithoutExpiry() ([]models.Cert, error)
+	// UpdateExpiryAlert 更新证书的过期告警状态
+	UpdateExpiryAlert(id uint, days int) error
+	// ArchiveByEntity 归档实体的所有证书版本，归档后的证书不再用于认证和查询
+	ArchiveByEntity(entityType, entityID string) error
+	// RestoreByEntity 恢复实体已归档的证书版本
+	RestoreByEntity(entityType, entityID string) error
 }
 
 // certRepository 证书仓库实现
@@ -56,10 +87,10 @@ func (r *certRepository) FindByID(id uint) (*models.Cert, error) {
 	return &cert, nil
 }
 
-// FindByEntity 根据实体类型和ID查找证书
+// FindByEntity 根据实体类型和ID查找当前使用的证书版本
 func (r *certRepository) FindByEntity(entityType, entityID string) (*models.Cert, error) {
 	var cert models.Cert
-	if err := r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).First(&cert).Error; err != nil {
+	if err := r.activeQuery(r.GetDB(), entityType, entityID).First(&cert).Error; err != nil {
 		return nil, err
 	}
 	return &cert, nil
@@ -74,6 +105,15 @@ func (r *certRepository) FindAll() ([]models.Cert, error) {
 	return certs, nil
 }
 
+// FindByEntityType 查找指定实体类型的所有证书
+func (r *certRepository) FindByEntityType(entityType string) ([]models.Cert, error) {
+	var certs []models.Cert
+	if err := r.GetDB().Where("entity_type = ?", entityType).Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
 // Create 创建证书
 func (r *certRepository) Create(cert *models.Cert) error {
 	// 确保上传时间已设置
@@ -93,52 +133,252 @@ func (r *certRepository) Delete(id uint) error {
 	return r.GetDB().Delete(&models.Cert{}, id).Error
 }
 
-// UpdateCertPath 更新证书路径
+// ArchiveByEntity 归档实体的所有证书版本
+func (r *certRepository) ArchiveByEntity(entityType, entityID string) error {
+	return r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).Delete(&models.Cert{}).Error
+}
+
+// RestoreByEntity 恢复实体已归档的证书版本
+func (r *certRepository) RestoreByEntity(entityType, entityID string) error {
+	return r.GetDB().Unscoped().Model(&models.Cert{}).
+		Where("entity_type = ? AND entity_id = ? AND deleted_at IS NOT NULL", entityType, entityID).
+		Update("deleted_at", nil).Error
+}
+
+// UpdateCertPath 更新当前版本的证书路径
 func (r *certRepository) UpdateCertPath(entityType, entityID, certPath string) error {
-	// 查找证书记录
-	var cert models.Cert
-	result := r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).First(&cert)
-
-	// 如果记录不存在，创建新记录
-	if result.Error == gorm.ErrRecordNotFound {
-		cert = models.Cert{
-			EntityType: entityType,
-			EntityID:   entityID,
-			CertPath:   certPath,
-			UploadTime: time.Now(),
+	return r.updateActive(entityType, entityID, func(cert *models.Cert) {
+		cert.CertPath = certPath
+	})
+}
+
+// UpdateKeyPath 更新当前版本的密钥路径
+func (r *certRepository) UpdateKeyPath(entityType, entityID, keyPath string) error {
+	return r.updateActive(entityType, entityID, func(cert *models.Cert) {
+		cert.KeyPath = keyPath
+	})
+}
+
+// updateActive 更新当前版本，实体没有证书记录时创建新版本
+// 与BindCert相同，当前版本加锁后在事务中分配版本号
+func (r *certRepository) updateActive(entityType, entityID string, update func(cert *models.Cert)) error {
+	return r.GetDB().Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+		var cert models.Cert
+		result := r.activeQuery(locked, entityType, entityID).First(&cert)
+
+		// 如果记录不存在，创建新记录
+		if result.Error == gorm.ErrRecordNotFound {
+			version, err := r.nextVersion(locked, entityType, entityID)
+			if err != nil {
+				return err
+			}
+			cert = models.Cert{
+				EntityType: entityType,
+				EntityID:   entityID,
+				Version:    version,
+				Active:     true,
+				UploadTime: time.Now(),
+			}
+			update(&cert)
+			return tx.Create(&cert).Error
+		} else if result.Error != nil {
+			return result.Error
+		}
+
+		update(&cert)
+		cert.UploadTime = time.Now()
+		return tx.Save(&cert).Error
+	})
+}
+
+// BindCert 绑定证书，创建新的证书版本并设为当前版本
+// 当前版本加锁并在事务中分配版本号，同一实体的并发绑定依次执行，不会分配到相同的版本号、覆盖彼此的文件
+func (r *certRepository) BindCert(cert *models.Cert, overlapUntil *time.Time, write func(cert *models.Cert) error) error {
+	return r.GetDB().Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+		var active models.Cert
+		result := r.activeQuery(locked, cert.EntityType, cert.EntityID).First(&active)
+		if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+			return result.Error
+		}
+		hasActive := result.Error == nil
+
+		// 当前版本只绑定了密钥时直接补充证书，不新增版本
+		if hasActive && active.CertPath == "" {
+			cert.Version = active.Version
+		} else {
+			version, err := r.nextVersion(locked, cert.EntityType, cert.EntityID)
+			if err != nil {
+				return err
+			}
+			cert.Version = version
+		}
+		if write != nil {
+			if err := write(cert); err != nil {
+				return err
+			}
+		}
+
+		if hasActive && active.CertPath == "" {
+			cert.ID = active.ID
+			cert.CreatedAt = active.CreatedAt
+			if cert.KeyPath == "" {
+				cert.KeyPath = active.KeyPath
+				cert.KeyPEM = active.KeyPEM
+				cert.KeyType = active.KeyType
+			}
+			cert.Active = true
+			cert.UploadTime = time.Now()
+			return tx.Save(cert).Error
+		}
+
+		// 未指定密钥时沿用上一版本的密钥，并停用上一版本
+		if hasActive {
+			if cert.KeyPath == "" {
+				cert.KeyPath = active.KeyPath
+				cert.KeyPEM = active.KeyPEM
+				cert.KeyType = active.KeyType
+			}
+			if err := tx.Model(&models.Cert{}).Where("id = ?", active.ID).Updates(map[string]interface{}{
+				"active":        false,
+				"overlap_until": overlapUntil,
+			}).Error; err != nil {
+				return err
+			}
 		}
-		return r.Create(&cert)
-	} else if result.Error != nil {
-		return result.Error
+
+		cert.ID = 0
+		cert.Active = true
+		cert.OverlapUntil = nil
+		cert.UploadTime = time.Now()
+		return tx.Create(cert).Error
+	})
+}
+
+// BindKey 绑定密钥
+func (r *certRepository) BindKey(key *models.Cert) error {
+	return r.updateActive(key.EntityType, key.EntityID, func(cert *models.Cert) {
+		cert.KeyPath = key.KeyPath
+		cert.KeyPEM = key.KeyPEM
+		cert.KeyType = key.KeyType
+	})
+}
+
+// FindByFingerprint 根据证书指纹查找证书
+func (r *certRepository) FindByFingerprint(entityType, fingerprint string) (*models.Cert, error) {
+	var cert models.Cert
+	if err := r.GetDB().Where("entity_type = ? AND fingerprint = ?", entityType, fingerprint).
+		Order("active DESC, version DESC").First(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// FindExpiringBefore 查找已绑定且在指定时间之前过期的证书
+func (r *certRepository) FindExpiringBefore(entityType string, before time.Time) ([]models.Cert, error) {
+	var certs []models.Cert
+	query := r.GetDB().Where("active = ? AND cert_path <> '' AND not_after IS NOT NULL AND not_after <= ?", true, before)
+	if entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if err := query.Order("not_after ASC").Find(&certs).Error; err != nil {
+		return nil, err
 	}
+	return certs, nil
+}
 
-	// 更新证书路径
-	cert.CertPath = certPath
-	cert.UploadTime = time.Now()
-	return r.Update(&cert)
+// FindWithoutExpiry 查找已绑定但未记录过期时间的证书
+func (r *certRepository) FindWithoutExpiry() ([]models.Cert, error) {
+	var certs []models.Cert
+	if err := r.GetDB().Where("cert_path <> '' AND not_after IS NULL").Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
 }
 
-// UpdateKeyPath 更新密钥路径
-func (r *certRepository) UpdateKeyPath(entityType, entityID, keyPath string) error {
-	// 查找证书记录
+// UpdateExpiryAlert 更新证书的过期告警状态
+func (r *certRepository) UpdateExpiryAlert(id uint, days int) error {
+	return r.GetDB().Model(&models.Cert{}).Where("id = ?", id).Update("expiry_alert_days", days).Error
+}
+
+// NextVersion 返回实体下一次绑定证书将使用的版本号
+// 当前版本只绑定了密钥时返回当前版本号，否则为已有最大版本号加1（包括已删除的版本）
+func (r *certRepository) NextVersion(entityType, entityID string) (int, error) {
+	var active models.Cert
+	result := r.activeQuery(r.GetDB(), entityType, entityID).First(&active)
+	if result.Error == nil && active.CertPath == "" {
+		return active.Version, nil
+	} else if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
+		return 0, result.Error
+	}
+	return r.nextVersion(r.GetDB(), entityType, entityID)
+}
+
+// nextVersion 返回已有最大版本号加1
+func (r *certRepository) nextVersion(db *gorm.DB, entityType, entityID string) (int, error) {
+	var maxVersion int
+	if err := db.Unscoped().Model(&models.Cert{}).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+		return 0, err
+	}
+	return maxVersion + 1, nil
+}
+
+// FindVersion 查找实体的指定证书版本
+func (r *certRepository) FindVersion(entityType, entityID string, version int) (*models.Cert, error) {
 	var cert models.Cert
-	result := r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).First(&cert)
-
-	// 如果记录不存在，创建新记录
-	if result.Error == gorm.ErrRecordNotFound {
-		cert = models.Cert{
-			EntityType: entityType,
-			EntityID:   entityID,
-			KeyPath:    keyPath,
-			UploadTime: time.Now(),
+	if err := r.GetDB().Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, version).
+		First(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// FindHistory 查找实体的所有证书版本
+func (r *certRepository) FindHistory(entityType, entityID string) ([]models.Cert, error) {
+	var certs []models.Cert
+	if err := r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC").Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// Activate 将指定版本设为当前版本
+func (r *certRepository) Activate(entityType, entityID string, version int, overlapUntil *time.Time) (*models.Cert, error) {
+	var target models.Cert
+	err := r.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, version).
+			First(&target).Error; err != nil {
+			return err
 		}
-		return r.Create(&cert)
-	} else if result.Error != nil {
-		return result.Error
+		if target.Active {
+			return nil
+		}
+
+		if err := r.activeQuery(tx.Model(&models.Cert{}), entityType, entityID).Updates(map[string]interface{}{
+			"active":        false,
+			"overlap_until": overlapUntil,
+		}).Error; err != nil {
+			return err
+		}
+
+		target.Active = true
+		target.OverlapUntil = nil
+		return tx.Model(&models.Cert{}).Where("id = ?", target.ID).Updates(map[string]interface{}{
+			"active":        true,
+			"overlap_until": nil,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &target, nil
+}
 
-	// 更新密钥路径
-	cert.KeyPath = keyPath
-	cert.UploadTime = time.Now()
-	return r.Update(&cert)
+// activeQuery 构造查询实体当前版本的条件
+func (r *certRepository) activeQuery(db *gorm.DB, entityType, entityID string) *gorm.DB {
+	return db.Where("entity_type = ? AND entity_id = ? AND active = ?", entityType, entityID, true)
 }
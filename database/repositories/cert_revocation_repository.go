@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+)
+
+// CertRevocationRepository 证书吊销仓库接口
+type CertRevocationRepository interface {
+	Repository
+	// Create 创建吊销记录
+	Create(revocation *models.CertRevocation) error
+	// FindByFingerprint 根据证书指纹查找吊销记录
+	FindByFingerprint(fingerprint string) (*models.CertRevocation, error)
+	// IsRevoked 检查证书是否已吊销
+	IsRevoked(fingerprint string) (bool, error)
+	// FindByIssuer 查找指定签发者签发的证书的吊销记录，按吊销时间排序
+	FindByIssuer(issuer string) ([]models.CertRevocation, error)
+	// FindByEntity 查找实体的所有吊销记录，按吊销时间倒序
+	FindByEntity(entityType, entityID string) ([]models.CertRevocation, error)
+}
+
+// certRevocationRepository 证书吊销仓库实现
+type certRevocationRepository struct {
+	*BaseRepository
+}
+
+// NewCertRevocationRepository 创建证书吊销仓库实例
+func NewCertRevocationRepository(db *gorm.DB) CertRevocationRepository {
+	return &certRevocationRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithTx 使用事务进行操作
+func (r *certRevocationRepository) WithTx(tx *gorm.DB) Repository {
+	return &certRevocationRepository{
+		BaseRepository: r.BaseRepository.WithTx(tx),
+	}
+}
+
+// Create 创建吊销记录
+func (r *certRevocationRepository) Create(revocation *models.CertRevocation) error {
+	return r.GetDB().Create(revocation).Error
+}
+
+// FindByFingerprint 根据证书指纹查找吊销记录
+func (r *certRevocationRepository) FindByFingerprint(fingerprint string) (*models.CertRevocation, error) {
+	var revocation models.CertRevocation
+	if err := r.GetDB().Where("fingerprint = ?", fingerprint).First(&revocation).Error; err != nil {
+		return nil, err
+	}
+	return &revocation, nil
+}
+
+// IsRevoked 检查证书是否已吊销
+func (r *certRevocationRepository) IsRevoked(fingerprint string) (bool, error) {
+	var count int64
+	if err := r.GetDB().Model(&models.CertRevocation{}).Where("fingerprint = ?", fingerprint).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindByIssuer 查找指定签发者签发的证书的吊销记录
+func (r *certRevocationRepository) FindByIssuer(issuer string) ([]models.CertRevocation, error) {
+	var revocations []models.CertRevocation
+	if err := r.GetDB().Where("issuer = ?", issuer).Order("revoked_at ASC").Find(&revocations).Error; err != nil {
+		return nil, err
+	}
+	return revocations, nil
+}
+
+// FindByEntity 查找实体的所有吊销记录
+func (r *certRevocationRepository) FindByEntity(entityType, entityID string) ([]models.CertRevocation, error) {
+	var revocations []models.CertRevocation
+	if err := r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("revoked_at DESC").Find(&revocations).Error; err != nil {
+		return nil, err
+	}
+	return revocations, nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"time"
+
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeviceMetricRepository 设备性能数据仓库接口
+type DeviceMetricRepository interface {
+	Repository
+	// Create 保存一条性能采样
+	Create(metric *models.DeviceMetric) error
+	// FindSamples 查找时间范围[from, to)内的性能采样，按采样时间排序，deviceID为0时查找所有设备
+	// limit大于0时最多返回limit条
+	FindSamples(deviceID int, from, to time.Time, limit int) ([]models.DeviceMetric, error)
+	// FindRollups 查找时间范围[from, to)内指定精度的聚合数据，按区间起始时间排序，deviceID为0时查找所有设备
+	FindRollups(deviceID int, resolution int, from, to time.Time) ([]models.DeviceMetricRollup, error)
+	// SaveRollups 保存聚合数据，同一设备、精度和区间的数据已存在时覆盖
+	SaveRollups(rollups []models.DeviceMetricRollup) error
+	// LatestRollupStart 返回指定精度最新一条聚合数据的区间起始时间，没有数据时返回nil
+	LatestRollupStart(resolution int) (*time.Time, error)
+	// DeleteSamplesBefore 删除指定时间之前的性能采样，返回删除的条数
+	DeleteSamplesBefore(before time.Time) (int64, error)
+	// DeleteRollupsBefore 删除区间起始时间在指定时间之前的聚合数据，返回删除的条数
+	DeleteRollupsBefore(resolution int, before time.Time) (int64, error)
+}
+
+// deviceMetricRepository 设备性能数据仓库实现
+type deviceMetricRepository struct {
+	*BaseRepository
+}
+
+// NewDeviceMetricRepository 创建设备性能数据仓库实例
+func NewDeviceMetricRepository(db *gorm.DB) DeviceMetricRepository {
+	return &deviceMetricRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithTx 使用事务进行操作
+func (r *deviceMetricRepository) WithTx(tx *gorm.DB) Repository {
+	return &deviceMetricRepository{
+		BaseRepository: r.BaseRepository.WithTx(tx),
+	}
+}
+
+// Create 保存一条性能采样
+func (r *deviceMetricRepository) Create(metric *models.DeviceMetric) error {
+	return r.GetDB().Create(metric).Error
+}
+
+// FindSamples 查找时间范围内的性能采样
+func (r *deviceMetricRepository) FindSamples(deviceID int, from, to time.Time, limit int) ([]models.DeviceMetric, error) {
+	query := r.GetDB().Where("sampled_at >= ? AND sampled_at < ?", from, to)
+	if deviceID != 0 {
+		query = query.Where("device_id = ?", deviceID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var metrics []models.DeviceMetric
+	if err := query.Order("sampled_at, id").Find(&metrics).Error; err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// FindRollups 查找时间范围内指定精度的聚合数据
+func (r *deviceMetricRepository) FindRollups(deviceID int, resolution int, from, to time.Time) ([]models.DeviceMetricRollup, error) {
+	query := r.GetDB().Where("resolution = ? AND bucket_start >= ? AND bucket_start < ?", resolution, from, to)
+	if deviceID != 0 {
+		query = query.Where("device_id = ?", deviceID)
+	}
+
+	var rollups []models.DeviceMetricRollup
+	if err := query.Order("bucket_start, device_id").Find(&rollups).Error; err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+// SaveRollups 保存聚合数据
+func (r *deviceMetricRepository) SaveRollups(rollups []models.DeviceMetricRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	return r.GetDB().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "device_id"}, {Name: "resolution"}, {Name: "bucket_start"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"sample_count", "cpu_min", "cpu_max", "cpu_avg", "memory_min", "memory_max", "memory_avg",
+		}),
+	}).CreateInBatches(rollups, 500).Error
+}
+
+// LatestRollupStart 返回指定精度最新一条聚合数据的区间起始时间
+func (r *deviceMetricRepository) LatestRollupStart(resolution int) (*time.Time, error) {
+	var rollup models.DeviceMetricRollup
+	err := r.GetDB().Where("resolution = ?", resolution).Order("bucket_start DESC").Take(&rollup).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rollup.BucketStart, nil
+}
+
+// DeleteSamplesBefore 删除指定时间之前的性能采样
+func (r *deviceMetricRepository) DeleteSamplesBefore(before time.Time) (int64, error) {
+	result := r.GetDB().Where("sampled_at < ?", before).Delete(&models.DeviceMetric{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteRollupsBefore 删除区间起始时间在指定时间之前的聚合数据
+func (r *deviceMetricRepository) DeleteRollupsBefore(resolution int, before time.Time) (int64, error) {
+	result := r.GetDB().Where("resolution = ? AND bucket_start < ?", resolution, before).Delete(&models.DeviceMetricRollup{})
+	return result.RowsAffected, result.Error
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"gin-server/database/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -17,12 +18,41 @@ type DeviceRepository interface {
 	FindByDeviceName(deviceName string) (*models.Device, error)
 	// FindAll 查找所有设备
 	FindAll() ([]models.Device, error)
+	// UpdateIfUnmodified 在更新时间与updatedAt一致时更新指定字段，返回是否已更新
+	UpdateIfUnmodified(id uint, updatedAt time.Time, fields map[string]interface{}) (bool, error)
+	// FindInBatches 按ID顺序分批遍历所有设备
+	FindInBatches(batchSize int, fn func([]models.Device) error) error
+	// FindByConditions 按条件分页查询设备，返回设备、总数和下一页游标
+	FindByConditions(query *models.DeviceQuery) ([]models.Device, int64, string, error)
 	// Create 创建设备
 	Create(device *models.Device) error
 	// Update 更新设备
 	Update(device *models.Device) error
 	// Delete 删除设备
 	Delete(id uint) error
+	// UpdatePassword 更新口令哈希
+	UpdatePassword(id uint, passwordHash string) error
+	// IncrementLoginFailures 登录失败次数加一，返回累计失败次数
+	IncrementLoginFailures(id uint) (int, error)
+	// LockLogin 锁定设备登录直到指定时间，并清零失败次数
+	LockLogin(id uint, until time.Time) error
+	// RecordLogin 记录登录成功：置为在线、记录登录IP并清除失败计数和锁定，首次登录时将登录IP记录为注册IP
+	// 设备已冻结或注销时不更新，返回false
+	RecordLogin(id uint, ip string) (bool, error)
+	// RecordHeartbeat 记录设备心跳，更新心跳计算出的字段，设备已冻结或注销时不更新并返回false
+	RecordHeartbeat(id uint, fields map[string]interface{}) (bool, error)
+	// FindHeartbeatTimeout 查找在线且最后一次心跳早于指定时间的设备，从未上报心跳的设备不包括在内
+	FindHeartbeatTimeout(before time.Time) ([]models.Device, error)
+	// MarkOffline 将心跳超时的设备置为离线，期间已重新上报心跳时不更新，返回是否已更新
+	MarkOffline(id uint, before, at time.Time) (bool, error)
+	// FindAddressConflict 查找同一安全接入管理设备下使用相同长地址或短地址的其他设备
+	FindAddressConflict(superiorDeviceID int, longAddress, shortAddress string, excludeDeviceID int) (*models.Device, error)
+	// FindBySuperiorDeviceID 查找指定设备的下级设备
+	FindBySuperiorDeviceID(superiorDeviceID int) ([]models.Device, error)
+	// FindDeletedByDeviceID 根据设备ID查找已删除的设备
+	FindDeletedByDeviceID(deviceID int) (*models.Device, error)
+	// Restore 恢复已删除的设备
+	Restore(id uint) error
 }
 
 // deviceRepository 设备仓库实现
@@ -80,6 +110,52 @@ func (r *deviceRepository) FindAll() ([]models.Device, error) {
 	return devices, nil
 }
 
+// UpdateIfUnmodified 在更新时间与updatedAt一致时更新指定字段
+// 更新时间不一致说明记录已被其他请求修改，此时不更新并返回false
+func (r *deviceRepository) UpdateIfUnmodified(id uint, updatedAt time.Time, fields map[string]interface{}) (bool, error) {
+	result := r.GetDB().Model(&models.Device{}).Where("id = ? AND updated_at = ?", id, updatedAt).Updates(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindInBatches 按ID顺序分批遍历所有设备，fn返回错误时停止
+func (r *deviceRepository) FindInBatches(batchSize int, fn func([]models.Device) error) error {
+	var batch []models.Device
+	return r.GetDB().FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// deviceSortFields 设备列表允许排序的字段
+var deviceSortFields = map[string]sortField[models.Device]{
+	"id":          {column: "id", kind: sortInt, value: func(d *models.Device) interface{} { return d.ID }},
+	"device_id":   {column: "device_id", kind: sortInt, value: func(d *models.Device) interface{} { return d.DeviceID }},
+	"device_name": {column: "device_name", kind: sortString, value: func(d *models.Device) interface{} { return d.DeviceName }},
+	"created_at":  {column: "created_at", kind: sortTime, value: func(d *models.Device) interface{} { return d.CreatedAt }},
+}
+
+// FindByConditions 按条件分页查询设备
+func (r *deviceRepository) FindByConditions(query *models.DeviceQuery) ([]models.Device, int64, string, error) {
+	db := r.GetDB().Model(&models.Device{})
+
+	if query.Status != nil {
+		db = db.Where("device_status = ?", *query.Status)
+	}
+	if query.DeviceType != nil {
+		db = db.Where("device_type = ?", *query.DeviceType)
+	}
+	if query.SuperiorDeviceID != nil {
+		db = db.Where("superior_device_id = ?", *query.SuperiorDeviceID)
+	}
+	if query.Name != "" {
+		db = db.Where("device_name LIKE ?", likePrefix(query.Name))
+	}
+
+	return paginate(db, &query.ListQuery, deviceSortFields, func(d *models.Device) uint { return d.ID })
+}
+
 // Create 创建设备
 func (r *deviceRepository) Create(device *models.Device) error {
 	return r.GetDB().Create(device).Error
@@ -94,3 +170,130 @@ func (r *deviceRepository) Update(device *models.Device) error {
 func (r *deviceRepository) Delete(id uint) error {
 	return r.GetDB().Delete(&models.Device{}, id).Error
 }
+
+// UpdatePassword 更新口令哈希
+func (r *deviceRepository) UpdatePassword(id uint, passwordHash string) error {
+	return r.GetDB().Model(&models.Device{}).Where("id = ?", id).Update("pass_wd", passwordHash).Error
+}
+
+// IncrementLoginFailures 登录失败次数加一，返回累计失败次数
+func (r *deviceRepository) IncrementLoginFailures(id uint) (int, error) {
+	if err := r.GetDB().Model(&models.Device{}).Where("id = ?", id).
+		Update("login_failures", gorm.Expr("login_failures + 1")).Error; err != nil {
+		return 0, err
+	}
+
+	var device models.Device
+	if err := r.GetDB().Select("login_failures").First(&device, id).Error; err != nil {
+		return 0, err
+	}
+	return device.LoginFailures, nil
+}
+
+// LockLogin 锁定设备登录直到指定时间，并清零失败次数
+func (r *deviceRepository) LockLogin(id uint, until time.Time) error {
+	return r.GetDB().Model(&models.Device{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"login_failures": 0,
+		"locked_until":   until,
+	}).Error
+}
+
+// RecordLogin 记录登录成功
+// 条件更新避免覆盖校验口令期间提交的冻结或注销；注册时记录的是管理员的IP，
+// 设备首次登录成功时（last_login_time为空）改为设备自身的IP
+func (r *deviceRepository) RecordLogin(id uint, ip string) (bool, error) {
+	recorded := false
+	err := r.GetDB().Transaction(func(tx *gorm.DB) error {
+		enabled := tx.Model(&models.Device{}).
+			Where("id = ? AND device_status NOT IN ?", id, []int{models.DeviceStatusFrozen, models.DeviceStatusCancelled}).
+			Session(&gorm.Session{})
+		if err := enabled.Where("last_login_time IS NULL").Update("register_ip", ip).Error; err != nil {
+			return err
+		}
+		result := enabled.Updates(map[string]interface{}{
+			"device_status":   models.DeviceStatusOnline,
+			"last_login_ip":   ip,
+			"login_failures":  0,
+			"locked_until":    nil,
+			"last_login_time": time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		recorded = result.RowsAffected > 0
+		return nil
+	})
+	return recorded, err
+}
+
+// RecordHeartbeat 记录设备心跳
+// 心跳不是对设备信息的修改，不更新updated_at，避免频繁改变设备的ETag；
+// 条件更新避免读取设备后提交的冻结或注销被心跳改回在线
+func (r *deviceRepository) RecordHeartbeat(id uint, fields map[string]interface{}) (bool, error) {
+	result := r.GetDB().Model(&models.Device{}).
+		Where("id = ? AND device_status NOT IN ?", id, []int{models.DeviceStatusFrozen, models.DeviceStatusCancelled}).
+		UpdateColumns(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindHeartbeatTimeout 查找在线且最后一次心跳早于指定时间的设备
+func (r *deviceRepository) FindHeartbeatTimeout(before time.Time) ([]models.Device, error) {
+	var devices []models.Device
+	if err := r.GetDB().Where("device_status = ? AND last_heartbeat_at IS NOT NULL AND last_heartbeat_at < ?",
+		models.DeviceStatusOnline, before).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// MarkOffline 将心跳超时的设备置为离线
+// 条件更新避免覆盖查询后才到达的心跳
+func (r *deviceRepository) MarkOffline(id uint, before, at time.Time) (bool, error) {
+	result := r.GetDB().Model(&models.Device{}).
+		Where("id = ? AND device_status = ? AND last_heartbeat_at < ?", id, models.DeviceStatusOnline, before).
+		UpdateColumns(map[string]interface{}{
+			"device_status":       models.DeviceStatusOffline,
+			"off_line_time_stamp": at,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindAddressConflict 查找同一安全接入管理设备下使用相同长地址或短地址的其他设备
+func (r *deviceRepository) FindAddressConflict(superiorDeviceID int, longAddress, shortAddress string, excludeDeviceID int) (*models.Device, error) {
+	var device models.Device
+	if err := r.GetDB().Where("superior_device_id = ? AND device_id <> ?", superiorDeviceID, excludeDeviceID).
+		Where("((long_address <> '' AND long_address = ?) OR (short_address <> '' AND short_address = ?))", longAddress, shortAddress).
+		First(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// FindBySuperiorDeviceID 查找指定设备的下级设备
+func (r *deviceRepository) FindBySuperiorDeviceID(superiorDeviceID int) ([]models.Device, error) {
+	var devices []models.Device
+	if err := r.GetDB().Where("superior_device_id = ?", superiorDeviceID).Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// FindDeletedByDeviceID 根据设备ID查找已删除的设备
+func (r *deviceRepository) FindDeletedByDeviceID(deviceID int) (*models.Device, error) {
+	var device models.Device
+	if err := r.GetDB().Unscoped().Where("device_id = ? AND deleted_at IS NOT NULL", deviceID).First(&device).Error; err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// Restore 恢复已删除的设备
+func (r *deviceRepository) Restore(id uint) error {
+	return r.GetDB().Unscoped().Model(&models.Device{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
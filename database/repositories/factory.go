@@ -27,6 +27,24 @@ type RepositoryFactory interface {
 	// GetRadiusAuthRepository 获取Radius认证仓库
 	GetRadiusAuthRepository() RadiusAuthRepository
 
+	// GetRevokedTokenRepository 获取吊销令牌仓库
+	GetRevokedTokenRepository() RevokedTokenRepository
+
+	// GetCertRevocationRepository 获取证书吊销仓库
+	GetCertRevocationRepository() CertRevocationRepository
+
+	// GetKeyExportAuditRepository 获取私钥导出审计仓库
+	GetKeyExportAuditRepository() KeyExportAuditRepository
+
+	// GetUserStatusHistoryRepository 获取用户状态变更记录仓库
+	GetUserStatusHistoryRepository() UserStatusHistoryRepository
+
+	// GetDeviceMetricRepository 获取设备性能数据仓库
+	GetDeviceMetricRepository() DeviceMetricRepository
+
+	// GetScanCursorRepository 获取扫描进度仓库
+	GetScanCursorRepository() ScanCursorRepository
+
 	// WithTx 使用事务创建仓库工厂
 	WithTx(tx *gorm.DB) RepositoryFactory
 }
@@ -78,6 +96,36 @@ func (f *repositoryFactory) GetRadiusAuthRepository() RadiusAuthRepository {
 	return NewRadiusAuthRepository(f.db)
 }
 
+// GetRevokedTokenRepository 获取吊销令牌仓库
+func (f *repositoryFactory) GetRevokedTokenRepository() RevokedTokenRepository {
+	return NewRevokedTokenRepository(f.db)
+}
+
+// GetCertRevocationRepository 获取证书吊销仓库
+func (f *repositoryFactory) GetCertRevocationRepository() CertRevocationRepository {
+	return NewCertRevocationRepository(f.db)
+}
+
+// GetKeyExportAuditRepository 获取私钥导出审计仓库
+func (f *repositoryFactory) GetKeyExportAuditRepository() KeyExportAuditRepository {
+	return NewKeyExportAuditRepository(f.db)
+}
+
+// GetUserStatusHistoryRepository 获取用户状态变更记录仓库
+func (f *repositoryFactory) GetUserStatusHistoryRepository() UserStatusHistoryRepository {
+	return NewUserStatusHistoryRepository(f.db)
+}
+
+// GetDeviceMetricRepository 获取设备性能数据仓库
+func (f *repositoryFactory) GetDeviceMetricRepository() DeviceMetricRepository {
+	return NewDeviceMetricRepository(f.db)
+}
+
+// GetScanCursorRepository 获取扫描进度仓库
+func (f *repositoryFactory) GetScanCursorRepository() ScanCursorRepository {
+	return NewScanCursorRepository(f.db)
+}
+
 // WithTx 使用事务创建仓库工厂
 func (f *repositoryFactory) WithTx(tx *gorm.DB) RepositoryFactory {
 	return &repositoryFactory{
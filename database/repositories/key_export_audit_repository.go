@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+)
+
+// KeyExportAuditRepository 私钥导出审计仓库接口
+type KeyExportAuditRepository interface {
+	Repository
+	// Create 创建审计记录
+	Create(audit *models.KeyExportAudit) error
+	// FindByEntity 查找实体的私钥导出记录，按导出时间倒序
+	FindByEntity(entityType, entityID string) ([]models.KeyExportAudit, error)
+	// FindAll 查找所有私钥导出记录，按导出时间倒序
+	FindAll() ([]models.KeyExportAudit, error)
+}
+
+// keyExportAuditRepository 私钥导出审计仓库实现
+type keyExportAuditRepository struct {
+	*BaseRepository
+}
+
+// NewKeyExportAuditRepository 创建私钥导出审计仓库实例
+func NewKeyExportAuditRepository(db *gorm.DB) KeyExportAuditRepository {
+	return &keyExportAuditRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithTx 使用事务进行操作
+func (r *keyExportAuditRepository) WithTx(tx *gorm.DB) Repository {
+	return &keyExportAuditRepository{
+		BaseRepository: r.BaseRepository.WithTx(tx),
+	}
+}
+
+// Create 创建审计记录
+func (r *keyExportAuditRepository) Create(audit *models.KeyExportAudit) error {
+	return r.GetDB().Create(audit).Error
+}
+
+// FindByEntity 查找实体的私钥导出记录
+func (r *keyExportAuditRepository) FindByEntity(entityType, entityID string) ([]models.KeyExportAudit, error) {
+	var audits []models.KeyExportAudit
+	if err := r.GetDB().Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("exported_at DESC").Find(&audits).Error; err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
+
+// FindAll 查找所有私钥导出记录
+func (r *keyExportAuditRepository) FindAll() ([]models.KeyExportAudit, error) {
+	var audits []models.KeyExportAudit
+	if err := r.GetDB().Order("exported_at DESC").Find(&audits).Error; err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+)
+
+// 列表分页默认值
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// ErrInvalidQuery 列表查询参数无效
+var ErrInvalidQuery = errors.New("无效的查询参数")
+
+// sortKind 排序字段的值类型，用于从游标中还原排序值
+type sortKind int
+
+const (
+	sortInt sortKind = iota
+	sortString
+	sortTime
+)
+
+// sortField 允许排序的字段
+type sortField[T any] struct {
+	column string
+	kind   sortKind
+	value  func(*T) interface{}
+}
+
+// listCursor 游标内容，记录上一页最后一条记录的排序值和ID
+type listCursor struct {
+	Sort  string      `json:"s"`
+	Order string      `json:"o"`
+	Value interface{} `json:"v"`
+	ID    uint        `json:"id"`
+}
+
+// likePrefix 生成前缀匹配的LIKE参数，转义通配符
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+}
+
+// paginate 对已添加过滤条件的查询按创建时间范围过滤、计数、排序并取出一页记录
+// 使用游标时按排序值和ID进行键集分页，返回记录、满足条件的总数和下一页游标，没有下一页时游标为空
+func paginate[T any](db *gorm.DB, query *models.ListQuery, fields map[string]sortField[T], id func(*T) uint) ([]T, int64, string, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+	if query.Sort == "" {
+		query.Sort = "id"
+	}
+	query.Order = strings.ToLower(query.Order)
+	if query.Order == "" {
+		query.Order = "asc"
+	}
+
+	field, ok := fields[query.Sort]
+	if !ok {
+		return nil, 0, "", fmt.Errorf("%w: 不支持按%s排序", ErrInvalidQuery, query.Sort)
+	}
+	if query.Order != "asc" && query.Order != "desc" {
+		return nil, 0, "", fmt.Errorf("%w: order只能是asc或desc", ErrInvalidQuery)
+	}
+
+	if query.CreatedFrom != "" {
+		from, _, err := parseTimeBound(query.CreatedFrom)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("%w: created_from %v", ErrInvalidQuery, err)
+		}
+		db = db.Where("created_at >= ?", from)
+	}
+	if query.CreatedTo != "" {
+		to, dateOnly, err := parseTimeBound(query.CreatedTo)
+		if err != nil {
+			return nil, 0, "", fmt.Errorf("%w: created_to %v", ErrInvalidQuery, err)
+		}
+		if dateOnly {
+			// 只有日期时包含当天
+			db = db.Where("created_at < ?", to.AddDate(0, 0, 1))
+		} else {
+			db = db.Where("created_at <= ?", to)
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, "", err
+	}
+
+	cmp := ">"
+	if query.Order == "desc" {
+		cmp = "<"
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor, query.Sort, query.Order, field.kind)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		if field.column == "id" {
+			db = db.Where("id "+cmp+" ?", cursor.ID)
+		} else {
+			db = db.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", field.column, cmp, field.column, cmp),
+				cursor.Value, cursor.Value, cursor.ID)
+		}
+	} else {
+		db = db.Offset((query.Page - 1) * query.PageSize)
+	}
+
+	order := field.column + " " + query.Order
+	if field.column != "id" {
+		order += ", id " + query.Order
+	}
+
+	// 多取一条用于判断是否存在下一页
+	var records []T
+	if err := db.Order(order).Limit(query.PageSize + 1).Find(&records).Error; err != nil {
+		return nil, 0, "", err
+	}
+	if len(records) <= query.PageSize {
+		return records, total, "", nil
+	}
+
+	records = records[:query.PageSize]
+	last := &records[len(records)-1]
+	next, err := encodeCursor(listCursor{Sort: query.Sort, Order: query.Order, Value: field.value(last), ID: id(last)})
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return records, total, next, nil
+}
+
+// parseTimeBound 解析时间范围参数，返回是否只有日期
+func parseTimeBound(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("格式应为2006-01-02或RFC3339")
+	}
+	return t, false, nil
+}
+
+// encodeCursor 将游标编码为URL安全的字符串
+func encodeCursor(cursor listCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 解码游标，并按排序字段类型还原排序值
+// 游标必须由相同的sort和order生成
+func decodeCursor(value, sort, order string, kind sortKind) (*listCursor, error) {
+	invalid := fmt.Errorf("%w: 无效的cursor", ErrInvalidQuery)
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor listCursor
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, invalid
+	}
+	if cursor.Sort != sort || cursor.Order != order {
+		return nil, fmt.Errorf("%w: cursor与sort或order不一致", ErrInvalidQuery)
+	}
+
+	switch kind {
+	case sortInt:
+		number, ok := cursor.Value.(json.Number)
+		if !ok {
+			return nil, invalid
+		}
+		if cursor.Value, err = number.Int64(); err != nil {
+			return nil, invalid
+		}
+	case sortString:
+		if _, ok := cursor.Value.(string); !ok {
+			return nil, invalid
+		}
+	case sortTime:
+		text, ok := cursor.Value.(string)
+		if !ok {
+			return nil, invalid
+		}
+		if cursor.Value, err = time.Parse(time.RFC3339Nano, text); err != nil {
+			return nil, invalid
+		}
+	}
+	return &cursor, nil
+}
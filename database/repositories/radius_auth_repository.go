@@ -18,6 +18,10 @@ type RadiusAuthRepository interface {
 	Create(auth *models.RadPostAuth) error
 	// CreateTable 确保表存在
 	CreateTable() error
+	// FindAfterID 按ID升序查找指定ID之后的认证记录
+	FindAfterID(lastID int, limit int) ([]models.RadPostAuth, error)
+	// MaxID 获取当前最大的认证记录ID
+	MaxID() (int, error)
 }
 
 // radiusAuthRepository Radius认证仓库实现
@@ -114,3 +118,24 @@ func (r *radiusAuthRepository) CreateTable() error {
 	// 检查表是否存在，不存在则创建
 	return r.GetDB().AutoMigrate(&models.RadPostAuth{})
 }
+
+// FindAfterID 按ID升序查找指定ID之后的认证记录
+func (r *radiusAuthRepository) FindAfterID(lastID int, limit int) ([]models.RadPostAuth, error) {
+	var records []models.RadPostAuth
+	if err := r.GetDB().Where("id > ?", lastID).Order("id ASC").Limit(limit).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// MaxID 获取当前最大的认证记录ID
+func (r *radiusAuthRepository) MaxID() (int, error) {
+	var maxID *int
+	if err := r.GetDB().Model(&models.RadPostAuth{}).Select("MAX(id)").Scan(&maxID).Error; err != nil {
+		return 0, err
+	}
+	if maxID == nil {
+		return 0, nil
+	}
+	return *maxID, nil
+}
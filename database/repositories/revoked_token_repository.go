@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"gin-server/database/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedTokenRepository 吊销令牌仓库接口
+type RevokedTokenRepository interface {
+	Repository
+	// Revoke 吊销令牌，重复吊销不报错
+	Revoke(token *models.RevokedToken) error
+	// IsRevoked 检查令牌是否已吊销
+	IsRevoked(tokenID string) (bool, error)
+	// DeleteExpired 清理已过期的吊销记录
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// revokedTokenRepository 吊销令牌仓库实现
+type revokedTokenRepository struct {
+	*BaseRepository
+}
+
+// NewRevokedTokenRepository 创建吊销令牌仓库实例
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithTx 使用事务进行操作
+func (r *revokedTokenRepository) WithTx(tx *gorm.DB) Repository {
+	return &revokedTokenRepository{
+		BaseRepository: r.BaseRepository.WithTx(tx),
+	}
+}
+
+// Revoke 吊销令牌
+func (r *revokedTokenRepository) Revoke(token *models.RevokedToken) error {
+	return r.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsRevoked 检查令牌是否已吊销
+func (r *revokedTokenRepository) IsRevoked(tokenID string) (bool, error) {
+	var count int64
+	if err := r.GetDB().Model(&models.RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired 清理已过期的吊销记录
+func (r *revokedTokenRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.GetDB().Unscoped().Where("expires_at < ?", before).Delete(&models.RevokedToken{})
+	return result.RowsAffected, result.Error
+}
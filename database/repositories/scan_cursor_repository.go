@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"errors"
+
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScanCursorRepository 扫描进度仓库接口
+type ScanCursorRepository interface {
+	Repository
+	// Get 获取扫描任务最后处理的位置，没有记录时found为false
+	Get(name string) (position int64, found bool, err error)
+	// Save 保存扫描任务最后处理的位置
+	Save(name string, position int64) error
+}
+
+// scanCursorRepository 扫描进度仓库实现
+type scanCursorRepository struct {
+	*BaseRepository
+}
+
+// NewScanCursorRepository 创建扫描进度仓库实例
+func NewScanCursorRepository(db *gorm.DB) ScanCursorRepository {
+	return &scanCursorRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithTx 使用事务进行操作
+func (r *scanCursorRepository) WithTx(tx *gorm.DB) Repository {
+	return &scanCursorRepository{
+		BaseRepository: r.BaseRepository.WithTx(tx),
+	}
+}
+
+// Get 获取扫描任务最后处理的位置
+func (r *scanCursorRepository) Get(name string) (int64, bool, error) {
+	var cursor models.ScanCursor
+	if err := r.GetDB().Where("name = ?", name).First(&cursor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return cursor.Position, true, nil
+}
+
+// Save 保存扫描任务最后处理的位置
+func (r *scanCursorRepository) Save(name string, position int64) error {
+	return r.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"position", "updated_at"}),
+	}).Create(&models.ScanCursor{Name: name, Position: position}).Error
+}
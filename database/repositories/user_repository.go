@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"gin-server/database/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -19,6 +20,12 @@ type UserRepository interface {
 	FindByEmail(email string) (*models.User, error)
 	// FindAll 查找所有用户
 	FindAll() ([]models.User, error)
+	// UpdateIfUnmodified 在更新时间与updatedAt一致时更新指定字段，返回是否已更新
+	UpdateIfUnmodified(id uint, updatedAt time.Time, fields map[string]interface{}) (bool, error)
+	// FindInBatches 按ID顺序分批遍历所有用户
+	FindInBatches(batchSize int, fn func([]models.User) error) error
+	// FindByConditions 按条件分页查询用户，返回用户、总数和下一页游标
+	FindByConditions(query *models.UserQuery) ([]models.User, int64, string, error)
 	// Create 创建用户
 	Create(user *models.User) error
 	// Update 更新用户
@@ -27,6 +34,24 @@ type UserRepository interface {
 	Delete(id uint) error
 	// UpdateLastLogin 更新最后登录信息
 	UpdateLastLogin(id uint, ip string) error
+	// UpdatePassword 更新口令哈希
+	UpdatePassword(id uint, passwordHash string) error
+	// IncrementIllegalLoginTimes 非法登录次数加一，返回累计次数
+	IncrementIllegalLoginTimes(id uint) (int, error)
+	// ResetIllegalLoginTimes 清零非法登录次数
+	ResetIllegalLoginTimes(id uint) error
+	// UpdateStatus 更新用户状态和冻结时间
+	UpdateStatus(id uint, status int, frozenAt *time.Time) error
+	// FindFrozenBefore 查找冻结时间早于指定时间的用户
+	FindFrozenBefore(before time.Time) ([]models.User, error)
+	// FindByGatewayDeviceID 查找属于指定网关设备的用户
+	FindByGatewayDeviceID(gatewayDeviceID int) ([]models.User, error)
+	// CountByGateway 按网关设备统计用户数和在线用户数
+	CountByGateway() ([]models.GatewayUserCount, error)
+	// FindDeletedByUserID 根据用户唯一标识查找已删除的用户
+	FindDeletedByUserID(userID int) (*models.User, error)
+	// Restore 恢复已删除的用户
+	Restore(id uint) error
 }
 
 // userRepository 用户仓库实现
@@ -93,6 +118,52 @@ func (r *userRepository) FindAll() ([]models.User, error) {
 	return users, nil
 }
 
+// UpdateIfUnmodified 在更新时间与updatedAt一致时更新指定字段
+// 更新时间不一致说明记录已被其他请求修改，此时不更新并返回false
+func (r *userRepository) UpdateIfUnmodified(id uint, updatedAt time.Time, fields map[string]interface{}) (bool, error) {
+	result := r.GetDB().Model(&models.User{}).Where("id = ? AND updated_at = ?", id, updatedAt).Updates(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindInBatches 按ID顺序分批遍历所有用户，fn返回错误时停止
+func (r *userRepository) FindInBatches(batchSize int, fn func([]models.User) error) error {
+	var batch []models.User
+	return r.GetDB().FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// userSortFields 用户列表允许排序的字段
+var userSortFields = map[string]sortField[models.User]{
+	"id":         {column: "id", kind: sortInt, value: func(u *models.User) interface{} { return u.ID }},
+	"user_id":    {column: "user_id", kind: sortInt, value: func(u *models.User) interface{} { return u.UserID }},
+	"user_name":  {column: "user_name", kind: sortString, value: func(u *models.User) interface{} { return u.Username }},
+	"created_at": {column: "created_at", kind: sortTime, value: func(u *models.User) interface{} { return u.CreatedAt }},
+}
+
+// FindByConditions 按条件分页查询用户
+func (r *userRepository) FindByConditions(query *models.UserQuery) ([]models.User, int64, string, error) {
+	db := r.GetDB().Model(&models.User{})
+
+	if query.Status != nil {
+		db = db.Where("status = ?", *query.Status)
+	}
+	if query.UserType != nil {
+		db = db.Where("user_type = ?", *query.UserType)
+	}
+	if query.GatewayDeviceID != nil {
+		db = db.Where("gateway_device_id = ?", *query.GatewayDeviceID)
+	}
+	if query.Name != "" {
+		db = db.Where("user_name LIKE ?", likePrefix(query.Name))
+	}
+
+	return paginate(db, &query.ListQuery, userSortFields, func(u *models.User) uint { return u.ID })
+}
+
 // Create 创建用户
 func (r *userRepository) Create(user *models.User) error {
 	return r.GetDB().Create(user).Error
@@ -115,3 +186,82 @@ func (r *userRepository) UpdateLastLogin(id uint, ip string) error {
 		"login_ip":              ip,
 	}).Error
 }
+
+// UpdatePassword 更新口令哈希
+func (r *userRepository) UpdatePassword(id uint, passwordHash string) error {
+	return r.GetDB().Model(&models.User{}).Where("id = ?", id).Update("pass_wd", passwordHash).Error
+}
+
+// IncrementIllegalLoginTimes 非法登录次数加一，返回累计次数
+func (r *userRepository) IncrementIllegalLoginTimes(id uint) (int, error) {
+	if err := r.GetDB().Model(&models.User{}).Where("id = ?", id).
+		Update("illegal_login_times", gorm.Expr("COALESCE(illegal_login_times, 0) + 1")).Error; err != nil {
+		return 0, err
+	}
+
+	var user models.User
+	if err := r.GetDB().Select("illegal_login_times").First(&user, id).Error; err != nil {
+		return 0, err
+	}
+	if user.IllegalLoginTimes == nil {
+		return 0, nil
+	}
+	return *user.IllegalLoginTimes, nil
+}
+
+// ResetIllegalLoginTimes 清零非法登录次数
+func (r *userRepository) ResetIllegalLoginTimes(id uint) error {
+	return r.GetDB().Model(&models.User{}).Where("id = ?", id).Update("illegal_login_times", 0).Error
+}
+
+// UpdateStatus 更新用户状态和冻结时间
+func (r *userRepository) UpdateStatus(id uint, status int, frozenAt *time.Time) error {
+	return r.GetDB().Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    status,
+		"frozen_at": frozenAt,
+	}).Error
+}
+
+// FindFrozenBefore 查找冻结时间早于指定时间的用户
+func (r *userRepository) FindFrozenBefore(before time.Time) ([]models.User, error) {
+	var users []models.User
+	if err := r.GetDB().Where("status = ? AND frozen_at IS NOT NULL AND frozen_at < ?", models.UserStatusFrozen, before).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindByGatewayDeviceID 查找属于指定网关设备的用户
+func (r *userRepository) FindByGatewayDeviceID(gatewayDeviceID int) ([]models.User, error) {
+	var users []models.User
+	if err := r.GetDB().Where("gateway_device_id = ?", gatewayDeviceID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CountByGateway 按网关设备统计用户数和在线用户数
+func (r *userRepository) CountByGateway() ([]models.GatewayUserCount, error) {
+	var counts []models.GatewayUserCount
+	if err := r.GetDB().Model(&models.User{}).
+		Select("gateway_device_id, COUNT(*) AS total, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS online", models.UserStatusOnline).
+		Group("gateway_device_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// FindDeletedByUserID 根据用户唯一标识查找已删除的用户
+func (r *userRepository) FindDeletedByUserID(userID int) (*models.User, error) {
+	var user models.User
+	if err := r.GetDB().Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Restore 恢复已删除的用户
+func (r *userRepository) Restore(id uint) error {
+	return r.GetDB().Unscoped().Model(&models.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
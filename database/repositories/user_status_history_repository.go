@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+)
+
+// UserStatusHistoryRepository 用户状态变更记录仓库接口
+type UserStatusHistoryRepository interface {
+	Repository
+	// Create 创建状态变更记录
+	Create(history *models.UserStatusHistory) error
+	// FindByUserID 查找用户的状态变更记录，按变更时间倒序
+	FindByUserID(userID int) ([]models.UserStatusHistory, error)
+}
+
+// userStatusHistoryRepository 用户状态变更记录仓库实现
+type userStatusHistoryRepository struct {
+	*BaseRepository
+}
+
+// NewUserStatusHistoryRepository 创建用户状态变更记录仓库实例
+func NewUserStatusHistoryRepository(db *gorm.DB) UserStatusHistoryRepository {
+	return &userStatusHistoryRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithTx 使用事务进行操作
+func (r *userStatusHistoryRepository) WithTx(tx *gorm.DB) Repository {
+	return &userStatusHistoryRepository{
+		BaseRepository: r.BaseRepository.WithTx(tx),
+	}
+}
+
+// Create 创建状态变更记录
+func (r *userStatusHistoryRepository) Create(history *models.UserStatusHistory) error {
+	return r.GetDB().Create(history).Error
+}
+
+// FindByUserID 查找用户的状态变更记录
+func (r *userStatusHistoryRepository) FindByUserID(userID int) ([]models.UserStatusHistory, error) {
+	var histories []models.UserStatusHistory
+	if err := r.GetDB().Where("user_id = ?", userID).
+		Order("changed_at DESC, id DESC").Find(&histories).Error; err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
@@ -3,6 +3,7 @@ package testdata
 import (
 	"fmt"
 	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
 	"gin-server/database/models"
 	"log"
 
@@ -37,11 +38,17 @@ func (g *DeviceGenerator) Generate(db *gorm.DB, count int) error {
 
 	g.LogInfo("开始生成设备测试数据，数量: %d", count)
 
+	// 测试设备口令同样以哈希形式保存
+	rootPasswordHash, err := crypto.HashPassword("admin123456")
+	if err != nil {
+		return fmt.Errorf("计算设备口令哈希失败: %w", err)
+	}
+
 	// 创建根设备 (安全接入管理设备，设备类型为4，上级设备ID为0)
 	rootDevice := &models.Device{
 		DeviceName:       "安全接入管理设备",
 		DeviceType:       4,
-		Password:         "admin123456",
+		Password:         rootPasswordHash,
 		DeviceID:         1000, // 给根设备一个特定ID
 		SuperiorDeviceID: 0,    // 根设备没有上级
 		DeviceStatus:     1,    // 1表示在线
@@ -73,10 +80,15 @@ func (g *DeviceGenerator) Generate(db *gorm.DB, count int) error {
 		// 生成SES密钥
 		sesKey := g.RandomString(16)
 
+		passwordHash, err := crypto.HashPassword("device" + g.RandomString(6))
+		if err != nil {
+			return fmt.Errorf("计算设备口令哈希失败: %w", err)
+		}
+
 		device := &models.Device{
 			DeviceName:          deviceName,
 			DeviceType:          deviceType,
-			Password:            "device" + g.RandomString(6),
+			Password:            passwordHash,
 			DeviceID:            deviceID,
 			SuperiorDeviceID:    1000,              // 指向根设备
 			DeviceStatus:        g.RandomInt(1, 2), // 状态随机在线/离线
@@ -3,6 +3,7 @@ package testdata
 import (
 	"fmt"
 	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
 	"gin-server/database/models"
 	"log"
 	"time"
@@ -87,9 +88,15 @@ func (g *UserGenerator) Generate(db *gorm.DB, count int) error {
 				illegalLogins = &illegalValue
 			}
 
+			// 测试用户口令同样以哈希形式保存
+			passwordHash, err := crypto.HashPassword("pass" + g.RandomString(8))
+			if err != nil {
+				return fmt.Errorf("计算用户口令哈希失败: %w", err)
+			}
+
 			user := &models.User{
 				Username:           userName,
-				Password:           "pass" + g.RandomString(8),
+				Password:           passwordHash,
 				UserID:             userID,
 				UserType:           g.RandomInt(1, 3), // 随机用户类型1-3
 				GatewayDeviceID:    device.DeviceID,
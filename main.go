@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	stdlog "log"
@@ -9,13 +11,18 @@ import (
 	"runtime"
 	"time"
 
+	authMiddleware "gin-server/auth/middleware"
 	authModel "gin-server/auth/model"
 	authRouter "gin-server/auth/router"
+	authService "gin-server/auth/service"
 	"gin-server/config"
 	"gin-server/configmanager/common/crypto"
 	"gin-server/configmanager/log"
 	"gin-server/database"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
 	"gin-server/regist/router"
+	registService "gin-server/regist/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -55,11 +62,173 @@ func initLogManager(cfg *config.Config) (*log.LogManager, error) {
 	return logManager, nil
 }
 
+// initRadiusScanner 初始化并启动Radius认证记录扫描器
+// 扫描间隔为0或数据库不可用时返回nil
+func initRadiusScanner(cfg *config.Config) *authService.RadiusScanner {
+	if cfg.Auth.RadiusScanInterval <= 0 {
+		return nil
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		stdlog.Printf("警告: Radius认证记录扫描器启动失败: %v", err)
+		return nil
+	}
+	radiusDB, err := database.GetRadiusDB()
+	if err != nil {
+		stdlog.Printf("警告: Radius认证记录扫描器启动失败: %v", err)
+		return nil
+	}
+
+	scanner := authService.NewRadiusScanner(repositories.NewRepositoryFactory(db), radiusDB)
+	if err := scanner.Start(); err != nil {
+		stdlog.Printf("警告: Radius认证记录扫描器启动失败: %v", err)
+		return nil
+	}
+
+	stdlog.Println("Radius认证记录扫描器启动成功")
+	return scanner
+}
+
+// initCRLPublisher 初始化并启动证书吊销列表发布器
+// 数据库或内置CA不可用时返回nil
+func initCRLPublisher() *registService.CRLPublisher {
+	db, err := database.GetDB()
+	if err != nil {
+		stdlog.Printf("警告: 证书吊销列表发布器启动失败: %v", err)
+		return nil
+	}
+
+	publisher, err := registService.InitCRLPublisher(repositories.NewRepositoryFactory(db))
+	if err != nil {
+		stdlog.Printf("警告: 证书吊销列表发布器启动失败: %v", err)
+		return nil
+	}
+
+	stdlog.Println("证书吊销列表发布器启动成功")
+	return publisher
+}
+
+// initCertExpiryScanner 初始化并启动证书有效期扫描器
+// 扫描间隔为0或数据库不可用时返回nil
+func initCertExpiryScanner(cfg *config.Config) *registService.CertExpiryScanner {
+	if cfg.CertExpiry.ScanInterval <= 0 {
+		return nil
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		stdlog.Printf("警告: 证书有效期扫描器启动失败: %v", err)
+		return nil
+	}
+
+	scanner := registService.NewCertExpiryScanner(repositories.NewRepositoryFactory(db))
+	scanner.Start()
+
+	stdlog.Println("证书有效期扫描器启动成功")
+	return scanner
+}
+
+// initDeviceWatchdog 初始化并启动设备离线检测器
+// 检查间隔为0或数据库不可用时返回nil
+func initDeviceWatchdog(cfg *config.Config) *registService.DeviceWatchdog {
+	if cfg.Heartbeat.CheckInterval <= 0 {
+		return nil
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		stdlog.Printf("警告: 设备离线检测器启动失败: %v", err)
+		return nil
+	}
+
+	watchdog := registService.NewDeviceWatchdog(repositories.NewRepositoryFactory(db))
+	watchdog.Start()
+
+	stdlog.Println("设备离线检测器启动成功")
+	return watchdog
+}
+
+// initMetricsRollup 初始化并启动设备性能数据聚合器
+// 聚合间隔为0或数据库不可用时返回nil
+func initMetricsRollup(cfg *config.Config) *registService.MetricsRollup {
+	if cfg.Metrics.RollupInterval <= 0 {
+		return nil
+	}
+
+	db, err := database.GetDB()
+	if err != nil {
+		stdlog.Printf("警告: 设备性能数据聚合器启动失败: %v", err)
+		return nil
+	}
+
+	rollup := registService.NewMetricsRollup(repositories.NewRepositoryFactory(db))
+	rollup.Start()
+
+	stdlog.Println("设备性能数据聚合器启动成功")
+	return rollup
+}
+
+// initTLSServer 初始化并启动HTTPS监听
+// 配置了客户端CA时校验客户端证书链，否则只要求客户端证书与设备绑定的证书一致
+func initTLSServer(cfg *config.Config, handler http.Handler) (*http.Server, error) {
+	serverCert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载服务端证书失败: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequestClientCert,
+	}
+	if cfg.TLS.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	}
+
+	if cfg.TLS.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("客户端CA证书文件中没有有效证书: %s", cfg.TLS.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.TLS.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	server := &http.Server{
+		Addr:      ":" + cfg.TLS.Port,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+
+	go func() {
+		stdlog.Printf("HTTPS服务启动，监听端口: %s\n", cfg.TLS.Port)
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			stdlog.Printf("HTTPS服务启动失败: %v", err)
+		}
+	}()
+
+	return server, nil
+}
+
 // setupRoutes 设置所有API路由
 func setupRoutes(r *gin.Engine, logManager *log.LogManager) {
 	// 添加自定义的恢复中间件
 	r.Use(customRecoveryMiddleware())
 
+	// 添加客户端证书认证中间件，仅对携带客户端证书的HTTPS请求生效
+	r.Use(authMiddleware.ClientCertAuth())
+
+	// 添加令牌认证中间件，免认证路由由配置决定
+	r.Use(authMiddleware.TokenAuth())
+
 	// 设置注册模块路由
 	router.SetupRouter(r)
 
@@ -110,6 +279,18 @@ func main() {
 		stdlog.Printf("警告: 密钥管理器初始化失败，部分加密功能可能不可用")
 	}
 
+	// 初始化私钥加密器，需在数据库迁移前完成以加密历史私钥文件（非致命错误，允许继续）
+	if err := crypto.InitKeyEnvelope(cfg); err != nil {
+		stdlog.Printf("警告: 私钥加密器初始化失败，绑定私钥和读取已加密的私钥将不可用: %v", err)
+	}
+
+	// 初始化内置证书颁发机构（非致命错误，允许继续）
+	if err := pki.InitCA(cfg); err != nil {
+		stdlog.Printf("警告: 证书颁发机构初始化失败，证书签发功能将不可用: %v", err)
+	} else {
+		stdlog.Println("证书颁发机构初始化成功")
+	}
+
 	// 初始化数据库连接管理器（致命错误，必须停止）
 	if err := database.Initialize(cfg); err != nil {
 		stdlog.Printf("数据库初始化失败: %v", err)
@@ -118,9 +299,39 @@ func main() {
 	}
 	defer database.CloseAll() // 程序结束时关闭所有数据库连接
 
+	// 初始化令牌签名器（非致命错误，但启用认证时受保护接口将不可用）
+	if err := authService.InitTokenSigner(cfg); err != nil {
+		stdlog.Printf("警告: 令牌签名器初始化失败，登录和受保护接口将不可用: %v", err)
+	}
+
+	// 创建初始管理员（非致命错误，允许继续）
+	if db, err := database.GetDB(); err == nil {
+		if err := authService.EnsureBootstrapAdmin(repositories.NewRepositoryFactory(db), cfg); err != nil {
+			stdlog.Printf("警告: 初始管理员创建失败: %v", err)
+		}
+	}
+
+	// 启动证书吊销列表发布器（非致命错误，吊销检查不依赖CRL）
+	if publisher := initCRLPublisher(); publisher != nil {
+		defer publisher.Stop()
+	}
+
+	// 启动证书有效期扫描器（非致命错误，允许继续）
+	if scanner := initCertExpiryScanner(cfg); scanner != nil {
+		defer scanner.Stop()
+	}
+	if watchdog := initDeviceWatchdog(cfg); watchdog != nil {
+		defer watchdog.Stop()
+	}
+	if rollup := initMetricsRollup(cfg); rollup != nil {
+		defer rollup.Stop()
+	}
+
 	// 初始化Radius数据库（非致命错误，允许继续）
 	if err := authModel.InitRadiusDB(); err != nil {
 		stdlog.Printf("警告: Radius数据库初始化失败，认证功能可能不可用: %v", err)
+	} else if scanner := initRadiusScanner(cfg); scanner != nil {
+		defer scanner.Stop()
 	}
 
 	// 初始化日志管理器（非致命错误，允许继续）
@@ -145,6 +356,15 @@ func main() {
 	// 设置所有路由
 	setupRoutes(r, logManager)
 
+	// 启动HTTPS监听（非致命错误，HTTP服务不受影响）
+	if cfg.TLS.Enabled {
+		if server, err := initTLSServer(cfg, r); err != nil {
+			stdlog.Printf("警告: HTTPS服务初始化失败，客户端证书认证将不可用: %v", err)
+		} else {
+			defer server.Close()
+		}
+	}
+
 	// 启动服务
 	stdlog.Printf("服务器启动，监听端口: %s\n", cfg.ServerPort)
 
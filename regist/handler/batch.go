@@ -0,0 +1,397 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/compress"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 批量绑定的限制
+const (
+	MaxBatchArchiveSize = 64 * 1024 * 1024  // 上传的压缩包大小上限（64MB）
+	MaxBatchExtractSize = 256 * 1024 * 1024 // 解压后的文件总大小上限（256MB）
+	MaxBatchEntries     = 1000              // 清单条目数上限
+)
+
+// BatchManifestFile 压缩包根目录中的清单文件名
+const BatchManifestFile = "manifest.json"
+
+// 批量绑定条目的处理结果
+const (
+	BatchStatusBound   = "bound"   // 绑定成功
+	BatchStatusFailed  = "failed"  // 校验或绑定失败
+	BatchStatusSkipped = "skipped" // 全部成功模式下因其他条目失败未绑定
+)
+
+// BatchManifest 批量绑定清单
+type BatchManifest struct {
+	Entries []BatchManifestEntry `json:"entries"`
+}
+
+// BatchManifestEntry 批量绑定清单条目，cert和key为压缩包内的相对路径，至少指定一个
+type BatchManifestEntry struct {
+	Type string `json:"type"` // 实体类型：user或device
+	ID   string `json:"id"`   // 用户ID或设备ID
+	Cert string `json:"cert"` // 证书文件
+	Key  string `json:"key"`  // 私钥文件
+}
+
+// batchBindResult 批量绑定条目的处理结果
+type batchBindResult struct {
+	Index       int        `json:"index"`
+	Type        string     `json:"type"`
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Version     int        `json:"version,omitempty"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	NotAfter    *time.Time `json:"notAfter,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// batchEntry 校验通过待绑定的条目
+type batchEntry struct {
+	result   *batchBindResult
+	certData []byte
+	keyData  []byte
+	record   *models.Cert // 上传证书时解析出的证书记录
+	keyType  string       // 只上传私钥时的私钥类型
+}
+
+// BindCertBatch 从压缩包批量绑定证书和私钥
+// 支持tar.gz和zip格式，压缩包根目录的manifest.json指定每个文件对应的用户或设备；
+// 所有条目先完成校验再写入，atomic=true时任一条目失败则全部不绑定
+func BindCertBatch(c *gin.Context) {
+	cfg := config.GetConfig()
+	atomic := c.PostForm("atomic") == "true"
+
+	// 检查上传的压缩包
+	file, err := c.FormFile("archive")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到压缩包文件"})
+		return
+	}
+	if file.Size > MaxBatchArchiveSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+		return
+	}
+	var compressor compress.Compressor
+	name := strings.ToLower(file.Filename)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		compressor, _ = compress.NewCompressor(compress.FormatTarGz)
+	case strings.HasSuffix(name, ".zip"):
+		compressor, _ = compress.NewCompressor(compress.FormatZip)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件必须是.tar.gz、.tgz或.zip格式"})
+		return
+	}
+
+	overlapUntil, err := parseOverlap(c.PostForm("overlap"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 解压到临时目录
+	tmpDir, err := os.MkdirTemp("", "cert-batch-*")
+	if err != nil {
+		log.Printf("创建临时目录失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建临时目录失败"})
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	archivePath := filepath.Join(tmpDir, "archive")
+	if err := c.SaveUploadedFile(file, archivePath); err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("保存上传的压缩包失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		return
+	}
+	extractDir := filepath.Join(tmpDir, "files")
+	if err := compressor.Decompress(archivePath, extractDir, compress.WithMaxSize(MaxBatchExtractSize)); err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("解压压缩包失败: %v\n", err)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("解压失败: %v", err)})
+		return
+	}
+
+	manifest, err := readBatchManifest(extractDir)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验所有条目，校验阶段不写入任何数据
+	results := make([]*batchBindResult, len(manifest.Entries))
+	entries := make([]*batchEntry, 0, len(manifest.Entries))
+	seen := make(map[string]int)
+	for i, item := range manifest.Entries {
+		result := &batchBindResult{Index: i, Type: item.Type, ID: item.ID}
+		results[i] = result
+
+		key := item.Type + "/" + item.ID
+		if first, ok := seen[key]; ok {
+			result.Status = BatchStatusFailed
+			result.Error = fmt.Sprintf("与条目%d重复", first)
+			continue
+		}
+		seen[key] = i
+
+		entry, err := validateBatchEntry(repoFactory, extractDir, item)
+		if err != nil {
+			result.Status = BatchStatusFailed
+			result.Error = err.Error()
+			continue
+		}
+		entry.result = result
+		entries = append(entries, entry)
+	}
+
+	if atomic && len(entries) < len(results) {
+		for _, entry := range entries {
+			entry.result.Status = BatchStatusSkipped
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "部分条目校验失败，未绑定任何证书",
+			"data":  batchResponse(results, atomic),
+		})
+		return
+	}
+
+	// 写入阶段，全部成功模式下所有条目在同一事务中绑定
+	// 事务回滚时同时撤销已写入本地存储的证书和私钥文件
+	if atomic {
+		var failed *batchEntry
+		journal := store.NewJournaledStore(certStore)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			txFactory := repoFactory.WithTx(tx)
+			for _, entry := range entries {
+				if err := bindBatchEntry(txFactory, journal, entry, overlapUntil); err != nil {
+					failed = entry
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("批量绑定证书失败，已回滚: %v\n", err)
+			if err := journal.Rollback(); err != nil {
+				log.Printf("撤销批量绑定写入的文件失败: %v\n", err)
+			}
+			for _, entry := range entries {
+				entry.result.Status = BatchStatusSkipped
+			}
+			if failed != nil {
+				failed.result.Status = BatchStatusFailed
+				failed.result.Error = "绑定失败"
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "批量绑定失败，已回滚",
+				"data":  batchResponse(results, atomic),
+			})
+			return
+		}
+	} else {
+		for _, entry := range entries {
+			journal := store.NewJournaledStore(certStore)
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return bindBatchEntry(repoFactory.WithTx(tx), journal, entry, overlapUntil)
+			})
+			if err != nil {
+				log.Printf("批量绑定%s %s的证书失败: %v\n", entry.result.Type, entry.result.ID, err)
+				if err := journal.Rollback(); err != nil {
+					log.Printf("撤销%s %s写入的文件失败: %v\n", entry.result.Type, entry.result.ID, err)
+				}
+				entry.result.Status = BatchStatusFailed
+				entry.result.Error = "绑定失败"
+			}
+		}
+	}
+
+	log.Printf("批量绑定证书完成，共%d个条目\n", len(results))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "批量绑定完成",
+		"data":    batchResponse(results, atomic),
+	})
+}
+
+// readBatchManifest 读取并校验压缩包中的清单
+func readBatchManifest(root string) (*BatchManifest, error) {
+	data, err := os.ReadFile(filepath.Join(root, BatchManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("压缩包中未找到%s", BatchManifestFile)
+	}
+
+	var manifest BatchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析%s失败: %v", BatchManifestFile, err)
+	}
+	if len(manifest.Entries) == 0 {
+		return nil, errors.New("清单中没有条目")
+	}
+	if len(manifest.Entries) > MaxBatchEntries {
+		return nil, fmt.Errorf("清单条目数超过上限%d", MaxBatchEntries)
+	}
+	return &manifest, nil
+}
+
+// validateBatchEntry 校验清单条目，与单个绑定接口执行相同的证书和私钥校验
+func validateBatchEntry(repoFactory repositories.RepositoryFactory, root string, item BatchManifestEntry) (*batchEntry, error) {
+	if item.Type != "user" && item.Type != "device" {
+		return nil, errors.New("无效的实体类型")
+	}
+	id, err := strconv.Atoi(item.ID)
+	if err != nil {
+		return nil, errors.New("无效的实体ID")
+	}
+	if item.Cert == "" && item.Key == "" {
+		return nil, errors.New("未指定证书或私钥文件")
+	}
+
+	if item.Type == "user" {
+		_, err = repoFactory.GetUserRepository().FindByUserID(id)
+	} else {
+		_, err = repoFactory.GetDeviceRepository().FindByDeviceID(id)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%s不存在", item.Type)
+		}
+		return nil, fmt.Errorf("检查%s是否存在失败", item.Type)
+	}
+
+	entry := &batchEntry{}
+	if item.Key != "" {
+		if entry.keyData, err = readBatchFile(root, item.Key); err != nil {
+			return nil, err
+		}
+	}
+	if item.Cert == "" {
+		entry.keyType, err = parseUploadedKey(entry.keyData, repoFactory.GetCertRepository(), item.Type, item.ID)
+		return entry, err
+	}
+
+	if entry.certData, err = readBatchFile(root, item.Cert); err != nil {
+		return nil, err
+	}
+	entry.record, err = parseUploadedCert(entry.certData, entry.keyData, repoFactory, item.Type, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// readBatchFile 读取压缩包中的证书或私钥文件，路径必须位于解压目录内
+func readBatchFile(root, name string) ([]byte, error) {
+	clean := filepath.Clean(name)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("无效的文件路径: %s", name)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".pem") {
+		return nil, fmt.Errorf("文件必须是.pem格式: %s", name)
+	}
+
+	file, err := os.Open(filepath.Join(root, clean))
+	if err != nil {
+		return nil, fmt.Errorf("压缩包中未找到文件: %s", name)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %s", name)
+	}
+	if len(data) > MaxFileSize {
+		return nil, fmt.Errorf("文件大小超过限制: %s", name)
+	}
+	return data, nil
+}
+
+// bindBatchEntry 绑定一个校验通过的条目，并同步用户或设备表中的证书和密钥路径
+func bindBatchEntry(repoFactory repositories.RepositoryFactory, certStore store.CertStore, entry *batchEntry, overlapUntil *time.Time) error {
+	certRepo := repoFactory.GetCertRepository()
+	entityType, entityID := entry.result.Type, entry.result.ID
+
+	if entry.record != nil {
+		if err := certRepo.BindCert(entry.record, overlapUntil, storeCertFiles(certStore, entry.certData, entry.keyData)); err != nil {
+			return err
+		}
+	} else {
+		version, err := keyFileVersion(certRepo, entityType, entityID)
+		if err != nil {
+			return err
+		}
+		key := &models.Cert{EntityType: entityType, EntityID: entityID, Version: version, KeyType: entry.keyType}
+		if err := certStore.PutKey(key, entry.keyData); err != nil {
+			return err
+		}
+		if err := certRepo.BindKey(key); err != nil {
+			return err
+		}
+	}
+
+	bound, err := certRepo.FindByEntity(entityType, entityID)
+	if err != nil {
+		return err
+	}
+	if err := syncEntityCertPaths(repoFactory, entityType, entityID, bound); err != nil {
+		return err
+	}
+
+	entry.result.Status = BatchStatusBound
+	entry.result.Version = bound.Version
+	entry.result.Fingerprint = bound.Fingerprint
+	entry.result.NotAfter = bound.NotAfter
+	return nil
+}
+
+// batchResponse 构造批量绑定的结果报告
+func batchResponse(results []*batchBindResult, atomic bool) gin.H {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	return gin.H{
+		"atomic":    atomic,
+		"total":     len(results),
+		"succeeded": counts[BatchStatusBound],
+		"failed":    counts[BatchStatusFailed],
+		"skipped":   counts[BatchStatusSkipped],
+		"results":   results,
+	}
+}
@@ -0,0 +1,337 @@
+package handler
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IssueCertRequest 证书签发请求
+type IssueCertRequest struct {
+	CSR            string   `json:"csr"`                                     // PEM编码的证书签名请求，为空时由服务端生成密钥对
+	KeyAlgorithm   string   `json:"key_algorithm"`                           // 服务端生成密钥的算法：RSA、ECDSA或ED25519，默认ECDSA
+	KeyLength      int      `json:"key_length"`                              // 服务端生成密钥的长度，默认ECDSA为256、RSA为2048
+	CommonName     string   `json:"common_name"`                             // 通用名称，为空时取CSR中的名称或实体名称
+	DNSNames       []string `json:"dns_names"`                               // DNS主题备用名称
+	IPAddresses    []string `json:"ip_addresses"`                            // IP主题备用名称
+	EmailAddresses []string `json:"email_addresses"`                         // 邮箱主题备用名称
+	ValidityDays   int      `json:"validity_days" binding:"omitempty,min=1"` // 有效期（天），为空时使用默认有效期
+	ExtKeyUsages   []string `json:"ext_key_usages"`                          // 扩展密钥用途：client_auth、server_auth、email_protection、code_signing
+	Overlap        *int     `json:"overlap" binding:"omitempty,min=0"`       // 上一版本证书继续可用于认证的秒数，为空时使用CERT_ROTATION_OVERLAP
+}
+
+// IssueUserCert 使用内置CA为用户签发证书
+func IssueUserCert(c *gin.Context) {
+	cfg := config.GetConfig()
+	userID := c.Param("id")
+
+	// 检查用户ID是否有效
+	userIDInt, err := strconv.Atoi(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var request IssueCertRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	userRepo := repoFactory.GetUserRepository()
+
+	// 检查用户是否存在
+	user, err := userRepo.FindByUserID(userIDInt)
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("检查用户是否存在失败: %v\n", err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户是否存在失败"})
+		}
+		return
+	}
+
+	// 用户证书默认以用户名为通用名称，并带上用户邮箱
+	issueRequest := request.toIssueRequest(user.Username)
+	if len(issueRequest.EmailAddresses) == 0 && request.CSR == "" && user.Email != "" {
+		issueRequest.EmailAddresses = []string{user.Email}
+	}
+
+	result, ok := issueEntityCert(c, repoFactory.GetCertRepository(), "user", userID, &request, issueRequest)
+	if !ok {
+		return
+	}
+
+	// 更新用户表中的证书信息
+	user.CertID = result.certPath
+	user.KeyID = result.keyPath
+	if err := userRepo.Update(user); err != nil {
+		// 不中断请求，因为证书已经签发并保存成功
+		log.Printf("警告: 更新用户证书信息失败: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证书签发成功",
+		"data":    result.response("userID", userID),
+	})
+}
+
+// IssueDeviceCert 使用内置CA为设备签发证书
+func IssueDeviceCert(c *gin.Context) {
+	cfg := config.GetConfig()
+	deviceID := c.Param("id")
+
+	// 将设备ID从字符串转换为整数
+	deviceIDInt, err := strconv.Atoi(deviceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+		return
+	}
+
+	var request IssueCertRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	deviceRepo := repoFactory.GetDeviceRepository()
+
+	// 检查设备是否存在
+	device, err := deviceRepo.FindByDeviceID(deviceIDInt)
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("检查设备是否存在失败: %v\n", err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查设备是否存在失败"})
+		}
+		return
+	}
+
+	// 设备证书默认以设备名称为通用名称
+	issueRequest := request.toIssueRequest(device.DeviceName)
+
+	result, ok := issueEntityCert(c, repoFactory.GetCertRepository(), "device", deviceID, &request, issueRequest)
+	if !ok {
+		return
+	}
+
+	// 更新设备表中的证书信息
+	device.CertID = result.certPath
+	device.KeyID = result.keyPath
+	if err := deviceRepo.Update(device); err != nil {
+		// 不中断请求，因为证书已经签发并保存成功
+		log.Printf("警告: 更新设备证书信息失败: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证书签发成功",
+		"data":    result.response("deviceID", deviceID),
+	})
+}
+
+// GetCACert 下载内置CA证书，用于配置客户端信任和TLS_CLIENT_CA_FILE
+func GetCACert(c *gin.Context) {
+	ca, err := pki.GetCA()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendWithETag(c, "application/x-pem-file", pki.CACertFile, ca.CertificatePEM())
+}
+
+// issuedCert 签发结果
+type issuedCert struct {
+	cert       *x509.Certificate
+	certPEM    []byte
+	keyPEM     []byte
+	caCertPEM  []byte
+	certPath   string
+	keyPath    string
+	certRecord *models.Cert
+}
+
+// response 构造签发成功的响应数据，服务端生成的私钥只在此次响应中返回
+func (r *issuedCert) response(idField, entityID string) gin.H {
+	data := gin.H{
+		idField:         entityID,
+		"certPath":      r.certPath,
+		"version":       r.certRecord.Version,
+		"certificate":   string(r.certPEM),
+		"caCertificate": string(r.caCertPEM),
+		"subject":       r.certRecord.Subject,
+		"serialNumber":  r.certRecord.SerialNumber,
+		"fingerprint":   r.certRecord.Fingerprint,
+		"notBefore":     r.certRecord.NotBefore,
+		"notAfter":      r.certRecord.NotAfter,
+	}
+	if r.keyPEM != nil {
+		data["keyPath"] = r.keyPath
+		data["privateKey"] = string(r.keyPEM)
+	}
+	return data
+}
+
+// toIssueRequest 转换为CA签发参数，defaultCommonName在请求和CSR都未指定通用名称时使用
+func (r *IssueCertRequest) toIssueRequest(defaultCommonName string) pki.IssueRequest {
+	issueRequest := pki.IssueRequest{
+		CommonName:     r.CommonName,
+		DNSNames:       r.DNSNames,
+		IPAddresses:    r.IPAddresses,
+		EmailAddresses: r.EmailAddresses,
+		ValidityDays:   r.ValidityDays,
+		ExtKeyUsages:   r.ExtKeyUsages,
+	}
+	if issueRequest.CommonName == "" && r.CSR == "" {
+		issueRequest.CommonName = defaultCommonName
+	}
+	return issueRequest
+}
+
+// issueEntityCert 签发证书并保存到证书存储，同时更新证书记录
+// 失败时直接返回错误响应
+func issueEntityCert(c *gin.Context, certRepo repositories.CertRepository, entityType, entityID string, request *IssueCertRequest, issueRequest pki.IssueRequest) (*issuedCert, bool) {
+	cfg := config.GetConfig()
+
+	ca, err := pki.GetCA()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	result := &issuedCert{caCertPEM: ca.CertificatePEM()}
+	if request.CSR != "" {
+		result.cert, err = ca.SignCSR([]byte(request.CSR), issueRequest)
+	} else {
+		var key crypto.Signer
+		key, err = generateEntityKey(request.KeyAlgorithm, request.KeyLength)
+		if err == nil {
+			result.keyPEM, err = pki.EncodePrivateKey(key)
+		}
+		if err == nil {
+			result.cert, err = ca.Sign(key.Public(), issueRequest)
+		}
+	}
+	if err != nil {
+		if isIssueRequestError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			log.Printf("签发证书失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "签发证书失败"})
+		}
+		return nil, false
+	}
+	result.certPEM = pki.EncodeCertificate(result.cert)
+
+	info := pki.NewCertInfo(result.cert)
+	result.certRecord = &models.Cert{
+		EntityType:   entityType,
+		EntityID:     entityID,
+		Subject:      info.Subject,
+		Issuer:       info.Issuer,
+		SerialNumber: info.SerialNumber,
+		Fingerprint:  info.Fingerprint,
+		NotBefore:    &info.NotBefore,
+		NotAfter:     &info.NotAfter,
+		CertKeyType:  info.KeyType,
+		ChainLength:  2,
+		Source:       models.CertSourceCA,
+	}
+	// 服务端生成的私钥与证书一并绑定
+	if result.keyPEM != nil {
+		result.certRecord.KeyType = info.KeyType
+	}
+
+	// 签发的证书保存为新版本，文件中附带CA证书组成完整证书链
+	certChain := append(append([]byte{}, result.certPEM...), result.caCertPEM...)
+	if err := bindCertWithFiles(certRepo, certStore, result.certRecord, overlapUntilFrom(request.Overlap),
+		certChain, result.keyPEM); err != nil {
+		bindCertFailed(c, cfg, err)
+		return nil, false
+	}
+	result.certPath = result.certRecord.CertPath
+	result.keyPath = result.certRecord.KeyPath
+
+	// 使用CSR签发时私钥由调用方持有，解除沿用的原有密钥绑定
+	if result.keyPEM == nil && result.keyPath != "" {
+		if err := certRepo.BindKey(&models.Cert{EntityType: entityType, EntityID: entityID}); err != nil {
+			log.Printf("警告: 解除原有密钥绑定失败: %v\n", err)
+		}
+		result.keyPath = ""
+	}
+
+	log.Printf("内置CA已为%s %s签发证书，序列号: %s\n", entityType, entityID, info.SerialNumber)
+	return result, true
+}
+
+// generateEntityKey 按请求参数生成实体私钥，默认ECDSA P-256
+func generateEntityKey(algorithm string, length int) (crypto.Signer, error) {
+	if algorithm == "" {
+		algorithm = "ECDSA"
+	}
+	if length == 0 {
+		switch algorithm {
+		case "RSA", "rsa":
+			length = 2048
+		default:
+			length = 256
+		}
+	}
+	return pki.GenerateKey(algorithm, length)
+}
+
+// isIssueRequestError 判断是否为签发参数错误
+func isIssueRequestError(err error) bool {
+	for _, target := range []error{
+		pki.ErrInvalidCSR,
+		pki.ErrInvalidValidity,
+		pki.ErrUnknownExtKeyUsage,
+		pki.ErrInvalidIPAddress,
+		pki.ErrUnsupportedKeyAlgorithm,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,19 +1,21 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"gin-server/config"
 	"gin-server/database"
+	"gin-server/database/models"
 	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/store"
 
 	// 保留model包在第二阶段，但计划在第三阶段完全移除它
 
@@ -24,13 +26,6 @@ import (
 // 最大文件大小（8MB）
 const MaxFileSize = 8 * 1024 * 1024
 
-// 证书文件存储的基础路径
-const (
-	BaseCertPath = "regist/certs"
-	CertsDir     = "certs"
-	KeysDir      = "keys"
-)
-
 // BindUserCert 处理用户证书绑定
 func BindUserCert(c *gin.Context) {
 	cfg := config.GetConfig()
@@ -51,6 +46,12 @@ func BindUserCert(c *gin.Context) {
 	}
 	repoFactory := repositories.NewRepositoryFactory(db)
 	userRepo := repoFactory.GetUserRepository()
+	certRepo := repoFactory.GetCertRepository()
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 检查用户是否存在
 	user, err := userRepo.FindByUserID(userIDInt)
@@ -66,56 +67,42 @@ func BindUserCert(c *gin.Context) {
 		return
 	}
 
-	// 获取上传的文件
-	file, err := c.FormFile("cert")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到证书文件"})
+	// 读取上传的文件
+	data, ok := readUploadedPEM(c, "cert", "未找到证书文件")
+	if !ok {
 		return
 	}
 
-	// 检查文件大小
-	if file.Size > MaxFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+	// 可同时上传密钥，用于一并更换证书和密钥
+	keyData, ok := readUploadedPEM(c, "key", "")
+	if !ok {
 		return
 	}
 
-	// 检查文件扩展名
-	if file.Filename[len(file.Filename)-4:] != ".pem" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件必须是.pem格式"})
-		return
-	}
-
-	// 打开文件
-	src, err := file.Open()
+	// 解析并校验证书，校验密钥与证书是否匹配
+	certRecord, err := parseUploadedCert(data, keyData, repoFactory, "user", userID)
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("打开上传的文件失败: %v\n", err)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer src.Close()
 
-	// 保存文件
-	filePath, err := saveFileToDisk("user", userID, src, false)
+	// 上一版本的重叠期
+	overlapUntil, err := parseOverlap(c.PostForm("overlap"))
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("保存证书文件失败: %v\n", err)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存证书文件失败"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 更新证书记录
-	certRepo := repoFactory.GetCertRepository()
-	err = certRepo.UpdateCertPath("user", userID, filePath)
+	// 新证书保存为新版本，不覆盖之前的版本；同时上传了密钥时一并保存
+	err = bindCertWithFiles(certRepo, certStore, certRecord, overlapUntil, data, keyData)
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("更新证书记录失败: %v\n", err)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新证书记录失败"})
+		bindCertFailed(c, cfg, err)
 		return
 	}
+	filePath := certRecord.CertPath
+	if keyData != nil {
+		user.KeyID = certRecord.KeyPath
+	}
 
 	// 更新用户表中的证书信息
 	user.CertID = filePath
@@ -133,8 +120,12 @@ func BindUserCert(c *gin.Context) {
 		"code":    200,
 		"message": "证书绑定成功",
 		"data": gin.H{
-			"userID":   userID,
-			"certPath": filePath,
+			"userID":      userID,
+			"certPath":    filePath,
+			"version":     certRecord.Version,
+			"subject":     certRecord.Subject,
+			"fingerprint": certRecord.Fingerprint,
+			"notAfter":    certRecord.NotAfter,
 		},
 	})
 }
@@ -160,6 +151,11 @@ func BindUserKey(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	userRepo := repoFactory.GetUserRepository()
 	certRepo := repoFactory.GetCertRepository()
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 检查用户是否存在
 	user, err := userRepo.FindByUserID(userIDInt)
@@ -175,52 +171,50 @@ func BindUserKey(c *gin.Context) {
 		return
 	}
 
-	// 获取上传的文件
-	file, err := c.FormFile("key")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到密钥文件"})
-		return
-	}
-
-	// 检查文件大小
-	if file.Size > MaxFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+	// 读取上传的文件
+	data, ok := readUploadedPEM(c, "key", "未找到密钥文件")
+	if !ok {
 		return
 	}
 
-	// 检查文件扩展名
-	if file.Filename[len(file.Filename)-4:] != ".pem" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件必须是.pem格式"})
+	// 解析私钥，已绑定证书时校验密钥与证书是否匹配
+	keyType, err := parseUploadedKey(data, certRepo, "user", userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 打开文件
-	src, err := file.Open()
+	// 密钥保存到当前证书版本
+	version, err := keyFileVersion(certRepo, "user", userID)
 	if err != nil {
 		if cfg.DebugLevel == "true" {
-			log.Printf("打开上传的文件失败: %v\n", err)
+			log.Printf("获取证书版本失败: %v\n", err)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书版本失败"})
 		return
 	}
-	defer src.Close()
 
-	// 保存文件
-	filePath, err := saveFileToDisk("user", userID, src, true)
-	if err != nil {
+	// 保存密钥
+	key := &models.Cert{EntityType: "user", EntityID: userID, Version: version, KeyType: keyType}
+	journal := store.NewJournaledStore(certStore)
+	if err := journal.PutKey(key, data); err != nil {
 		if cfg.DebugLevel == "true" {
-			log.Printf("保存密钥文件失败: %v\n", err)
+			log.Printf("保存密钥失败: %v\n", err)
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存密钥文件失败"})
 		return
 	}
+	filePath := key.KeyPath
 
 	// 更新密钥记录
-	err = certRepo.UpdateKeyPath("user", userID, filePath)
+	err = certRepo.BindKey(key)
 	if err != nil {
 		if cfg.DebugLevel == "true" {
 			log.Printf("更新密钥记录失败: %v\n", err)
 		}
+		if err := journal.Rollback(); err != nil {
+			log.Printf("撤销写入的密钥文件失败: %v\n", err)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新密钥记录失败"})
 		return
 	}
@@ -243,6 +237,7 @@ func BindUserKey(c *gin.Context) {
 		"data": gin.H{
 			"userID":  userID,
 			"keyPath": filePath,
+			"keyType": keyType,
 		},
 	})
 }
@@ -261,6 +256,11 @@ func BindDeviceCert(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	deviceRepo := repoFactory.GetDeviceRepository()
 	certRepo := repoFactory.GetCertRepository()
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 将设备ID从字符串转换为整数
 	deviceIDInt, err := strconv.Atoi(deviceID)
@@ -283,55 +283,42 @@ func BindDeviceCert(c *gin.Context) {
 		return
 	}
 
-	// 获取上传的文件
-	file, err := c.FormFile("cert")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到证书文件"})
+	// 读取上传的文件
+	data, ok := readUploadedPEM(c, "cert", "未找到证书文件")
+	if !ok {
 		return
 	}
 
-	// 检查文件大小
-	if file.Size > MaxFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+	// 可同时上传密钥，用于一并更换证书和密钥
+	keyData, ok := readUploadedPEM(c, "key", "")
+	if !ok {
 		return
 	}
 
-	// 检查文件扩展名
-	if file.Filename[len(file.Filename)-4:] != ".pem" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件必须是.pem格式"})
-		return
-	}
-
-	// 打开文件
-	src, err := file.Open()
+	// 解析并校验证书，校验密钥与证书是否匹配
+	certRecord, err := parseUploadedCert(data, keyData, repoFactory, "device", deviceID)
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("打开上传的文件失败: %v\n", err)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer src.Close()
 
-	// 保存文件
-	filePath, err := saveFileToDisk("device", deviceID, src, false)
+	// 上一版本的重叠期
+	overlapUntil, err := parseOverlap(c.PostForm("overlap"))
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("保存证书文件失败: %v\n", err)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存证书文件失败"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 更新证书记录
-	err = certRepo.UpdateCertPath("device", deviceID, filePath)
+	// 新证书保存为新版本，不覆盖之前的版本；同时上传了密钥时一并保存
+	err = bindCertWithFiles(certRepo, certStore, certRecord, overlapUntil, data, keyData)
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("更新证书记录失败: %v\n", err)
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新证书记录失败"})
+		bindCertFailed(c, cfg, err)
 		return
 	}
+	filePath := certRecord.CertPath
+	if keyData != nil {
+		device.KeyID = certRecord.KeyPath
+	}
 
 	// 更新设备表中的证书信息
 	device.CertID = filePath
@@ -349,8 +336,12 @@ func BindDeviceCert(c *gin.Context) {
 		"code":    200,
 		"message": "证书绑定成功",
 		"data": gin.H{
-			"deviceID": deviceID,
-			"certPath": filePath,
+			"deviceID":    deviceID,
+			"certPath":    filePath,
+			"version":     certRecord.Version,
+			"subject":     certRecord.Subject,
+			"fingerprint": certRecord.Fingerprint,
+			"notAfter":    certRecord.NotAfter,
 		},
 	})
 }
@@ -369,6 +360,11 @@ func BindDeviceKey(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	deviceRepo := repoFactory.GetDeviceRepository()
 	certRepo := repoFactory.GetCertRepository()
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 将设备ID从字符串转换为整数
 	deviceIDInt, err := strconv.Atoi(deviceID)
@@ -391,52 +387,50 @@ func BindDeviceKey(c *gin.Context) {
 		return
 	}
 
-	// 获取上传的文件
-	file, err := c.FormFile("key")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到密钥文件"})
-		return
-	}
-
-	// 检查文件大小
-	if file.Size > MaxFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+	// 读取上传的文件
+	data, ok := readUploadedPEM(c, "key", "未找到密钥文件")
+	if !ok {
 		return
 	}
 
-	// 检查文件扩展名
-	if file.Filename[len(file.Filename)-4:] != ".pem" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "文件必须是.pem格式"})
+	// 解析私钥，已绑定证书时校验密钥与证书是否匹配
+	keyType, err := parseUploadedKey(data, certRepo, "device", deviceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 打开文件
-	src, err := file.Open()
+	// 密钥保存到当前证书版本
+	version, err := keyFileVersion(certRepo, "device", deviceID)
 	if err != nil {
 		if cfg.DebugLevel == "true" {
-			log.Printf("打开上传的文件失败: %v\n", err)
+			log.Printf("获取证书版本失败: %v\n", err)
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书版本失败"})
 		return
 	}
-	defer src.Close()
 
-	// 保存文件
-	filePath, err := saveFileToDisk("device", deviceID, src, true)
-	if err != nil {
+	// 保存密钥
+	key := &models.Cert{EntityType: "device", EntityID: deviceID, Version: version, KeyType: keyType}
+	journal := store.NewJournaledStore(certStore)
+	if err := journal.PutKey(key, data); err != nil {
 		if cfg.DebugLevel == "true" {
-			log.Printf("保存密钥文件失败: %v\n", err)
+			log.Printf("保存密钥失败: %v\n", err)
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存密钥文件失败"})
 		return
 	}
+	filePath := key.KeyPath
 
 	// 更新密钥记录
-	err = certRepo.UpdateKeyPath("device", deviceID, filePath)
+	err = certRepo.BindKey(key)
 	if err != nil {
 		if cfg.DebugLevel == "true" {
 			log.Printf("更新密钥记录失败: %v\n", err)
 		}
+		if err := journal.Rollback(); err != nil {
+			log.Printf("撤销写入的密钥文件失败: %v\n", err)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新密钥记录失败"})
 		return
 	}
@@ -459,6 +453,7 @@ func BindDeviceKey(c *gin.Context) {
 		"data": gin.H{
 			"deviceID": deviceID,
 			"keyPath":  filePath,
+			"keyType":  keyType,
 		},
 	})
 }
@@ -533,128 +528,204 @@ func GetCertInfo(c *gin.Context) {
 		return
 	}
 
-	// 返回证书信息
+	// 返回证书信息，并标记是否已过期
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "获取证书信息成功",
-		"data":    cert,
+		"data":    newCertStatus(*cert, time.Now()),
 	})
 }
 
-// 确保证书目录存在
-func ensureCertDirsExist() error {
+// readUploadedPEM 读取上传的PEM文件，校验失败时直接返回错误响应
+// missingMessage为空表示该文件可选，未上传时返回nil
+func readUploadedPEM(c *gin.Context, field, missingMessage string) ([]byte, bool) {
 	cfg := config.GetConfig()
 
-	// 创建基础目录
-	basePath := BaseCertPath
-	if err := os.MkdirAll(basePath, 0755); err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("创建基础证书目录失败: %v\n", err)
+	// 获取上传的文件
+	file, err := c.FormFile(field)
+	if err != nil {
+		if missingMessage == "" {
+			return nil, true
 		}
-		return fmt.Errorf("创建基础证书目录失败: %w", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": missingMessage})
+		return nil, false
 	}
 
-	// 创建证书目录
-	certPath := filepath.Join(basePath, CertsDir)
-	if err := os.MkdirAll(certPath, 0755); err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("创建证书目录失败: %v\n", err)
-		}
-		return fmt.Errorf("创建证书目录失败: %w", err)
+	// 检查文件大小
+	if file.Size > MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+		return nil, false
+	}
+
+	// 检查文件扩展名
+	if !strings.HasSuffix(strings.ToLower(file.Filename), ".pem") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件必须是.pem格式"})
+		return nil, false
 	}
 
-	// 创建密钥目录
-	keyPath := filepath.Join(basePath, KeysDir)
-	if err := os.MkdirAll(keyPath, 0700); err != nil { // 密钥目录权限更严格
+	// 读取文件内容
+	src, err := file.Open()
+	if err != nil {
 		if cfg.DebugLevel == "true" {
-			log.Printf("创建密钥目录失败: %v\n", err)
+			log.Printf("打开上传的文件失败: %v\n", err)
 		}
-		return fmt.Errorf("创建密钥目录失败: %w", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		return nil, false
 	}
+	defer src.Close()
 
-	if cfg.DebugLevel == "true" {
-		log.Println("证书目录结构已创建")
+	data, err := io.ReadAll(io.LimitReader(src, MaxFileSize))
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("读取上传的文件失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		return nil, false
 	}
 
-	return nil
+	return data, true
 }
 
-// 获取文件的绝对路径
-func getAbsolutePath(relativePath string) string {
-	// 获取当前工作目录
-	workDir, err := os.Getwd()
+// parseUploadedCert 解析并校验上传的证书链
+// 证书链中每个证书都必须在有效期内，已吊销的证书不能再次绑定；
+// 同时上传了密钥时密钥必须与证书匹配，否则实体已绑定的密钥必须与新证书的公钥匹配
+func parseUploadedCert(data, keyData []byte, repoFactory repositories.RepositoryFactory, entityType, entityID string) (*models.Cert, error) {
+	certRepo := repoFactory.GetCertRepository()
+	certStore, err := store.Default()
 	if err != nil {
-		log.Printf("获取工作目录失败: %v\n", err)
-		return relativePath // 如果失败，返回相对路径
+		return nil, err
 	}
+	chain, err := pki.ParseCertificateChain(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := pki.ValidateChain(chain, time.Now()); err != nil {
+		return nil, err
+	}
+	leaf := chain[0]
 
-	// 构建绝对路径
-	absPath := filepath.Join(workDir, relativePath)
-
-	// 根据操作系统规范化路径
-	if runtime.GOOS == "windows" {
-		// Windows 下使用反斜杠
-		absPath = strings.ReplaceAll(absPath, "/", "\\")
+	if revoked, err := repoFactory.GetCertRevocationRepository().IsRevoked(pki.Fingerprint(leaf)); err != nil {
+		log.Printf("查询证书吊销状态失败: %v\n", err)
+		return nil, errors.New("查询证书吊销状态失败")
+	} else if revoked {
+		return nil, errCertRevoked
 	}
 
-	return absPath
+	keyType := ""
+	if keyData != nil {
+		key, err := pki.ParsePrivateKey(keyData)
+		if err != nil {
+			return nil, err
+		}
+		if !pki.KeyMatchesCertificate(key, leaf) {
+			return nil, pki.ErrKeyCertMismatch
+		}
+		keyType = pki.PublicKeyType(key.Public())
+	} else if existing, err := certRepo.FindByEntity(entityType, entityID); err == nil && existing.KeyPath != "" {
+		if keyData, err := certStore.GetKey(existing); err != nil {
+			log.Printf("警告: 读取已绑定的密钥文件失败，跳过密钥匹配校验: %v\n", err)
+		} else if key, err := pki.ParsePrivateKey(keyData); err != nil {
+			log.Printf("警告: 解析已绑定的密钥失败，跳过密钥匹配校验: %v\n", err)
+		} else if !pki.KeyMatchesCertificate(key, leaf) {
+			return nil, pki.ErrKeyCertMismatch
+		}
+	}
+
+	info := pki.NewCertInfo(leaf)
+	return &models.Cert{
+		EntityType:   entityType,
+		EntityID:     entityID,
+		Subject:      info.Subject,
+		Issuer:       info.Issuer,
+		SerialNumber: info.SerialNumber,
+		Fingerprint:  info.Fingerprint,
+		NotBefore:    &info.NotBefore,
+		NotAfter:     &info.NotAfter,
+		CertKeyType:  info.KeyType,
+		KeyType:      keyType,
+		ChainLength:  len(chain),
+		Source:       models.CertSourceUpload,
+	}, nil
 }
 
-// saveFileToDisk 保存文件到磁盘
-func saveFileToDisk(entityType, entityID string, fileReader io.Reader, isKey bool) (string, error) {
-	cfg := config.GetConfig()
-
-	// 确保目录存在
-	if err := ensureCertDirsExist(); err != nil {
+// parseUploadedKey 解析上传的私钥，返回私钥类型
+// 实体已绑定证书时，私钥必须与证书的公钥匹配
+func parseUploadedKey(data []byte, certRepo repositories.CertRepository, entityType, entityID string) (string, error) {
+	key, err := pki.ParsePrivateKey(data)
+	if err != nil {
+		return "", err
+	}
+	certStore, err := store.Default()
+	if err != nil {
 		return "", err
 	}
 
-	// 确定文件类型和目录
-	dirType := CertsDir
-	if isKey {
-		dirType = KeysDir
+	if existing, err := certRepo.FindByEntity(entityType, entityID); err == nil && existing.CertPath != "" {
+		if certData, err := certStore.GetCert(existing); err != nil {
+			log.Printf("警告: 读取已绑定的证书文件失败，跳过密钥匹配校验: %v\n", err)
+		} else if chain, err := pki.ParseCertificateChain(certData); err != nil {
+			log.Printf("警告: 解析已绑定的证书失败，跳过密钥匹配校验: %v\n", err)
+		} else if !pki.KeyMatchesCertificate(key, chain[0]) {
+			return "", pki.ErrKeyCertMismatch
+		}
 	}
 
-	// 构建文件名和路径
-	fileName := fmt.Sprintf("%s_%s.pem", entityType, entityID)
-	filePath := filepath.Join(BaseCertPath, dirType, fileName)
+	return pki.PublicKeyType(key.Public()), nil
+}
 
-	// 创建文件
-	out, err := os.Create(filePath)
-	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("创建文件失败: %v\n", err)
+// certFileError 绑定证书时保存证书或私钥文件失败
+type certFileError struct {
+	message string
+	err     error
+}
+
+func (e *certFileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.message, e.err)
+}
+
+func (e *certFileError) Unwrap() error {
+	return e.err
+}
+
+// storeCertFiles 返回绑定证书时保存文件的回调，在分配版本号后保存证书，keyData不为nil时一并保存私钥
+func storeCertFiles(certStore store.CertStore, certData, keyData []byte) func(cert *models.Cert) error {
+	return func(cert *models.Cert) error {
+		if err := certStore.PutCert(cert, certData); err != nil {
+			return &certFileError{message: "保存证书文件失败", err: err}
+		}
+		if keyData != nil {
+			if err := certStore.PutKey(cert, keyData); err != nil {
+				return &certFileError{message: "保存密钥文件失败", err: err}
+			}
 		}
-		return "", fmt.Errorf("创建文件失败: %w", err)
+		return nil
 	}
-	defer out.Close()
+}
 
-	// 写入文件内容
-	_, err = io.Copy(out, fileReader)
+// bindCertWithFiles 绑定证书并保存证书和私钥文件，绑定失败时撤销已写入本地存储的文件
+func bindCertWithFiles(certRepo repositories.CertRepository, certStore store.CertStore, cert *models.Cert, overlapUntil *time.Time, certData, keyData []byte) error {
+	journal := store.NewJournaledStore(certStore)
+	err := certRepo.BindCert(cert, overlapUntil, storeCertFiles(journal, certData, keyData))
 	if err != nil {
-		if cfg.DebugLevel == "true" {
-			log.Printf("写入文件失败: %v\n", err)
+		if err := journal.Rollback(); err != nil {
+			log.Printf("撤销%s %s写入的文件失败: %v\n", cert.EntityType, cert.EntityID, err)
 		}
-		return "", fmt.Errorf("写入文件失败: %w", err)
 	}
+	return err
+}
 
-	// 设置合适的权限
-	if isKey {
-		// 密钥文件权限更严格
-		if err := os.Chmod(filePath, 0600); err != nil {
-			if cfg.DebugLevel == "true" {
-				log.Printf("设置密钥文件权限失败: %v\n", err)
-			}
-			// 不中断流程，只记录错误
-			log.Printf("警告: 设置密钥文件权限失败: %v\n", err)
+// bindCertFailed 返回绑定证书失败的响应，区分保存文件失败和更新证书记录失败
+func bindCertFailed(c *gin.Context, cfg *config.Config, err error) {
+	var fileErr *certFileError
+	if errors.As(err, &fileErr) {
+		if cfg.DebugLevel == "true" {
+			log.Printf("%v\n", fileErr)
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fileErr.message})
+		return
 	}
-
-	// 获取并返回绝对路径
-	absPath := getAbsolutePath(filePath)
 	if cfg.DebugLevel == "true" {
-		log.Printf("文件保存成功: %s\n", absPath)
+		log.Printf("更新证书记录失败: %v\n", err)
 	}
-
-	return absPath, nil
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "更新证书记录失败"})
 }
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"gin-server/auth/middleware"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 删除用户或设备时已绑定证书的处理方式
+const (
+	CertPolicyArchive = "archive" // 归档所有证书版本，恢复实体时一并恢复
+	CertPolicyRevoke  = "revoke"  // 吊销当前证书后归档，恢复实体后需重新绑定证书
+)
+
+// 删除网关设备时所属用户的处理方式
+const (
+	CascadeRefuse     = "refuse"     // 存在所属用户时拒绝删除
+	CascadeDeactivate = "deactivate" // 将所属用户置为注销状态后删除
+)
+
+// parseCertPolicy 解析certs查询参数，默认归档
+func parseCertPolicy(c *gin.Context) (string, error) {
+	policy := c.DefaultQuery("certs", CertPolicyArchive)
+	if policy != CertPolicyArchive && policy != CertPolicyRevoke {
+		return "", fmt.Errorf("certs只能是%s或%s", CertPolicyArchive, CertPolicyRevoke)
+	}
+	return policy, nil
+}
+
+// retireEntityCerts 按删除策略处理实体已绑定的证书
+// revoke时以停止使用为原因吊销当前证书，已吊销的证书不重复吊销；返回新建的吊销记录，没有吊销时为nil
+func retireEntityCerts(c *gin.Context, repoFactory repositories.RepositoryFactory, entityType, entityID, policy string) (*models.CertRevocation, error) {
+	var revocation *models.CertRevocation
+	if policy == CertPolicyRevoke {
+		var err error
+		if revocation, err = revokeActiveCert(c, repoFactory, entityType, entityID, fmt.Sprintf("删除%s", entityType)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repoFactory.GetCertRepository().ArchiveByEntity(entityType, entityID); err != nil {
+		return nil, err
+	}
+	return revocation, nil
+}
+
+// revokeActiveCert 以停止使用为原因吊销实体当前绑定的证书，已吊销的证书不重复吊销
+// 返回新建的吊销记录，没有绑定证书或已吊销时为nil
+func revokeActiveCert(c *gin.Context, repoFactory repositories.RepositoryFactory, entityType, entityID, comment string) (*models.CertRevocation, error) {
+	active, err := repoFactory.GetCertRepository().FindByEntity(entityType, entityID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if active == nil || active.CertPath == "" {
+		return nil, nil
+	}
+
+	revocation, err := newRevocation(active, pki.RevocationReasonCessationOfOperation, comment)
+	if err != nil {
+		return nil, err
+	}
+	revocationRepo := repoFactory.GetCertRevocationRepository()
+	if revoked, err := revocationRepo.IsRevoked(revocation.Fingerprint); err != nil || revoked {
+		return nil, err
+	}
+	if claims, ok := middleware.GetClaims(c); ok {
+		revocation.RevokedBy = claims.Name
+	}
+	if err := revocationRepo.Create(revocation); err != nil {
+		return nil, err
+	}
+	return revocation, nil
+}
+
+// publishRevocation 删除或注销实体时吊销了证书，重新生成CRL
+func publishRevocation(revocations ...*models.CertRevocation) {
+	revoked := false
+	for _, revocation := range revocations {
+		revoked = revoked || revocation != nil
+	}
+	if !revoked {
+		return
+	}
+	if publisher := service.GetCRLPublisher(); publisher != nil {
+		publisher.Trigger()
+	}
+}
+
+// userIDConflict 检查用户ID是否已被使用，返回冲突说明，未被使用时返回空字符串
+// 已删除用户的记录仍占用用户ID，需要通过恢复接口重新启用
+func userIDConflict(userRepo repositories.UserRepository, userID int) (string, error) {
+	_, err := userRepo.FindByUserID(userID)
+	if exists, err := recordExists(err); err != nil {
+		return "", err
+	} else if exists {
+		return "用户 ID 已存在", nil
+	}
+	_, err = userRepo.FindDeletedByUserID(userID)
+	if exists, err := recordExists(err); err != nil {
+		return "", err
+	} else if exists {
+		return fmt.Sprintf("用户 ID 属于已删除的用户，请通过 POST /restore/users/%d 恢复", userID), nil
+	}
+	return "", nil
+}
+
+// deviceIDConflict 检查设备ID是否已被使用，返回冲突说明，未被使用时返回空字符串
+// 已删除设备的记录仍占用设备ID，需要通过恢复接口重新启用
+func deviceIDConflict(deviceRepo repositories.DeviceRepository, deviceID int) (string, error) {
+	_, err := deviceRepo.FindByDeviceID(deviceID)
+	if exists, err := recordExists(err); err != nil {
+		return "", err
+	} else if exists {
+		return "设备 ID 已存在", nil
+	}
+	_, err = deviceRepo.FindDeletedByDeviceID(deviceID)
+	if exists, err := recordExists(err); err != nil {
+		return "", err
+	} else if exists {
+		return fmt.Sprintf("设备 ID 属于已删除的设备，请通过 POST /restore/devices/%d 恢复", deviceID), nil
+	}
+	return "", nil
+}
+
+// checkUserIDAvailable 检查用户ID未被使用，冲突或查询失败时写入响应并返回false
+func checkUserIDAvailable(c *gin.Context, userRepo repositories.UserRepository, userID int) bool {
+	conflict, err := userIDConflict(userRepo, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户 ID 失败"})
+		return false
+	}
+	if conflict != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": conflict})
+		return false
+	}
+	return true
+}
+
+// checkDeviceIDAvailable 检查设备ID未被使用，冲突或查询失败时写入响应并返回false
+func checkDeviceIDAvailable(c *gin.Context, deviceRepo repositories.DeviceRepository, deviceID int) bool {
+	conflict, err := deviceIDConflict(deviceRepo, deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查设备 ID 失败"})
+		return false
+	}
+	if conflict != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": conflict})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+
+	"gorm.io/gorm"
+)
+
+// fakeIDUserRepo 按用户ID区分正常和已删除用户的用户仓库
+type fakeIDUserRepo struct {
+	repositories.UserRepository
+	live, deleted map[int]bool
+	err           error
+}
+
+func (r *fakeIDUserRepo) FindByUserID(userID int) (*models.User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.live[userID] {
+		return &models.User{UserID: userID}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeIDUserRepo) FindDeletedByUserID(userID int) (*models.User, error) {
+	if r.deleted[userID] {
+		return &models.User{UserID: userID}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeIDDeviceRepo 按设备ID区分正常和已删除设备的设备仓库
+type fakeIDDeviceRepo struct {
+	repositories.DeviceRepository
+	live, deleted map[int]bool
+}
+
+func (r *fakeIDDeviceRepo) FindByDeviceID(deviceID int) (*models.Device, error) {
+	if r.live[deviceID] {
+		return &models.Device{DeviceID: deviceID}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeIDDeviceRepo) FindDeletedByDeviceID(deviceID int) (*models.Device, error) {
+	if r.deleted[deviceID] {
+		return &models.Device{DeviceID: deviceID}, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestIDConflict(t *testing.T) {
+	userRepo := &fakeIDUserRepo{live: map[int]bool{1: true}, deleted: map[int]bool{2: true}}
+	deviceRepo := &fakeIDDeviceRepo{live: map[int]bool{1: true}, deleted: map[int]bool{2: true}}
+
+	tests := []struct {
+		name string
+		id   int
+		want string
+	}{
+		{"未被使用", 3, ""},
+		{"正常记录占用", 1, "已存在"},
+		{"已删除记录占用", 2, "已删除"},
+	}
+	for _, tt := range tests {
+		userConflict, err := userIDConflict(userRepo, tt.id)
+		if err != nil {
+			t.Fatalf("%s: userIDConflict() error = %v", tt.name, err)
+		}
+		deviceConflict, err := deviceIDConflict(deviceRepo, tt.id)
+		if err != nil {
+			t.Fatalf("%s: deviceIDConflict() error = %v", tt.name, err)
+		}
+		for _, got := range []string{userConflict, deviceConflict} {
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("%s: 冲突说明 = %q, want 包含 %q", tt.name, got, tt.want)
+			}
+		}
+	}
+	if conflict, _ := userIDConflict(userRepo, 2); !strings.Contains(conflict, "/restore/users/2") {
+		t.Errorf("已删除用户的冲突说明 = %q, want 包含恢复接口", conflict)
+	}
+
+	dbErr := errors.New("connection refused")
+	if _, err := userIDConflict(&fakeIDUserRepo{err: dbErr}, 1); !errors.Is(err, dbErr) {
+		t.Errorf("查询失败时 userIDConflict() error = %v, want %v", err, dbErr)
+	}
+}
@@ -1,26 +1,32 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
+	"gin-server/auth/middleware"
 	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
 	"gin-server/database"
 	"gin-server/database/models"
 	"gin-server/database/repositories"
+	"gin-server/regist/service"
 
 	// 临时保留，后续完全迁移后可删除
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Device 结构体定义设备信息
 type Device struct {
 	DeviceName          string  `json:"device_name" binding:"required,min=4,max=50"` // 设备名称，长度限制，注册时需要
 	DeviceType          int     `json:"device_type" binding:"required"`              // 设备类型，1代表网关设备A型，2代表网关设备B型，3代表网关设备C型，4代表安全接入管理设备，注册时需要
-	PassWD              string  `json:"pass_wd" binding:"required,min=8"`            // 设备登录口令，注册时需要
+	PassWD              string  `json:"pass_wd" binding:"omitempty,min=8"`           // 设备登录口令，更新时为空表示保持原口令
 	DeviceID            int     `json:"device_id" binding:"required"`                // 设备唯一标识，注册时需要
-	SuperiorDeviceID    int     `json:"superior_device_id" binding:"required"`       // 上级设备ID，注册时需要，当设备为安全接入管理设备时，上级设备ID为0
+	SuperiorDeviceID    int     `json:"superior_device_id"`                          // 上级设备ID，当设备为安全接入管理设备时，上级设备ID为0
 	CertID              string  `json:"cert_id"`                                     // 证书ID，允许为 NULL
 	KeyID               string  `json:"key_id"`                                      // 密钥ID，允许为 NULL
 	DeviceStatus        int     `json:"device_status"`                               // 设备状态，注册时需要
@@ -68,10 +74,8 @@ func RegisterDevice(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	deviceRepo := repoFactory.GetDeviceRepository()
 
-	// 检查设备 ID 是否存在
-	existingDevice, err := deviceRepo.FindByDeviceID(request.DeviceID)
-	if err == nil && existingDevice != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "设备 ID 已存在"})
+	// 检查设备 ID 是否存在，包括已删除的设备
+	if !checkDeviceIDAvailable(c, deviceRepo, request.DeviceID) {
 		return
 	}
 
@@ -98,11 +102,25 @@ func RegisterDevice(c *gin.Context) {
 		}
 	}
 
+	// 校验设备类型、上级设备和网关地址
+	if !checkDeviceHierarchy(c, deviceRepo, request.DeviceID, request.DeviceType, request.SuperiorDeviceID,
+		request.LongAddress, request.ShortAddress) {
+		return
+	}
+
+	// 计算口令哈希，数据库中不保存明文口令
+	passwordHash, err := crypto.HashPassword(request.PassWD)
+	if err != nil {
+		log.Printf("计算设备口令哈希失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法创建设备"})
+		return
+	}
+
 	// 创建新设备模型
 	newDevice := &models.Device{
 		DeviceName:       request.DeviceName,
 		DeviceType:       request.DeviceType,
-		Password:         request.PassWD,
+		Password:         passwordHash,
 		DeviceID:         request.DeviceID,
 		SuperiorDeviceID: request.SuperiorDeviceID,
 		DeviceStatus:     2, // 默认离线状态
@@ -128,12 +146,19 @@ func RegisterDevice(c *gin.Context) {
 	})
 }
 
-// GetDevices 处理获取所有设备的请求
+// GetDevices 处理获取设备列表的请求
+// 支持按状态、类型、上级设备、设备名前缀和创建时间过滤，按page或cursor分页
 func GetDevices(c *gin.Context) {
 	cfg := config.GetConfig() // 获取全局配置
 
 	if cfg.DebugLevel == "true" {
-		log.Println("接收到获取所有设备的请求")
+		log.Println("接收到获取设备列表的请求")
+	}
+
+	var query models.DeviceQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的查询参数"})
+		return
 	}
 
 	// 获取数据库连接和仓库
@@ -145,9 +170,15 @@ func GetDevices(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	deviceRepo := repoFactory.GetDeviceRepository()
 
-	// 获取所有设备
-	devices, err := deviceRepo.FindAll()
+	devices, total, nextCursor, err := deviceRepo.FindByConditions(&query)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询设备列表失败: %v\n", err)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备列表失败"})
 		return
 	}
@@ -155,7 +186,7 @@ func GetDevices(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "获取设备列表成功",
-		"data":    devices,
+		"data":    listResponse(&query.ListQuery, total, nextCursor, devices),
 	})
 }
 
@@ -215,10 +246,46 @@ func UpdateDevice(c *gin.Context) {
 		}
 	}
 
+	// 修改上级设备时不能形成环
+	if device.SuperiorDeviceID != existingDevice.SuperiorDeviceID {
+		cycle, err := checkSuperiorCycle(deviceRepo, existingDevice.DeviceID, device.SuperiorDeviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		if cycle {
+			c.JSON(http.StatusConflict, gin.H{"error": "修改上级设备后会形成环"})
+			return
+		}
+	}
+
+	// 校验设备类型、上级设备和网关地址
+	if !checkDeviceHierarchy(c, deviceRepo, existingDevice.DeviceID, device.DeviceType, device.SuperiorDeviceID,
+		device.LongAddress, device.ShortAddress) {
+		return
+	}
+
+	// 修改设备类型时已有的下级设备和所属用户仍需满足层级关系
+	if device.DeviceType != existingDevice.DeviceType {
+		subordinates, err := deviceRepo.FindBySuperiorDeviceID(existingDevice.DeviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		users, err := repoFactory.GetUserRepository().FindByGatewayDeviceID(existingDevice.DeviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		if err := service.ValidateDeviceTypeChange(existingDevice.DeviceType, device.DeviceType, len(subordinates), len(users)); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// 更新设备字段
 	existingDevice.DeviceName = device.DeviceName
 	existingDevice.DeviceType = device.DeviceType
-	existingDevice.Password = device.PassWD
 	existingDevice.SuperiorDeviceID = device.SuperiorDeviceID
 	existingDevice.DeviceStatus = device.DeviceStatus
 	existingDevice.CertID = device.CertID
@@ -229,6 +296,17 @@ func UpdateDevice(c *gin.Context) {
 	existingDevice.ShortAddress = device.ShortAddress
 	existingDevice.SESKey = device.SESKey
 
+	// 仅在提供新口令时更新口令哈希
+	if device.PassWD != "" {
+		passwordHash, err := crypto.HashPassword(device.PassWD)
+		if err != nil {
+			log.Printf("计算设备口令哈希失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		existingDevice.Password = passwordHash
+	}
+
 	// 处理可能为nil的指针字段
 	if device.HardwareFingerprint != nil {
 		existingDevice.HardwareFingerprint = *device.HardwareFingerprint
@@ -252,6 +330,8 @@ func UpdateDevice(c *gin.Context) {
 }
 
 // DeleteDevice 处理删除设备的请求
+// 存在下级设备时拒绝删除；存在所属用户时按cascade参数拒绝删除或将用户置为注销状态；
+// 设备被软删除，已绑定的证书按certs参数归档或吊销，删除后可通过恢复接口恢复
 func DeleteDevice(c *gin.Context) {
 	cfg := config.GetConfig() // 获取全局配置
 
@@ -268,6 +348,16 @@ func DeleteDevice(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
 		return
 	}
+	cascade := c.DefaultQuery("cascade", CascadeRefuse)
+	if cascade != CascadeRefuse && cascade != CascadeDeactivate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cascade只能是refuse或deactivate"})
+		return
+	}
+	certPolicy, err := parseCertPolicy(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 获取数据库连接和仓库
 	db, err := database.GetDB()
@@ -285,14 +375,163 @@ func DeleteDevice(c *gin.Context) {
 		return
 	}
 
-	// 删除设备
-	if err := deviceRepo.Delete(existingDevice.ID); err != nil {
+	// 存在下级设备时不能删除
+	subordinates, err := deviceRepo.FindBySuperiorDeviceID(deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询下级设备失败"})
+		return
+	}
+	if len(subordinates) > 0 {
+		ids := make([]int, 0, len(subordinates))
+		for _, d := range subordinates {
+			ids = append(ids, d.DeviceID)
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "存在下级设备，不能删除", "subordinateDeviceIDs": ids})
+		return
+	}
+
+	// 存在所属用户时按级联策略处理
+	users, err := repoFactory.GetUserRepository().FindByGatewayDeviceID(deviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询所属用户失败"})
+		return
+	}
+	if len(users) > 0 && cascade == CascadeRefuse {
+		ids := make([]int, 0, len(users))
+		for _, u := range users {
+			ids = append(ids, u.UserID)
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "存在所属用户，不能删除", "userIDs": ids})
+		return
+	}
+
+	// 处理证书和所属用户并删除设备
+	actor := ""
+	if claims, ok := middleware.GetClaims(c); ok {
+		actor = claims.Name
+	}
+
+	var revocation *models.CertRevocation
+	var deactivated int64
+	var userRevocations []*models.CertRevocation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txFactory := repoFactory.WithTx(tx)
+		var err error
+		if revocation, err = retireEntityCerts(c, txFactory, "device", deviceIDStr, certPolicy); err != nil {
+			return err
+		}
+		// 所属用户按生命周期注销，吊销其当前证书
+		for i := range users {
+			user := &users[i]
+			if user.StatusIs(models.UserStatusCancelled) {
+				continue
+			}
+			if _, err := service.TransitionUser(txFactory, user, service.UserTransition{
+				To:     models.UserStatusCancelled,
+				Reason: fmt.Sprintf("所属网关设备 %d 已删除", deviceID),
+				Actor:  actor,
+			}); err != nil {
+				return err
+			}
+			userRevocation, err := revokeActiveCert(c, txFactory, "user", strconv.Itoa(user.UserID), "注销用户")
+			if err != nil {
+				return err
+			}
+			userRevocations = append(userRevocations, userRevocation)
+			deactivated++
+		}
+		return txFactory.GetDeviceRepository().Delete(existingDevice.ID)
+	})
+	if err != nil {
+		log.Printf("删除设备 %d 失败: %v\n", deviceID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法删除设备"})
 		return
 	}
+	publishRevocation(append(userRevocations, revocation)...)
 
+	log.Printf("设备 %d 已删除，证书处理方式: %s，注销所属用户: %d\n", deviceID, certPolicy, deactivated)
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "设备删除成功",
+		"data": gin.H{
+			"deviceID":         deviceID,
+			"cascade":          cascade,
+			"deactivatedUsers": deactivated,
+			"certs":            certPolicy,
+			"certRevoked":      revocation != nil,
+		},
+	})
+}
+
+// RestoreDevice 恢复已删除的设备，归档的证书一并恢复
+// 上级设备已删除时需要先恢复上级设备；级联注销的用户不会自动恢复
+func RestoreDevice(c *gin.Context) {
+	cfg := config.GetConfig() // 获取全局配置
+
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到恢复设备的请求")
+	}
+
+	deviceIDStr := c.Param("id")
+	deviceID, err := strconv.Atoi(deviceIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	deviceRepo := repoFactory.GetDeviceRepository()
+
+	if _, err := deviceRepo.FindByDeviceID(deviceID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "设备未被删除"})
+		return
+	}
+	device, err := deviceRepo.FindDeletedByDeviceID(deviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未找到已删除的设备"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询已删除的设备失败"})
+		}
+		return
+	}
+
+	// 检查恢复后是否冲突
+	if _, err := deviceRepo.FindByDeviceName(device.DeviceName); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "设备名称已被其他设备使用"})
+		return
+	}
+	if device.SuperiorDeviceID != 0 {
+		if _, err := deviceRepo.FindByDeviceID(device.SuperiorDeviceID); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "上级设备不存在，请先恢复上级设备"})
+			return
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txFactory := repoFactory.WithTx(tx)
+		if err := txFactory.GetDeviceRepository().Restore(device.ID); err != nil {
+			return err
+		}
+		return txFactory.GetCertRepository().RestoreByEntity("device", deviceIDStr)
+	})
+	if err != nil {
+		log.Printf("恢复设备 %d 失败: %v\n", deviceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法恢复设备"})
+		return
+	}
+	device.DeletedAt = gorm.DeletedAt{}
+
+	log.Printf("设备 %d 已恢复\n", deviceID)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "设备恢复成功",
+		"data":    convertDeviceModelToResponse(device),
 	})
 }
@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gin-server/auth/middleware"
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportKeyRequest 私钥导出请求
+type ExportKeyRequest struct {
+	Type    string `json:"type" binding:"required,oneof=user device"` // 实体类型
+	ID      string `json:"id" binding:"required"`                     // 用户ID或设备ID
+	Version int    `json:"version" binding:"omitempty,min=1"`         // 证书版本，为空时导出当前版本的私钥
+	Reason  string `json:"reason" binding:"required,max=255"`         // 导出原因，记录到审计日志
+}
+
+// DownloadCert 下载实体的证书
+// 默认返回当前版本的PEM编码实体证书，format=der时返回DER编码，version指定历史版本
+func DownloadCert(c *gin.Context) {
+	format := c.DefaultQuery("format", "pem")
+	if format != "pem" && format != "der" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format只能是pem或der"})
+		return
+	}
+
+	cert, chain, ok := loadEntityCert(c)
+	if !ok {
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_%s_v%d", cert.EntityType, cert.EntityID, cert.Version)
+	if format == "der" {
+		sendWithETag(c, "application/pkix-cert", fileName+".der", chain[0].Raw)
+		return
+	}
+	sendWithETag(c, "application/x-pem-file", fileName+".pem", pki.EncodeCertificate(chain[0]))
+}
+
+// DownloadCertChain 下载实体证书及其证书链，PEM编码，实体证书在前
+func DownloadCertChain(c *gin.Context) {
+	cert, chain, ok := loadEntityCert(c)
+	if !ok {
+		return
+	}
+
+	fileName := fmt.Sprintf("%s_%s_v%d_chain.pem", cert.EntityType, cert.EntityID, cert.Version)
+	sendWithETag(c, "application/x-pem-file", fileName, pki.EncodeCertificates(chain))
+}
+
+// GetCABundle 下载CA证书包
+// 包含内置CA证书和TLS_CLIENT_CA_FILE中配置的CA证书，用于校验本系统签发和接受的客户端证书
+func GetCABundle(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	var sets [][]*x509.Certificate
+	if ca, err := pki.GetCA(); err == nil {
+		sets = append(sets, []*x509.Certificate{ca.Certificate()})
+	}
+	if cfg.TLS.ClientCAFile != "" {
+		if data, err := os.ReadFile(cfg.TLS.ClientCAFile); err != nil {
+			log.Printf("读取客户端CA证书文件失败: %v\n", err)
+		} else if certs, err := pki.ParseCertificateChain(data); err != nil {
+			log.Printf("解析客户端CA证书文件失败: %v\n", err)
+		} else {
+			sets = append(sets, certs)
+		}
+	}
+
+	bundle := pki.CABundle(sets...)
+	if len(bundle) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "没有可用的CA证书"})
+		return
+	}
+	sendWithETag(c, "application/x-pem-file", "ca-bundle.pem", pki.EncodeCertificates(bundle))
+}
+
+// ExportKey 导出实体已绑定的私钥
+// 需要私钥导出权限且必须开启令牌认证，每次导出都会记录审计日志，审计记录保存失败时不返回私钥
+func ExportKey(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	claims, ok := middleware.GetClaims(c)
+	if !cfg.Auth.Enabled || !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "未开启令牌认证时不允许导出私钥"})
+		return
+	}
+
+	var request ExportKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	if !checkEntityExists(c, repoFactory, request.Type, request.ID) {
+		return
+	}
+
+	cert, ok := findCertVersion(c, repoFactory.GetCertRepository(), request.Type, request.ID, request.Version)
+	if !ok {
+		return
+	}
+	if cert.KeyPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未绑定私钥"})
+		return
+	}
+
+	var keyPEM []byte
+	certStore, readErr := store.Default()
+	if readErr == nil {
+		keyPEM, readErr = certStore.GetKey(cert)
+	}
+
+	audit := &models.KeyExportAudit{
+		CertID:      cert.ID,
+		EntityType:  cert.EntityType,
+		EntityID:    cert.EntityID,
+		Version:     cert.Version,
+		Fingerprint: cert.Fingerprint,
+		KeyType:     cert.KeyType,
+		ExportedBy:  claims.Name,
+		OperatorID:  claims.Subject,
+		ClientIP:    c.ClientIP(),
+		Reason:      request.Reason,
+		Success:     readErr == nil,
+		ExportedAt:  time.Now(),
+	}
+	if err := repoFactory.GetKeyExportAuditRepository().Create(audit); err != nil {
+		log.Printf("保存私钥导出审计记录失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存审计记录失败，未导出私钥"})
+		return
+	}
+
+	if readErr != nil {
+		log.Printf("读取%s %s的私钥失败: %v\n", cert.EntityType, cert.EntityID, readErr)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取私钥失败"})
+		return
+	}
+
+	log.Printf("用户 %s 导出了%s %s版本%d的私钥，原因: %s\n",
+		claims.Name, cert.EntityType, cert.EntityID, cert.Version, request.Reason)
+
+	fileName := fmt.Sprintf("%s_%s_v%d.key.pem", cert.EntityType, cert.EntityID, cert.Version)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, "application/x-pem-file", keyPEM)
+}
+
+// GetKeyExportAudits 查询私钥导出审计记录
+// 指定type和id时只返回该实体的记录
+func GetKeyExportAudits(c *gin.Context) {
+	cfg := config.GetConfig()
+	entityType := c.Query("type")
+	entityID := c.Query("id")
+
+	if (entityType == "") != (entityID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type和id需要同时指定"})
+		return
+	}
+	if entityType != "" && entityType != "user" && entityType != "device" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实体类型"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	auditRepo := repositories.NewRepositoryFactory(db).GetKeyExportAuditRepository()
+
+	var audits []models.KeyExportAudit
+	if entityType != "" {
+		audits, err = auditRepo.FindByEntity(entityType, entityID)
+	} else {
+		audits, err = auditRepo.FindAll()
+	}
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询私钥导出记录失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询私钥导出记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查询成功",
+		"data":    audits,
+	})
+}
+
+// loadEntityCert 根据type、id和version查询参数读取实体的证书链，失败时直接返回错误响应
+func loadEntityCert(c *gin.Context) (*models.Cert, []*x509.Certificate, bool) {
+	entityType := c.Query("type")
+	entityID := c.Query("id")
+
+	// 检查参数
+	if entityType == "" || entityID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少必要参数"})
+		return nil, nil, false
+	}
+	if entityType != "user" && entityType != "device" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实体类型"})
+		return nil, nil, false
+	}
+	version := 0
+	if value := c.Query("version"); value != "" {
+		v, err := strconv.Atoi(value)
+		if err != nil || v < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的证书版本"})
+			return nil, nil, false
+		}
+		version = v
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return nil, nil, false
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	if !checkEntityExists(c, repoFactory, entityType, entityID) {
+		return nil, nil, false
+	}
+
+	cert, ok := findCertVersion(c, repoFactory.GetCertRepository(), entityType, entityID, version)
+	if !ok {
+		return nil, nil, false
+	}
+	if cert.CertPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未绑定证书"})
+		return nil, nil, false
+	}
+
+	certStore, err := store.Default()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	data, err := certStore.GetCert(cert)
+	if err != nil {
+		log.Printf("读取证书文件 %s 失败: %v\n", cert.CertPath, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取证书文件失败"})
+		return nil, nil, false
+	}
+	chain, err := pki.ParseCertificateChain(data)
+	if err != nil {
+		log.Printf("解析证书文件 %s 失败: %v\n", cert.CertPath, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析证书文件失败"})
+		return nil, nil, false
+	}
+	return cert, chain, true
+}
+
+// findCertVersion 查询实体指定版本的证书记录，version为0时查询当前版本，失败时直接返回错误响应
+func findCertVersion(c *gin.Context, certRepo repositories.CertRepository, entityType, entityID string, version int) (*models.Cert, bool) {
+	cfg := config.GetConfig()
+
+	var cert *models.Cert
+	var err error
+	if version > 0 {
+		cert, err = certRepo.FindVersion(entityType, entityID, version)
+	} else {
+		cert, err = certRepo.FindByEntity(entityType, entityID)
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未找到证书信息"})
+		} else {
+			if cfg.DebugLevel == "true" {
+				log.Printf("获取证书信息失败: %v\n", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书信息失败"})
+		}
+		return nil, false
+	}
+	return cert, true
+}
+
+// sendWithETag 返回可缓存的下载内容
+// ETag由内容的SHA-256计算，请求的If-None-Match匹配时返回304
+func sendWithETag(c *gin.Context, contentType, fileName string, data []byte) {
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+fileName)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// etagMatches 判断If-None-Match请求头是否包含指定的ETag，弱校验时忽略W/前缀
+func etagMatches(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+	return false
+}
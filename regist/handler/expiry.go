@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// certStatus 证书记录及有效期状态
+type certStatus struct {
+	models.Cert
+	Expired       bool `json:"expired"`        // 是否已过期
+	RemainingDays *int `json:"remaining_days"` // 剩余有效天数，已过期时为负数，未记录过期时间时为空
+}
+
+// newCertStatus 计算证书的有效期状态
+func newCertStatus(cert models.Cert, now time.Time) certStatus {
+	status := certStatus{Cert: cert}
+	if cert.NotAfter != nil {
+		days := service.RemainingDays(*cert.NotAfter, now)
+		status.RemainingDays = &days
+		status.Expired = now.After(*cert.NotAfter)
+	}
+	return status
+}
+
+// GetExpiringCerts 查询指定天数内过期的证书，包括已过期的证书
+// 查询参数: within 天数，默认为最大的告警阈值；type 实体类型，可选user或device
+func GetExpiringCerts(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	within := 30
+	if thresholds := cfg.CertExpiry.AlertThresholds; len(thresholds) > 0 {
+		within = 0
+		for _, t := range thresholds {
+			if t > within {
+				within = t
+			}
+		}
+	}
+	if value := c.Query("within"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within必须是非负整数"})
+			return
+		}
+		within = days
+	}
+
+	entityType := c.Query("type")
+	if entityType != "" && entityType != "user" && entityType != "device" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实体类型"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	certRepo := repositories.NewRepositoryFactory(db).GetCertRepository()
+
+	now := time.Now()
+	certs, err := certRepo.FindExpiringBefore(entityType, now.Add(time.Duration(within)*24*time.Hour))
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询即将过期的证书失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询即将过期的证书失败"})
+		return
+	}
+
+	statuses := make([]certStatus, 0, len(certs))
+	for _, cert := range certs {
+		statuses = append(statuses, newCertStatus(cert, now))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查询成功",
+		"data":    statuses,
+	})
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"gin-server/auth/middleware"
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HeartbeatRequest 设备心跳请求
+type HeartbeatRequest struct {
+	DeviceID    int  `json:"device_id"`                                     // 设备ID，使用设备会话令牌或客户端证书时可省略
+	CPUUsage    *int `json:"cpu_usage" binding:"required,min=0,max=100"`    // CPU使用率（%）
+	MemoryUsage *int `json:"memory_usage" binding:"required,min=0,max=100"` // 内存使用率（%）
+	Uptime      int  `json:"uptime" binding:"min=0"`                        // 设备运行时长（秒）
+}
+
+// ReportHeartbeat 处理设备心跳上报
+// 更新设备的性能峰值和在线时长，记录性能采样，并将设备置为在线
+func ReportHeartbeat(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	var request HeartbeatRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 设备身份以令牌或客户端证书为准，请求中的设备ID必须一致
+	deviceID := request.DeviceID
+	if tokenDeviceID, ok := middleware.GetDeviceID(c); ok {
+		if deviceID != 0 && deviceID != tokenDeviceID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能上报当前设备的心跳"})
+			return
+		}
+		deviceID = tokenDeviceID
+	}
+	if deviceID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少设备ID"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	deviceRepo := repoFactory.GetDeviceRepository()
+
+	device, err := deviceRepo.FindByDeviceID(deviceID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询设备失败"})
+		}
+		return
+	}
+
+	now := time.Now()
+	recovered := service.DeviceRecovered(device)
+	offlineAt := device.OffLineTimeStamp
+	fields, err := service.ApplyHeartbeat(device, service.HeartbeatSample{
+		CPUUsage:    *request.CPUUsage,
+		MemoryUsage: *request.MemoryUsage,
+		Uptime:      request.Uptime,
+		At:          now,
+	}, service.HeartbeatTimeout(&cfg.Heartbeat), time.Duration(cfg.Heartbeat.PeakWindow)*time.Second)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	recorded, err := deviceRepo.RecordHeartbeat(device.ID, fields)
+	if err != nil {
+		log.Printf("保存设备 %d 的心跳失败: %v\n", deviceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存心跳失败"})
+		return
+	}
+	// 读取设备后设备被冻结或注销
+	if !recorded {
+		c.JSON(http.StatusForbidden, gin.H{"error": service.ErrDeviceDisabled.Error()})
+		return
+	}
+
+	// 记录性能采样，失败不影响心跳
+	if err := repoFactory.GetDeviceMetricRepository().Create(&models.DeviceMetric{
+		DeviceID:    device.DeviceID,
+		SampledAt:   now,
+		CPUUsage:    *request.CPUUsage,
+		MemoryUsage: *request.MemoryUsage,
+	}); err != nil {
+		log.Printf("保存设备 %d 的性能采样失败: %v\n", deviceID, err)
+	}
+
+	// 心跳超时被判定离线的设备重新上报，记录恢复事件
+	if recovered {
+		service.NotifyDeviceRecovered(repoFactory, device, *offlineAt, now)
+	}
+
+	if cfg.DebugLevel == "true" {
+		log.Printf("设备 %d 心跳: CPU %d%%, 内存 %d%%, 运行时长 %d秒\n",
+			deviceID, *request.CPUUsage, *request.MemoryUsage, request.Uptime)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "心跳上报成功",
+		"data": gin.H{
+			"deviceID":        device.DeviceID,
+			"deviceStatus":    device.DeviceStatus,
+			"peakCPUUsage":    device.PeakCPUUsage,
+			"peakMemoryUsage": device.PeakMemoryUsage,
+			"onlineDuration":  device.OnlineDuration,
+			"interval":        cfg.Heartbeat.Interval,
+		},
+	})
+}
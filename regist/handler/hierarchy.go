@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// checkDeviceHierarchy 校验设备类型与上级设备的组合，以及网关设备的地址在所属安全接入管理设备下是否唯一
+// 失败时直接返回错误响应
+func checkDeviceHierarchy(c *gin.Context, deviceRepo repositories.DeviceRepository, deviceID, deviceType, superiorID int, longAddress, shortAddress string) bool {
+	var superior *models.Device
+	if superiorID != 0 {
+		device, err := deviceRepo.FindByDeviceID(superiorID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("查询上级设备失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询上级设备失败"})
+			return false
+		}
+		superior = device
+	}
+	if err := service.ValidateDeviceHierarchy(deviceType, superiorID, superior); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if !models.IsGatewayType(deviceType) {
+		return true
+	}
+
+	conflict, err := deviceRepo.FindAddressConflict(superiorID, longAddress, shortAddress, deviceID)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "长地址或短地址已被同一安全接入管理设备下的其他设备使用",
+			"conflictDeviceID": conflict.DeviceID,
+		})
+		return false
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("检查设备地址失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检查设备地址失败"})
+		return false
+	}
+	return true
+}
+
+// checkUserGateway 校验用户所属的设备是已存在的网关设备，失败时直接返回错误响应
+func checkUserGateway(c *gin.Context, deviceRepo repositories.DeviceRepository, gatewayDeviceID int) bool {
+	gateway, err := deviceRepo.FindByDeviceID(gatewayDeviceID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("查询网关设备失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询网关设备失败"})
+		return false
+	}
+	if err := service.ValidateUserGateway(gateway); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ActivateCertRequest 切换证书版本请求
+type ActivateCertRequest struct {
+	Type    string `json:"type" binding:"required,oneof=user device"` // 实体类型
+	ID      string `json:"id" binding:"required"`                     // 用户ID或设备ID
+	Version int    `json:"version" binding:"required,min=1"`          // 切换到的证书版本
+	Overlap *int   `json:"overlap" binding:"omitempty,min=0"`         // 原当前版本继续可用于认证的秒数，为空时使用CERT_ROTATION_OVERLAP
+}
+
+// certHistoryItem 证书版本信息
+type certHistoryItem struct {
+	certStatus
+	Revoked bool `json:"revoked"` // 是否已吊销
+}
+
+// GetCertHistory 查询实体的所有证书版本
+func GetCertHistory(c *gin.Context) {
+	cfg := config.GetConfig()
+	entityType := c.Query("type")
+	entityID := c.Query("id")
+
+	// 检查参数
+	if entityType == "" || entityID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少必要参数"})
+		return
+	}
+	if entityType != "user" && entityType != "device" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的实体类型"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	if !checkEntityExists(c, repoFactory, entityType, entityID) {
+		return
+	}
+
+	certs, err := repoFactory.GetCertRepository().FindHistory(entityType, entityID)
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询证书版本失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证书版本失败"})
+		return
+	}
+
+	revocations, err := repoFactory.GetCertRevocationRepository().FindByEntity(entityType, entityID)
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询吊销记录失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询吊销记录失败"})
+		return
+	}
+	revoked := make(map[string]bool, len(revocations))
+	for _, r := range revocations {
+		revoked[r.Fingerprint] = true
+	}
+
+	now := time.Now()
+	items := make([]certHistoryItem, 0, len(certs))
+	for _, cert := range certs {
+		items = append(items, certHistoryItem{
+			certStatus: newCertStatus(cert, now),
+			Revoked:    cert.Fingerprint != "" && revoked[cert.Fingerprint],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查询成功",
+		"data":    items,
+	})
+}
+
+// ActivateCert 切换实体当前使用的证书版本，用于轮换到新版本或回滚到旧版本
+// 已吊销、已过期或证书文件丢失的版本不能切换
+func ActivateCert(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	var request ActivateCertRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	certRepo := repoFactory.GetCertRepository()
+
+	if !checkEntityExists(c, repoFactory, request.Type, request.ID) {
+		return
+	}
+
+	target, err := certRepo.FindVersion(request.Type, request.ID, request.Version)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "证书版本不存在"})
+		} else {
+			if cfg.DebugLevel == "true" {
+				log.Printf("查询证书版本失败: %v\n", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证书版本失败"})
+		}
+		return
+	}
+
+	// 校验目标版本是否可用
+	if target.CertPath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该版本未绑定证书"})
+		return
+	}
+	if certStore, err := store.Default(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if _, err := certStore.GetCert(target); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该版本的证书文件不存在"})
+		return
+	}
+	if target.NotAfter != nil && time.Now().After(*target.NotAfter) {
+		c.JSON(http.StatusConflict, gin.H{"error": "该版本的证书已过期"})
+		return
+	}
+	if target.Fingerprint != "" {
+		if revoked, err := repoFactory.GetCertRevocationRepository().IsRevoked(target.Fingerprint); err != nil {
+			log.Printf("查询证书吊销状态失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证书吊销状态失败"})
+			return
+		} else if revoked {
+			c.JSON(http.StatusConflict, gin.H{"error": errCertRevoked.Error()})
+			return
+		}
+	}
+
+	previous, err := certRepo.FindByEntity(request.Type, request.ID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询当前证书版本失败"})
+		return
+	}
+
+	activated, err := certRepo.Activate(request.Type, request.ID, request.Version, overlapUntilFrom(request.Overlap))
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("切换证书版本失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "切换证书版本失败"})
+		return
+	}
+
+	// 用户和设备表中记录的是当前版本的证书和密钥路径
+	if err := syncEntityCertPaths(repoFactory, request.Type, request.ID, activated); err != nil {
+		log.Printf("警告: 更新%s证书信息失败: %v\n", request.Type, err)
+	}
+
+	previousVersion := 0
+	if previous != nil {
+		previousVersion = previous.Version
+	}
+	log.Printf("%s %s的证书已从版本%d切换到版本%d\n", request.Type, request.ID, previousVersion, activated.Version)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证书版本切换成功",
+		"data": gin.H{
+			"type":            request.Type,
+			"id":              request.ID,
+			"version":         activated.Version,
+			"previousVersion": previousVersion,
+			"certPath":        activated.CertPath,
+			"keyPath":         activated.KeyPath,
+			"fingerprint":     activated.Fingerprint,
+			"notAfter":        activated.NotAfter,
+		},
+	})
+}
+
+// checkEntityExists 检查用户或设备是否存在，不存在时直接返回错误响应
+func checkEntityExists(c *gin.Context, repoFactory repositories.RepositoryFactory, entityType, entityID string) bool {
+	id, err := strconv.Atoi(entityID)
+	if err != nil {
+		if entityType == "user" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+		}
+		return false
+	}
+
+	if entityType == "user" {
+		_, err = repoFactory.GetUserRepository().FindByUserID(id)
+	} else {
+		_, err = repoFactory.GetDeviceRepository().FindByDeviceID(id)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s不存在", entityType)})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("检查%s是否存在失败", entityType)})
+		}
+		return false
+	}
+	return true
+}
+
+// syncEntityCertPaths 将用户或设备表中的证书和密钥路径更新为指定版本
+func syncEntityCertPaths(repoFactory repositories.RepositoryFactory, entityType, entityID string, cert *models.Cert) error {
+	id, err := strconv.Atoi(entityID)
+	if err != nil {
+		return err
+	}
+
+	if entityType == "user" {
+		userRepo := repoFactory.GetUserRepository()
+		user, err := userRepo.FindByUserID(id)
+		if err != nil {
+			return err
+		}
+		user.CertID = cert.CertPath
+		user.KeyID = cert.KeyPath
+		return userRepo.Update(user)
+	}
+
+	deviceRepo := repoFactory.GetDeviceRepository()
+	device, err := deviceRepo.FindByDeviceID(id)
+	if err != nil {
+		return err
+	}
+	device.CertID = cert.CertPath
+	device.KeyID = cert.KeyPath
+	return deviceRepo.Update(device)
+}
+
+// keyFileVersion 返回单独绑定密钥时密钥文件使用的版本号，即当前证书版本
+func keyFileVersion(certRepo repositories.CertRepository, entityType, entityID string) (int, error) {
+	active, err := certRepo.FindByEntity(entityType, entityID)
+	if err == nil {
+		return active.Version, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return 0, err
+	}
+	return certRepo.NextVersion(entityType, entityID)
+}
+
+// parseOverlap 解析表单中的重叠期秒数，为空时使用默认重叠期
+func parseOverlap(value string) (*time.Time, error) {
+	if value == "" {
+		return overlapUntilFrom(nil), nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return nil, errors.New("overlap必须是非负整数")
+	}
+	return overlapUntilFrom(&seconds), nil
+}
+
+// overlapUntilFrom 计算上一版本的重叠期截止时间，重叠期为0时返回nil
+func overlapUntilFrom(seconds *int) *time.Time {
+	overlap := config.GetConfig().CertRotation.Overlap
+	if seconds != nil {
+		overlap = *seconds
+	}
+	if overlap <= 0 {
+		return nil
+	}
+	until := time.Now().Add(time.Duration(overlap) * time.Second)
+	return &until
+}
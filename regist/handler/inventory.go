@@ -0,0 +1,516 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"sort"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/inventory"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 导入导出的限制
+const (
+	MaxImportFileSize = 16 * 1024 * 1024 // 导入文件大小上限（16MB）
+	MaxImportRows     = 5000             // 导入记录数上限
+	ExportBatchSize   = 500              // 导出时每批查询的记录数
+)
+
+// ImportUsers 从CSV或JSON清单批量注册用户
+// 所有记录先完成校验，任一记录校验失败时返回422及行级错误且不导入任何记录；
+// dry_run=true时只校验不导入，校验通过后在一个事务中创建所有用户
+func ImportUsers(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	file, format, dryRun, ok := openImportFile(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	records, rows, rowErrors, err := inventory.ReadUsers(file, format, MaxImportRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	total := len(records) + countRows(rowErrors)
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	userRepo := repoFactory.GetUserRepository()
+	deviceRepo := repoFactory.GetDeviceRepository()
+
+	userIDRows := make(map[int]int)
+	nameRows := make(map[string]int)
+	gatewayErrors := make(map[int]error)
+	for i := range records {
+		record, row := &records[i], rows[i]
+		errs := record.Validate(row)
+
+		// 清单内重复
+		if prev, ok := userIDRows[record.UserID]; ok {
+			errs = append(errs, inventory.RowError{Row: row, Field: "user_id", Error: fmt.Sprintf("与第%d行的用户ID重复", prev)})
+		} else {
+			userIDRows[record.UserID] = row
+		}
+		if prev, ok := nameRows[record.UserName]; ok {
+			errs = append(errs, inventory.RowError{Row: row, Field: "user_name", Error: fmt.Sprintf("与第%d行的用户名重复", prev)})
+		} else {
+			nameRows[record.UserName] = row
+		}
+
+		// 与已有用户冲突
+		if conflict, err := userIDConflict(userRepo, record.UserID); err != nil {
+			importFailed(c, cfg, err)
+			return
+		} else if conflict != "" {
+			errs = append(errs, inventory.RowError{Row: row, Field: "user_id", Error: conflict})
+		}
+		_, err := userRepo.FindByUsername(record.UserName)
+		if exists, err := recordExists(err); err != nil {
+			importFailed(c, cfg, err)
+			return
+		} else if exists {
+			errs = append(errs, inventory.RowError{Row: row, Field: "user_name", Error: "用户名已存在"})
+		}
+
+		// 所属网关设备
+		gatewayErr, checked := gatewayErrors[record.GatewayDeviceID]
+		if !checked {
+			gateway, err := deviceRepo.FindByDeviceID(record.GatewayDeviceID)
+			if _, err := recordExists(err); err != nil {
+				importFailed(c, cfg, err)
+				return
+			}
+			gatewayErr = service.ValidateUserGateway(gateway)
+			gatewayErrors[record.GatewayDeviceID] = gatewayErr
+		}
+		if gatewayErr != nil {
+			errs = append(errs, inventory.RowError{Row: row, Field: "gateway_device_id", Error: gatewayErr.Error()})
+		}
+
+		// 调用者只能授予自己拥有的权限
+		if mask, err := resolvePermissionMask(c, record.PermissionMask); err != nil {
+			errs = append(errs, inventory.RowError{Row: row, Field: "permission_mask", Error: err.Error()})
+		} else {
+			record.PermissionMask = mask
+		}
+
+		rowErrors = append(rowErrors, errs...)
+	}
+
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "导入数据校验失败，未导入任何用户",
+			"data":  importResponse(dryRun, total, 0, rowErrors),
+		})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "校验通过，未导入",
+			"data":    importResponse(dryRun, total, 0, nil),
+		})
+		return
+	}
+
+	users := make([]*models.User, 0, len(records))
+	for _, record := range records {
+		// 计算口令哈希，数据库中不保存明文口令
+		passwordHash, err := crypto.HashPassword(record.PassWD)
+		if err != nil {
+			log.Printf("计算用户口令哈希失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法导入用户"})
+			return
+		}
+		users = append(users, &models.User{
+			Username:        record.UserName,
+			Password:        passwordHash,
+			UserID:          record.UserID,
+			UserType:        record.UserType,
+			GatewayDeviceID: record.GatewayDeviceID,
+			Email:           record.Email,
+			PermissionMask:  record.PermissionMask,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := repoFactory.WithTx(tx).GetUserRepository()
+		for _, user := range users {
+			if err := txUserRepo.Create(user); err != nil {
+				return fmt.Errorf("创建用户%d失败: %w", user.UserID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("导入用户失败，已回滚: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入用户失败，未导入任何用户"})
+		return
+	}
+
+	log.Printf("已导入 %d 个用户\n", len(users))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "导入成功",
+		"data":    importResponse(dryRun, total, len(users), nil),
+	})
+}
+
+// ImportDevices 从CSV或JSON清单批量注册设备
+// 网关设备的上级可以是已有设备，也可以是同一清单中的安全接入管理设备；
+// 校验规则、dry_run和事务处理与用户导入相同
+func ImportDevices(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	file, format, dryRun, ok := openImportFile(c)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	records, rows, rowErrors, err := inventory.ReadDevices(file, format, MaxImportRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	total := len(records) + countRows(rowErrors)
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	deviceRepo := repoFactory.GetDeviceRepository()
+
+	// 清单中的设备，用于校验清单内的上级关系
+	imported := make(map[int]*inventory.DeviceRecord, len(records))
+	for i := range records {
+		if _, ok := imported[records[i].DeviceID]; !ok {
+			imported[records[i].DeviceID] = &records[i]
+		}
+	}
+
+	deviceIDRows := make(map[int]int)
+	nameRows := make(map[string]int)
+	addressRows := make(map[string]int)
+	for i := range records {
+		record, row := &records[i], rows[i]
+		errs := record.Validate(row)
+
+		// 清单内重复
+		if prev, ok := deviceIDRows[record.DeviceID]; ok {
+			errs = append(errs, inventory.RowError{Row: row, Field: "device_id", Error: fmt.Sprintf("与第%d行的设备ID重复", prev)})
+		} else {
+			deviceIDRows[record.DeviceID] = row
+		}
+		if prev, ok := nameRows[record.DeviceName]; ok {
+			errs = append(errs, inventory.RowError{Row: row, Field: "device_name", Error: fmt.Sprintf("与第%d行的设备名称重复", prev)})
+		} else {
+			nameRows[record.DeviceName] = row
+		}
+
+		// 与已有设备冲突
+		if conflict, err := deviceIDConflict(deviceRepo, record.DeviceID); err != nil {
+			importFailed(c, cfg, err)
+			return
+		} else if conflict != "" {
+			errs = append(errs, inventory.RowError{Row: row, Field: "device_id", Error: conflict})
+		}
+		_, err := deviceRepo.FindByDeviceName(record.DeviceName)
+		if exists, err := recordExists(err); err != nil {
+			importFailed(c, cfg, err)
+			return
+		} else if exists {
+			errs = append(errs, inventory.RowError{Row: row, Field: "device_name", Error: "设备名称已存在"})
+		}
+
+		// 上级设备优先在清单中查找
+		var superior *models.Device
+		if record.SuperiorDeviceID != 0 {
+			if r, ok := imported[record.SuperiorDeviceID]; ok {
+				superior = &models.Device{DeviceID: r.DeviceID, DeviceType: r.DeviceType}
+			} else {
+				device, err := deviceRepo.FindByDeviceID(record.SuperiorDeviceID)
+				if _, err := recordExists(err); err != nil {
+					importFailed(c, cfg, err)
+					return
+				}
+				superior = device
+			}
+		}
+		if err := service.ValidateDeviceHierarchy(record.DeviceType, record.SuperiorDeviceID, superior); err != nil {
+			errs = append(errs, inventory.RowError{Row: row, Field: "superior_device_id", Error: err.Error()})
+		}
+
+		// 网关地址在所属安全接入管理设备下唯一
+		if models.IsGatewayType(record.DeviceType) {
+			conflict, err := deviceRepo.FindAddressConflict(record.SuperiorDeviceID, record.LongAddress, record.ShortAddress, record.DeviceID)
+			if exists, err := recordExists(err); err != nil {
+				importFailed(c, cfg, err)
+				return
+			} else if exists {
+				errs = append(errs, inventory.RowError{Row: row, Field: "long_address",
+					Error: fmt.Sprintf("长地址或短地址已被设备%d使用", conflict.DeviceID)})
+			}
+			for _, address := range [][2]string{{"long_address", record.LongAddress}, {"short_address", record.ShortAddress}} {
+				field := address[0]
+				if address[1] == "" {
+					continue
+				}
+				key := fmt.Sprintf("%d/%s/%s", record.SuperiorDeviceID, field, address[1])
+				if prev, ok := addressRows[key]; ok {
+					errs = append(errs, inventory.RowError{Row: row, Field: field, Error: fmt.Sprintf("与第%d行的地址重复", prev)})
+				} else {
+					addressRows[key] = row
+				}
+			}
+		}
+
+		rowErrors = append(rowErrors, errs...)
+	}
+
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "导入数据校验失败，未导入任何设备",
+			"data":  importResponse(dryRun, total, 0, rowErrors),
+		})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "校验通过，未导入",
+			"data":    importResponse(dryRun, total, 0, nil),
+		})
+		return
+	}
+
+	devices := make([]*models.Device, 0, len(records))
+	for _, record := range records {
+		// 计算口令哈希，数据库中不保存明文口令
+		passwordHash, err := crypto.HashPassword(record.PassWD)
+		if err != nil {
+			log.Printf("计算设备口令哈希失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法导入设备"})
+			return
+		}
+		devices = append(devices, &models.Device{
+			DeviceName:       record.DeviceName,
+			DeviceType:       record.DeviceType,
+			Password:         passwordHash,
+			DeviceID:         record.DeviceID,
+			SuperiorDeviceID: record.SuperiorDeviceID,
+			DeviceStatus:     models.DeviceStatusOffline,
+			RegisterIP:       c.ClientIP(),
+			Email:            record.Email,
+			LongAddress:      record.LongAddress,
+			ShortAddress:     record.ShortAddress,
+			SESKey:           record.SESKey,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txDeviceRepo := repoFactory.WithTx(tx).GetDeviceRepository()
+		for _, device := range devices {
+			if err := txDeviceRepo.Create(device); err != nil {
+				return fmt.Errorf("创建设备%d失败: %w", device.DeviceID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("导入设备失败，已回滚: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入设备失败，未导入任何设备"})
+		return
+	}
+
+	log.Printf("已导入 %d 个设备\n", len(devices))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "导入成功",
+		"data":    importResponse(dryRun, total, len(devices), nil),
+	})
+}
+
+// ExportUsers 按CSV或JSON格式流式导出所有用户，不包含口令
+func ExportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", inventory.FormatCSV)
+	if err := inventory.CheckFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	userRepo := repositories.NewRepositoryFactory(db).GetUserRepository()
+
+	startExport(c, "users", format)
+	writer, err := inventory.NewUserWriter(c.Writer, format)
+	if err == nil {
+		err = userRepo.FindInBatches(ExportBatchSize, func(users []models.User) error {
+			for i := range users {
+				record := inventory.UserRecordFromModel(&users[i])
+				if err := writer.Write(&record); err != nil {
+					return err
+				}
+			}
+			c.Writer.Flush()
+			return nil
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+	}
+	// 响应已开始写出，失败时只能记录日志
+	if err != nil {
+		log.Printf("导出用户失败: %v\n", err)
+	}
+}
+
+// ExportDevices 按CSV或JSON格式流式导出所有设备，不包含口令和SES密钥
+func ExportDevices(c *gin.Context) {
+	format := c.DefaultQuery("format", inventory.FormatCSV)
+	if err := inventory.CheckFormat(format); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	deviceRepo := repositories.NewRepositoryFactory(db).GetDeviceRepository()
+
+	startExport(c, "devices", format)
+	writer, err := inventory.NewDeviceWriter(c.Writer, format)
+	if err == nil {
+		err = deviceRepo.FindInBatches(ExportBatchSize, func(devices []models.Device) error {
+			for i := range devices {
+				record := inventory.DeviceRecordFromModel(&devices[i])
+				if err := writer.Write(&record); err != nil {
+					return err
+				}
+			}
+			c.Writer.Flush()
+			return nil
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+	}
+	// 响应已开始写出，失败时只能记录日志
+	if err != nil {
+		log.Printf("导出设备失败: %v\n", err)
+	}
+}
+
+// openImportFile 打开上传的清单文件并解析导入参数，失败时直接返回错误响应
+// 格式由format表单字段指定，未指定时根据文件扩展名判断
+func openImportFile(c *gin.Context) (multipart.File, string, bool, bool) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到上传的文件"})
+		return nil, "", false, false
+	}
+	if fileHeader.Size > MaxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件大小超过限制"})
+		return nil, "", false, false
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format, err = inventory.FormatFromFileName(fileHeader.Filename)
+	} else {
+		err = inventory.CheckFormat(format)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法读取上传的文件"})
+		return nil, "", false, false
+	}
+	return file, format, c.PostForm("dry_run") == "true", true
+}
+
+// startExport 设置导出响应头并开始写出响应
+func startExport(c *gin.Context, name, format string) {
+	c.Header("Content-Type", inventory.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, format))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// importResponse 生成导入结果，行级错误按行号排序
+func importResponse(dryRun bool, total, imported int, rowErrors []inventory.RowError) gin.H {
+	if rowErrors == nil {
+		rowErrors = []inventory.RowError{}
+	}
+	sort.SliceStable(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Row < rowErrors[j].Row
+	})
+	return gin.H{
+		"dryRun":    dryRun,
+		"total":     total,
+		"failed":    countRows(rowErrors),
+		"imported":  imported,
+		"rowErrors": rowErrors,
+	}
+}
+
+// countRows 统计存在错误的行数
+func countRows(rowErrors []inventory.RowError) int {
+	rows := make(map[int]bool, len(rowErrors))
+	for _, e := range rowErrors {
+		rows[e.Row] = true
+	}
+	return len(rows)
+}
+
+// recordExists 根据查询错误判断记录是否存在，查询失败时返回错误
+func recordExists(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// importFailed 校验过程中查询数据库失败
+func importFailed(c *gin.Context, cfg *config.Config, err error) {
+	if cfg.DebugLevel == "true" {
+		log.Printf("校验导入数据失败: %v\n", err)
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "校验导入数据失败"})
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gin-server/auth/middleware"
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UserLifecycleRequest 用户生命周期操作请求
+type UserLifecycleRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"` // 操作原因，记录到状态变更历史
+}
+
+// FreezeUser 冻结用户，冻结的用户不能登录，只能由管理员解冻
+func FreezeUser(c *gin.Context) {
+	transitionUser(c, service.UserActionFreeze)
+}
+
+// UnfreezeUser 解冻用户，状态恢复为离线
+func UnfreezeUser(c *gin.Context) {
+	transitionUser(c, service.UserActionUnfreeze)
+}
+
+// CancelUser 注销用户，同时吊销用户当前绑定的证书
+func CancelUser(c *gin.Context) {
+	transitionUser(c, service.UserActionCancel)
+}
+
+// ReactivateUser 重新激活已注销的用户，状态恢复为离线，注销时吊销的证书需要重新签发或绑定
+func ReactivateUser(c *gin.Context) {
+	transitionUser(c, service.UserActionReactivate)
+}
+
+// GetUserStatusHistory 查询用户的状态变更历史，按变更时间倒序
+func GetUserStatusHistory(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	if _, err := repoFactory.GetUserRepository().FindByUserID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户是否存在失败"})
+		}
+		return
+	}
+
+	histories, err := repoFactory.GetUserStatusHistoryRepository().FindByUserID(userID)
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询用户状态变更历史失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询状态变更历史失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查询成功",
+		"data":    histories,
+	})
+}
+
+// transitionUser 执行用户生命周期操作
+// 状态变更、状态变更历史、安全事件以及注销时的证书吊销在同一事务中完成
+func transitionUser(c *gin.Context, action string) {
+	cfg := config.GetConfig()
+
+	if cfg.DebugLevel == "true" {
+		log.Printf("接收到用户生命周期操作请求: %s\n", action)
+	}
+
+	userIDStr := c.Param("id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	var request UserLifecycleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	user, err := repoFactory.GetUserRepository().FindByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户是否存在失败"})
+		}
+		return
+	}
+
+	to, err := service.UserActionTarget(action, service.CurrentUserStatus(user))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := ""
+	if claims, ok := middleware.GetClaims(c); ok {
+		actor = claims.Name
+	}
+
+	var history *models.UserStatusHistory
+	var revocation *models.CertRevocation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txFactory := repoFactory.WithTx(tx)
+		var err error
+		if history, err = service.TransitionUser(txFactory, user, service.UserTransition{
+			To:     to,
+			Reason: request.Reason,
+			Actor:  actor,
+		}); err != nil {
+			return err
+		}
+		if to == models.UserStatusCancelled {
+			revocation, err = revokeActiveCert(c, txFactory, "user", userIDStr, "注销用户")
+		}
+		return err
+	})
+	if err != nil {
+		log.Printf("用户 %d 执行%s失败: %v\n", userID, action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "变更用户状态失败"})
+		return
+	}
+	publishRevocation(revocation)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户状态已变更为" + service.UserStatusName(to),
+		"data": gin.H{
+			"userID":      userID,
+			"fromStatus":  history.FromStatus,
+			"status":      history.ToStatus,
+			"reason":      history.Reason,
+			"actor":       history.Actor,
+			"changedAt":   history.ChangedAt,
+			"certRevoked": revocation != nil,
+		},
+	})
+}
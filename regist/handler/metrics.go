@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/metrics"
+	"gin-server/database/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDeviceMetrics 查询设备的性能数据
+// from、to为RFC3339格式的时间，默认查询最近1小时；step为数据点的间隔（秒或Go时长格式，如5m），
+// 为空时尽量返回原始采样。查询范围在原始采样保留时长内时由原始采样聚合，否则使用分钟或小时聚合数据
+func GetDeviceMetrics(c *gin.Context) {
+	cfg := config.GetConfig()
+	deviceIDStr := c.Param("id")
+
+	now := time.Now()
+	to := now
+	if value := c.Query("to"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的结束时间格式，请使用RFC3339格式"})
+			return
+		}
+		to = t
+	}
+	from := to.Add(-time.Hour)
+	if value := c.Query("from"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开始时间格式，请使用RFC3339格式"})
+			return
+		}
+		from = t
+	}
+	step := 0
+	if value := c.Query("step"); value != "" {
+		s, err := parseMetricStep(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "step必须是非负整数秒或时长格式，如30s、5m、1h"})
+			return
+		}
+		step = s
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	if !checkEntityExists(c, repoFactory, "device", deviceIDStr) {
+		return
+	}
+	deviceID, _ := strconv.Atoi(deviceIDStr)
+
+	series, err := metrics.QueryDeviceMetrics(repoFactory.GetDeviceMetricRepository(), &cfg.Metrics, deviceID, from, to, step)
+	if err != nil {
+		if errors.Is(err, metrics.ErrInvalidMetricRange) || errors.Is(err, metrics.ErrTooManyMetricPoints) ||
+			errors.Is(err, metrics.ErrTooManyMetricSamples) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询设备 %d 的性能数据失败: %v\n", deviceID, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询性能数据失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查询成功",
+		"data":    series,
+	})
+}
+
+// parseMetricStep 解析步长，支持整数秒和Go时长格式
+func parseMetricStep(value string) (int, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, errors.New("步长不能为负数")
+		}
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("步长不能为负数")
+	}
+	return int(d / time.Second), nil
+}
@@ -0,0 +1,468 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxPatchSize PATCH请求体大小上限
+const MaxPatchSize = 64 * 1024
+
+// UserPatch 用户部分更新请求，只修改请求中出现的字段
+type UserPatch struct {
+	UserName        *string `json:"user_name"`
+	PassWD          *string `json:"pass_wd"`
+	UserID          *int    `json:"user_id"`
+	UserType        *int    `json:"user_type"`
+	GatewayDeviceID *int    `json:"gateway_device_id"`
+	CertID          *string `json:"cert_id"`         // 为null时清空
+	KeyID           *string `json:"key_id"`          // 为null时清空
+	Email           *string `json:"email"`           // 为null时清空
+	PermissionMask  *string `json:"permission_mask"` // 为null时清空
+}
+
+// userNullableFields 用户部分更新中允许为null的字段
+var userNullableFields = map[string]bool{"cert_id": true, "key_id": true, "email": true, "permission_mask": true}
+
+// DevicePatch 设备部分更新请求，只修改请求中出现的字段
+type DevicePatch struct {
+	DeviceName          *string `json:"device_name"`
+	DeviceType          *int    `json:"device_type"`
+	PassWD              *string `json:"pass_wd"`
+	DeviceID            *int    `json:"device_id"`
+	SuperiorDeviceID    *int    `json:"superior_device_id"`
+	CertID              *string `json:"cert_id"`              // 为null时清空
+	KeyID               *string `json:"key_id"`               // 为null时清空
+	Email               *string `json:"email"`                // 为null时清空
+	HardwareFingerprint *string `json:"hardware_fingerprint"` // 为null时清空
+	AnonymousUser       *string `json:"anonymous_user"`       // 为null时清空
+	LongAddress         *string `json:"long_address"`         // 为null时清空
+	ShortAddress        *string `json:"short_address"`        // 为null时清空
+	SESKey              *string `json:"ses_key"`              // 为null时清空
+}
+
+// deviceNullableFields 设备部分更新中允许为null的字段
+var deviceNullableFields = map[string]bool{
+	"cert_id": true, "key_id": true, "email": true, "hardware_fingerprint": true,
+	"anonymous_user": true, "long_address": true, "short_address": true, "ses_key": true,
+}
+
+// PatchUser 按JSON Merge Patch部分更新用户
+// 请求携带If-Match时必须与用户当前的ETag一致，否则返回412；已绑定证书的用户不能修改用户ID
+func PatchUser(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	userRepo := repoFactory.GetUserRepository()
+
+	existingUser, err := userRepo.FindByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if !checkIfMatch(c, entityETag(existingUser.UpdatedAt)) {
+		return
+	}
+
+	var patch UserPatch
+	present, err := readMergePatch(c, &patch, userNullableFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fields := make(map[string]interface{})
+	if patch.UserName != nil && *patch.UserName != existingUser.Username {
+		if n := utf8.RuneCountInString(*patch.UserName); n < 4 || n > 20 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "用户名长度必须为4-20个字符"})
+			return
+		}
+		if _, err := userRepo.FindByUsername(*patch.UserName); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "用户名已存在"})
+			return
+		}
+		fields["user_name"] = *patch.UserName
+	}
+	if patch.PassWD != nil {
+		if utf8.RuneCountInString(*patch.PassWD) < 8 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "口令至少8个字符"})
+			return
+		}
+		passwordHash, err := crypto.HashPassword(*patch.PassWD)
+		if err != nil {
+			log.Printf("计算用户口令哈希失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新用户信息"})
+			return
+		}
+		fields["pass_wd"] = passwordHash
+	}
+	if patch.UserID != nil && *patch.UserID != existingUser.UserID {
+		if *patch.UserID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+			return
+		}
+		if !checkUserIDChange(c, repoFactory, existingUser.UserID, *patch.UserID) {
+			return
+		}
+		fields["user_id"] = *patch.UserID
+	}
+	if patch.UserType != nil && *patch.UserType != existingUser.UserType {
+		if *patch.UserType == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户类型"})
+			return
+		}
+		fields["user_type"] = *patch.UserType
+	}
+	if patch.GatewayDeviceID != nil && *patch.GatewayDeviceID != existingUser.GatewayDeviceID {
+		if !checkUserGateway(c, repoFactory.GetDeviceRepository(), *patch.GatewayDeviceID) {
+			return
+		}
+		fields["gateway_device_id"] = *patch.GatewayDeviceID
+	}
+	if present["permission_mask"] {
+		permissionMask, err := resolvePermissionMask(c, stringValue(patch.PermissionMask))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if permissionMask != existingUser.PermissionMask {
+			fields["permission_mask"] = permissionMask
+		}
+	}
+	setChangedString(fields, present, "cert_id", patch.CertID, existingUser.CertID)
+	setChangedString(fields, present, "key_id", patch.KeyID, existingUser.KeyID)
+	setChangedString(fields, present, "email", patch.Email, existingUser.Email)
+
+	if len(fields) > 0 {
+		updated, err := userRepo.UpdateIfUnmodified(existingUser.ID, existingUser.UpdatedAt, fields)
+		if err != nil {
+			if cfg.DebugLevel == "true" {
+				log.Printf("更新用户失败: %v\n", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新用户信息"})
+			return
+		}
+		if !updated {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "用户已被修改，请重新获取后再更新"})
+			return
+		}
+		if existingUser, err = userRepo.FindByID(existingUser.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+			return
+		}
+	}
+
+	c.Header("ETag", entityETag(existingUser.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户信息更新成功",
+		"data":    convertUserModelToResponse(existingUser),
+	})
+}
+
+// PatchDevice 按JSON Merge Patch部分更新设备
+// 请求携带If-Match时必须与设备当前的ETag一致，否则返回412；修改后的设备仍需满足层级规则，
+// 已绑定证书、存在下级设备或所属用户的设备不能修改设备ID
+func PatchDevice(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	deviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	deviceRepo := repoFactory.GetDeviceRepository()
+	userRepo := repoFactory.GetUserRepository()
+
+	existingDevice, err := deviceRepo.FindByDeviceID(deviceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		return
+	}
+	if !checkIfMatch(c, entityETag(existingDevice.UpdatedAt)) {
+		return
+	}
+
+	var patch DevicePatch
+	present, err := readMergePatch(c, &patch, deviceNullableFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 合并后的设备，用于校验层级规则
+	merged := *existingDevice
+	fields := make(map[string]interface{})
+	if patch.DeviceName != nil && *patch.DeviceName != existingDevice.DeviceName {
+		if n := utf8.RuneCountInString(*patch.DeviceName); n < 4 || n > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "设备名称长度必须为4-50个字符"})
+			return
+		}
+		if _, err := deviceRepo.FindByDeviceName(*patch.DeviceName); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "设备名称已存在"})
+			return
+		}
+		fields["device_name"] = *patch.DeviceName
+	}
+	if patch.PassWD != nil {
+		if utf8.RuneCountInString(*patch.PassWD) < 8 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "口令至少8个字符"})
+			return
+		}
+		passwordHash, err := crypto.HashPassword(*patch.PassWD)
+		if err != nil {
+			log.Printf("计算设备口令哈希失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		fields["pass_wd"] = passwordHash
+	}
+
+	// 下级设备和所属用户，修改设备ID和设备类型时需要
+	subordinates, err := deviceRepo.FindBySuperiorDeviceID(existingDevice.DeviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+		return
+	}
+	users, err := userRepo.FindByGatewayDeviceID(existingDevice.DeviceID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+		return
+	}
+
+	if patch.DeviceID != nil && *patch.DeviceID != existingDevice.DeviceID {
+		if *patch.DeviceID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+			return
+		}
+		bound, err := hasCertHistory(repoFactory, "device", existingDevice.DeviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书信息失败"})
+			return
+		}
+		switch {
+		case bound:
+			c.JSON(http.StatusConflict, gin.H{"error": "已绑定证书的设备不能修改设备ID"})
+			return
+		case len(subordinates) > 0 || len(users) > 0:
+			c.JSON(http.StatusConflict, gin.H{"error": "存在下级设备或所属用户的设备不能修改设备ID"})
+			return
+		}
+		if !checkDeviceIDAvailable(c, deviceRepo, *patch.DeviceID) {
+			return
+		}
+		merged.DeviceID = *patch.DeviceID
+		fields["device_id"] = *patch.DeviceID
+	}
+	if patch.DeviceType != nil && *patch.DeviceType != existingDevice.DeviceType {
+		if err := service.ValidateDeviceTypeChange(existingDevice.DeviceType, *patch.DeviceType, len(subordinates), len(users)); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		merged.DeviceType = *patch.DeviceType
+		fields["device_type"] = *patch.DeviceType
+	}
+	if patch.SuperiorDeviceID != nil && *patch.SuperiorDeviceID != existingDevice.SuperiorDeviceID {
+		cycle, err := checkSuperiorCycle(deviceRepo, existingDevice.DeviceID, *patch.SuperiorDeviceID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		if cycle {
+			c.JSON(http.StatusConflict, gin.H{"error": "修改上级设备后会形成环"})
+			return
+		}
+		merged.SuperiorDeviceID = *patch.SuperiorDeviceID
+		fields["superior_device_id"] = *patch.SuperiorDeviceID
+	}
+	setChangedString(fields, present, "cert_id", patch.CertID, existingDevice.CertID)
+	setChangedString(fields, present, "key_id", patch.KeyID, existingDevice.KeyID)
+	setChangedString(fields, present, "email", patch.Email, existingDevice.Email)
+	setChangedString(fields, present, "hardware_fingerprint", patch.HardwareFingerprint, existingDevice.HardwareFingerprint)
+	setChangedString(fields, present, "anonymous_user", patch.AnonymousUser, existingDevice.AnonymousUser)
+	if setChangedString(fields, present, "long_address", patch.LongAddress, existingDevice.LongAddress) {
+		merged.LongAddress = stringValue(patch.LongAddress)
+	}
+	if setChangedString(fields, present, "short_address", patch.ShortAddress, existingDevice.ShortAddress) {
+		merged.ShortAddress = stringValue(patch.ShortAddress)
+	}
+	if setChangedString(fields, present, "ses_key", patch.SESKey, existingDevice.SESKey) {
+		merged.SESKey = stringValue(patch.SESKey)
+	}
+
+	// 修改了层级相关字段时，按合并后的设备重新校验
+	_, typeChanged := fields["device_type"]
+	_, superiorChanged := fields["superior_device_id"]
+	_, longChanged := fields["long_address"]
+	_, shortChanged := fields["short_address"]
+	_, sesChanged := fields["ses_key"]
+	if typeChanged || superiorChanged || longChanged || shortChanged || sesChanged {
+		if models.IsGatewayType(merged.DeviceType) {
+			if merged.LongAddress == "" || merged.ShortAddress == "" || merged.SESKey == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "网关设备必须提供长地址、短地址和SES密钥"})
+				return
+			}
+		}
+		if !checkDeviceHierarchy(c, deviceRepo, existingDevice.DeviceID, merged.DeviceType, merged.SuperiorDeviceID,
+			merged.LongAddress, merged.ShortAddress) {
+			return
+		}
+	}
+
+	if len(fields) > 0 {
+		updated, err := deviceRepo.UpdateIfUnmodified(existingDevice.ID, existingDevice.UpdatedAt, fields)
+		if err != nil {
+			if cfg.DebugLevel == "true" {
+				log.Printf("更新设备失败: %v\n", err)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新设备信息"})
+			return
+		}
+		if !updated {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "设备已被修改，请重新获取后再更新"})
+			return
+		}
+		if existingDevice, err = deviceRepo.FindByID(existingDevice.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备信息失败"})
+			return
+		}
+	}
+
+	c.Header("ETag", entityETag(existingDevice.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "设备信息更新成功",
+		"data":    convertDeviceModelToResponse(existingDevice),
+	})
+}
+
+// entityETag 根据更新时间生成用户或设备的ETag，精确到毫秒与数据库保存的精度一致
+func entityETag(updatedAt time.Time) string {
+	return `"` + strconv.FormatInt(updatedAt.UnixMilli(), 36) + `"`
+}
+
+// checkIfMatch 检查If-Match请求头，未携带时不检查，不一致时直接返回412及当前ETag
+func checkIfMatch(c *gin.Context, etag string) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || etagMatches(header, etag) {
+		return true
+	}
+	c.Header("ETag", etag)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "资源已被修改，请重新获取后再更新"})
+	return false
+}
+
+// readMergePatch 读取JSON Merge Patch请求体并解码到target
+// 返回请求中出现的字段；未知字段，或nullable之外的字段为null时返回错误
+func readMergePatch(c *gin.Context, target interface{}, nullable map[string]bool) (map[string]bool, error) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, MaxPatchSize+1))
+	if err != nil {
+		return nil, errors.New("无法读取请求体")
+	}
+	if len(body) > MaxPatchSize {
+		return nil, errors.New("请求体过大")
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil || raw == nil {
+		return nil, errors.New("请求体必须是JSON对象")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil {
+		return nil, fmt.Errorf("无效的请求参数: %v", err)
+	}
+
+	fields := make([]string, 0, len(raw))
+	for field := range raw {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	present := make(map[string]bool, len(raw))
+	for _, field := range fields {
+		if string(bytes.TrimSpace(raw[field])) == "null" && !nullable[field] {
+			return nil, fmt.Errorf("%s不能为null", field)
+		}
+		present[field] = true
+	}
+	return present, nil
+}
+
+// setChangedString 请求中出现的字符串字段与原值不同时加入待更新字段，null表示清空；返回是否加入
+func setChangedString(fields map[string]interface{}, present map[string]bool, column string, value *string, current string) bool {
+	if !present[column] || stringValue(value) == current {
+		return false
+	}
+	fields[column] = stringValue(value)
+	return true
+}
+
+// stringValue 返回字符串指针的值，nil为空字符串
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// checkUserIDChange 检查用户ID能否从current改为target，不能修改时写入响应并返回false
+// 证书记录按用户ID关联，已绑定过证书或密钥的用户修改ID后，新用户使用原ID会继承原用户的证书
+func checkUserIDChange(c *gin.Context, repoFactory repositories.RepositoryFactory, current, target int) bool {
+	bound, err := hasCertHistory(repoFactory, "user", current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书信息失败"})
+		return false
+	}
+	if bound {
+		c.JSON(http.StatusConflict, gin.H{"error": "已绑定证书的用户不能修改用户ID"})
+		return false
+	}
+	return checkUserIDAvailable(c, repoFactory.GetUserRepository(), target)
+}
+
+// hasCertHistory 判断实体是否绑定过证书或密钥
+func hasCertHistory(repoFactory repositories.RepositoryFactory, entityType string, entityID int) (bool, error) {
+	history, err := repoFactory.GetCertRepository().FindHistory(entityType, strconv.Itoa(entityID))
+	if err != nil {
+		return false, err
+	}
+	return len(history) > 0, nil
+}
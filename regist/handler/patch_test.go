@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newPatchContext 创建携带指定请求体和If-Match请求头的测试上下文
+func newPatchContext(body, ifMatch string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	if ifMatch != "" {
+		c.Request.Header.Set("If-Match", ifMatch)
+	}
+	return c, w
+}
+
+func TestReadMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		present []string
+		wantErr string
+	}{
+		{"部分字段", `{"user_name":"alice","email":"a@example.com"}`, []string{"user_name", "email"}, ""},
+		{"可清空字段为null", `{"email":null}`, []string{"email"}, ""},
+		{"空对象", `{}`, nil, ""},
+		{"不可清空字段为null", `{"user_name":null}`, nil, "user_name不能为null"},
+		{"未知字段", `{"nickname":"alice"}`, nil, "无效的请求参数"},
+		{"不是对象", `["user_name"]`, nil, "请求体必须是JSON对象"},
+		{"null请求体", `null`, nil, "请求体必须是JSON对象"},
+		{"类型错误", `{"user_id":"1001"}`, nil, "无效的请求参数"},
+		{"请求体过大", `{"email":"` + strings.Repeat("a", MaxPatchSize) + `"}`, nil, "请求体过大"},
+	}
+	for _, tt := range tests {
+		c, _ := newPatchContext(tt.body, "")
+		var patch UserPatch
+		present, err := readMergePatch(c, &patch, userNullableFields)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: readMergePatch() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: readMergePatch() error = %v", tt.name, err)
+			continue
+		}
+		if len(present) != len(tt.present) {
+			t.Errorf("%s: 出现的字段 = %v, want %v", tt.name, present, tt.present)
+		}
+		for _, field := range tt.present {
+			if !present[field] {
+				t.Errorf("%s: 出现的字段缺少 %s", tt.name, field)
+			}
+		}
+	}
+
+	// null解码为nil指针，由present区分未出现和清空
+	c, _ := newPatchContext(`{"email":null,"user_type":2}`, "")
+	var patch UserPatch
+	present, err := readMergePatch(c, &patch, userNullableFields)
+	if err != nil {
+		t.Fatalf("readMergePatch() error = %v", err)
+	}
+	if patch.Email != nil || !present["email"] || patch.UserType == nil || *patch.UserType != 2 {
+		t.Errorf("解码结果 email = %v（出现 %v），user_type = %v", patch.Email, present["email"], patch.UserType)
+	}
+	if patch.CertID != nil || present["cert_id"] {
+		t.Error("未出现的字段不应标记为出现")
+	}
+}
+
+func TestSetChangedString(t *testing.T) {
+	value := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		present bool
+		value   *string
+		current string
+		want    interface{}
+	}{
+		{"未出现", false, nil, "old", nil},
+		{"null清空", true, nil, "old", ""},
+		{"null且原值为空", true, nil, "", nil},
+		{"修改", true, value("new"), "old", "new"},
+		{"与原值相同", true, value("old"), "old", nil},
+	}
+	for _, tt := range tests {
+		fields := make(map[string]interface{})
+		present := map[string]bool{"email": tt.present}
+		changed := setChangedString(fields, present, "email", tt.value, tt.current)
+		got, ok := fields["email"]
+		if changed != ok || (tt.want == nil) == ok || (ok && got != tt.want) {
+			t.Errorf("%s: setChangedString() = %v，字段 = %v（%v）, want %v", tt.name, changed, got, ok, tt.want)
+		}
+	}
+}
+
+func TestEntityETag(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	etag := entityETag(base.Add(1500 * time.Microsecond))
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Errorf("entityETag() = %s, want 带引号的强ETag", etag)
+	}
+	// 数据库只保存到毫秒，毫秒以下的差异不改变ETag
+	if got := entityETag(base.Add(time.Millisecond)); got != etag {
+		t.Errorf("同一毫秒内的ETag = %s, want %s", got, etag)
+	}
+	if got := entityETag(base.Add(2 * time.Millisecond)); got == etag {
+		t.Errorf("相差1毫秒的ETag相同: %s", got)
+	}
+	if got := entityETag(base.In(time.FixedZone("CST", 8*3600)).Add(time.Millisecond)); got != etag {
+		t.Errorf("不同时区的同一时刻ETag = %s, want %s", got, etag)
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	etag := entityETag(time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    bool
+	}{
+		{"未携带", "", true},
+		{"一致", etag, true},
+		{"弱ETag", "W/" + etag, true},
+		{"多个ETag之一", `"other", ` + etag, true},
+		{"通配符", "*", true},
+		{"不一致", `"other"`, false},
+	}
+	for _, tt := range tests {
+		c, w := newPatchContext("", tt.ifMatch)
+		if got := checkIfMatch(c, etag); got != tt.want {
+			t.Errorf("%s: checkIfMatch() = %v, want %v", tt.name, got, tt.want)
+		}
+		if tt.want {
+			if c.Writer.Written() {
+				t.Errorf("%s: 通过检查时不应写入响应", tt.name)
+			}
+			continue
+		}
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("%s: 状态码 = %d, want 412", tt.name, w.Code)
+		}
+		if got := w.Header().Get("ETag"); got != etag {
+			t.Errorf("%s: 响应ETag = %s, want %s", tt.name, got, etag)
+		}
+	}
+}
@@ -2,8 +2,27 @@ package handler
 
 import (
 	"gin-server/database/models"
+
+	"github.com/gin-gonic/gin"
 )
 
+// listResponse 生成列表查询的响应数据，字段与认证记录查询一致，另外返回下一页游标
+// 使用游标分页时page为0，next_cursor为空表示没有下一页
+func listResponse(query *models.ListQuery, total int64, nextCursor string, records interface{}) gin.H {
+	page := query.Page
+	if query.Cursor != "" {
+		page = 0
+	}
+	return gin.H{
+		"total":       total,
+		"total_pages": (total + int64(query.PageSize) - 1) / int64(query.PageSize),
+		"page":        page,
+		"page_size":   query.PageSize,
+		"next_cursor": nextCursor,
+		"records":     records,
+	}
+}
+
 // UserResponse 用户查询响应结构体
 type UserResponse struct {
 	ID                 uint    `json:"id"`
@@ -74,26 +93,32 @@ func convertUserModelToResponse(user *models.User) UserResponse {
 
 // DeviceResponse 设备查询响应结构体
 type DeviceResponse struct {
-	ID                  uint   `json:"id"`
-	DeviceName          string `json:"device_name"`
-	DeviceType          int    `json:"device_type"`
-	DeviceID            int    `json:"device_id"`
-	SuperiorDeviceID    int    `json:"superior_device_id"`
-	DeviceStatus        int    `json:"device_status"`
-	PeakCPUUsage        int    `json:"peak_cpu_usage,omitempty"`
-	PeakMemoryUsage     int    `json:"peak_memory_usage,omitempty"`
-	OnlineDuration      int    `json:"online_duration"`
-	CertID              string `json:"cert_id"`
-	KeyID               string `json:"key_id"`
-	RegisterIP          string `json:"register_ip"`
-	Email               string `json:"email"`
-	HardwareFingerprint string `json:"hardware_fingerprint,omitempty"`
-	AnonymousUser       string `json:"anonymous_user,omitempty"`
-	LongAddress         string `json:"long_address,omitempty"`
-	ShortAddress        string `json:"short_address,omitempty"`
-	SESKey              string `json:"ses_key,omitempty"`
-	CreatedAt           string `json:"created_at"`
-	UpdatedAt           string `json:"updated_at,omitempty"`
+	ID                  uint    `json:"id"`
+	DeviceName          string  `json:"device_name"`
+	DeviceType          int     `json:"device_type"`
+	DeviceID            int     `json:"device_id"`
+	SuperiorDeviceID    int     `json:"superior_device_id"`
+	DeviceStatus        int     `json:"device_status"`
+	PeakCPUUsage        int     `json:"peak_cpu_usage,omitempty"`
+	PeakMemoryUsage     int     `json:"peak_memory_usage,omitempty"`
+	OnlineDuration      int     `json:"online_duration"`
+	CertID              string  `json:"cert_id"`
+	KeyID               string  `json:"key_id"`
+	RegisterIP          string  `json:"register_ip"`
+	Email               string  `json:"email"`
+	HardwareFingerprint string  `json:"hardware_fingerprint,omitempty"`
+	AnonymousUser       string  `json:"anonymous_user,omitempty"`
+	LongAddress         string  `json:"long_address,omitempty"`
+	ShortAddress        string  `json:"short_address,omitempty"`
+	SESKey              string  `json:"ses_key,omitempty"`
+	LoginFailures       int     `json:"login_failures"`
+	LockedUntil         *string `json:"locked_until,omitempty"`
+	LastLoginTime       *string `json:"last_login_time,omitempty"`
+	LastLoginIP         string  `json:"last_login_ip,omitempty"`
+	LastHeartbeatAt     *string `json:"last_heartbeat_at,omitempty"`
+	Uptime              int     `json:"uptime,omitempty"`
+	CreatedAt           string  `json:"created_at"`
+	UpdatedAt           string  `json:"updated_at,omitempty"`
 }
 
 // convertDeviceModelToResponse 将设备模型转换为响应结构体
@@ -117,6 +142,9 @@ func convertDeviceModelToResponse(device *models.Device) DeviceResponse {
 		LongAddress:         device.LongAddress,
 		ShortAddress:        device.ShortAddress,
 		SESKey:              device.SESKey,
+		LoginFailures:       device.LoginFailures,
+		LastLoginIP:         device.LastLoginIP,
+		Uptime:              device.Uptime,
 		CreatedAt:           device.CreatedAt.Format("2006-01-02T15:04:05Z"),
 	}
 
@@ -126,5 +154,20 @@ func convertDeviceModelToResponse(device *models.Device) DeviceResponse {
 		response.UpdatedAt = updatedAt
 	}
 
+	if device.LockedUntil != nil {
+		lockedUntil := device.LockedUntil.Format("2006-01-02T15:04:05Z")
+		response.LockedUntil = &lockedUntil
+	}
+
+	if device.LastLoginTime != nil {
+		lastLogin := device.LastLoginTime.Format("2006-01-02T15:04:05Z")
+		response.LastLoginTime = &lastLogin
+	}
+
+	if device.LastHeartbeatAt != nil {
+		lastHeartbeat := device.LastHeartbeatAt.Format("2006-01-02T15:04:05Z")
+		response.LastHeartbeatAt = &lastHeartbeat
+	}
+
 	return response
 }
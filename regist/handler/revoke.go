@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gin-server/auth/middleware"
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/service"
+	"gin-server/regist/store"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errCertRevoked 证书已吊销
+var errCertRevoked = errors.New("证书已吊销")
+
+// RevokeCertRequest 证书吊销请求
+type RevokeCertRequest struct {
+	ReasonCode *int   `json:"reason_code" binding:"required"` // 吊销原因代码（RFC 5280），如0未指定、1密钥泄露、4已被取代、5停止使用
+	Comment    string `json:"comment" binding:"max=255"`      // 吊销说明
+}
+
+// RevokeUserCert 吊销用户当前绑定的证书
+func RevokeUserCert(c *gin.Context) {
+	cfg := config.GetConfig()
+	userID := c.Param("id")
+
+	// 检查用户ID是否有效
+	userIDInt, err := strconv.Atoi(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	var request RevokeCertRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	// 检查用户是否存在
+	if _, err := repoFactory.GetUserRepository().FindByUserID(userIDInt); err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("检查用户是否存在失败: %v\n", err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户是否存在失败"})
+		}
+		return
+	}
+
+	revocation, ok := revokeEntityCert(c, repoFactory, "user", userID, &request)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证书吊销成功",
+		"data":    revocationResponse(revocation, "userID", userID),
+	})
+}
+
+// RevokeDeviceCert 吊销设备当前绑定的证书
+func RevokeDeviceCert(c *gin.Context) {
+	cfg := config.GetConfig()
+	deviceID := c.Param("id")
+
+	// 将设备ID从字符串转换为整数
+	deviceIDInt, err := strconv.Atoi(deviceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+		return
+	}
+
+	var request RevokeCertRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	// 检查设备是否存在
+	if _, err := repoFactory.GetDeviceRepository().FindByDeviceID(deviceIDInt); err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("检查设备是否存在失败: %v\n", err)
+		}
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查设备是否存在失败"})
+		}
+		return
+	}
+
+	revocation, ok := revokeEntityCert(c, repoFactory, "device", deviceID, &request)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "证书吊销成功",
+		"data":    revocationResponse(revocation, "deviceID", deviceID),
+	})
+}
+
+// GetCRL 下载内置CA签名的证书吊销列表
+// 默认返回DER编码，format=pem时返回PEM编码
+func GetCRL(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	data, err := os.ReadFile(cfg.CRL.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "证书吊销列表尚未生成"})
+			return
+		}
+		log.Printf("读取证书吊销列表失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取证书吊销列表失败"})
+		return
+	}
+
+	if c.Query("format") == "pem" {
+		c.Header("Content-Disposition", "attachment; filename=ca.crl.pem")
+		c.Data(http.StatusOK, "application/x-pem-file", pki.EncodeCRL(data))
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename=ca.crl")
+	c.Data(http.StatusOK, "application/pkix-crl", data)
+}
+
+// revokeEntityCert 吊销实体当前绑定的证书并触发CRL重新生成
+// 失败时直接返回错误响应
+func revokeEntityCert(c *gin.Context, repoFactory repositories.RepositoryFactory, entityType, entityID string, request *RevokeCertRequest) (*models.CertRevocation, bool) {
+	cfg := config.GetConfig()
+
+	if err := pki.ValidateRevocationReason(*request.ReasonCode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	certRecord, err := repoFactory.GetCertRepository().FindByEntity(entityType, entityID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		if cfg.DebugLevel == "true" {
+			log.Printf("获取证书信息失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取证书信息失败"})
+		return nil, false
+	}
+	if certRecord == nil || certRecord.CertPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到绑定的证书"})
+		return nil, false
+	}
+
+	revocation, err := newRevocation(certRecord, *request.ReasonCode, request.Comment)
+	if err != nil {
+		log.Printf("读取待吊销的证书失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取证书文件失败"})
+		return nil, false
+	}
+
+	revocationRepo := repoFactory.GetCertRevocationRepository()
+	if revoked, err := revocationRepo.IsRevoked(revocation.Fingerprint); err != nil {
+		log.Printf("查询证书吊销状态失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询证书吊销状态失败"})
+		return nil, false
+	} else if revoked {
+		c.JSON(http.StatusConflict, gin.H{"error": errCertRevoked.Error()})
+		return nil, false
+	}
+
+	if claims, ok := middleware.GetClaims(c); ok {
+		revocation.RevokedBy = claims.Name
+	}
+	if err := revocationRepo.Create(revocation); err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("保存吊销记录失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存吊销记录失败"})
+		return nil, false
+	}
+
+	log.Printf("%s %s的证书已吊销，序列号: %s，原因: %s\n",
+		entityType, entityID, revocation.SerialNumber, pki.RevocationReasonName(revocation.ReasonCode))
+
+	// 重新生成CRL，吊销检查以数据库为准，不依赖CRL是否生成成功
+	if publisher := service.GetCRLPublisher(); publisher != nil {
+		publisher.Trigger()
+	}
+	return revocation, true
+}
+
+// revocationResponse 构造吊销成功的响应数据
+func revocationResponse(r *models.CertRevocation, idField, entityID string) gin.H {
+	return gin.H{
+		idField:        entityID,
+		"serialNumber": r.SerialNumber,
+		"fingerprint":  r.Fingerprint,
+		"issuer":       r.Issuer,
+		"reasonCode":   r.ReasonCode,
+		"reason":       pki.RevocationReasonName(r.ReasonCode),
+		"revokedAt":    r.RevokedAt,
+	}
+}
+
+// newRevocation 根据证书记录创建吊销记录
+// 绑定时未解析证书字段的历史记录，从证书文件中读取
+func newRevocation(certRecord *models.Cert, reasonCode int, comment string) (*models.CertRevocation, error) {
+	revocation := &models.CertRevocation{
+		CertID:       certRecord.ID,
+		EntityType:   certRecord.EntityType,
+		EntityID:     certRecord.EntityID,
+		SerialNumber: certRecord.SerialNumber,
+		Issuer:       certRecord.Issuer,
+		Fingerprint:  certRecord.Fingerprint,
+		NotAfter:     certRecord.NotAfter,
+		ReasonCode:   reasonCode,
+		Comment:      comment,
+		RevokedAt:    time.Now(),
+	}
+
+	if revocation.Fingerprint == "" {
+		info, err := readCertFileInfo(certRecord)
+		if err != nil {
+			return nil, err
+		}
+		revocation.SerialNumber = info.SerialNumber
+		revocation.Issuer = info.Issuer
+		revocation.Fingerprint = info.Fingerprint
+		revocation.NotAfter = &info.NotAfter
+	}
+	return revocation, nil
+}
+
+// readCertFileInfo 读取证书记录对应的证书并解析其中的实体证书
+func readCertFileInfo(cert *models.Cert) (*pki.CertInfo, error) {
+	certStore, err := store.Default()
+	if err != nil {
+		return nil, err
+	}
+	data, err := certStore.GetCert(cert)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := pki.ParseCertificateChain(data)
+	if err != nil {
+		return nil, err
+	}
+	return pki.NewCertInfo(chain[0]), nil
+}
@@ -60,7 +60,8 @@ func GetUserByID(c *gin.Context) {
 	// 转换为响应结构体
 	userResponse := convertUserModelToResponse(user)
 
-	// 返回用户信息
+	// 返回用户信息，ETag用于部分更新时的If-Match
+	c.Header("ETag", entityETag(user.UpdatedAt))
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "用户查询成功",
@@ -115,7 +116,8 @@ func GetDeviceByID(c *gin.Context) {
 	// 转换为响应结构体
 	deviceResponse := convertDeviceModelToResponse(device)
 
-	// 返回设备信息
+	// 返回设备信息，ETag用于部分更新时的If-Match
+	c.Header("ETag", entityETag(device.UpdatedAt))
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "设备查询成功",
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"gin-server/config"
+	"gin-server/database"
+	"gin-server/database/repositories"
+	"gin-server/regist/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTopology 查询设备拓扑
+// 不指定root时返回完整拓扑，指定root时返回以该设备为根的子树；depth限制展开的下级层数，
+// 每个节点包含直接所属的用户数以及子树的设备、在线设备、用户和在线用户统计
+func GetTopology(c *gin.Context) {
+	cfg := config.GetConfig()
+
+	rootID := 0
+	if value := c.Query("root"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的设备ID格式"})
+			return
+		}
+		rootID = id
+	}
+	depth := -1
+	if value := c.Query("depth"); value != "" {
+		d, err := strconv.Atoi(value)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "depth必须是非负整数"})
+			return
+		}
+		depth = d
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+
+	devices, err := repoFactory.GetDeviceRepository().FindAll()
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询设备失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取设备列表失败"})
+		return
+	}
+	userCounts, err := repoFactory.GetUserRepository().CountByGateway()
+	if err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("统计用户数失败: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计用户数失败"})
+		return
+	}
+
+	topology := service.BuildTopology(devices, userCounts)
+	cycleIDs := topology.CycleDeviceIDs()
+	if len(cycleIDs) > 0 {
+		log.Printf("设备上级关系存在环，涉及设备: %v\n", cycleIDs)
+	}
+
+	if rootID == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "查询成功",
+			"data": gin.H{
+				"roots":            topology.Roots(depth),
+				"cycle_device_ids": cycleIDs,
+			},
+		})
+		return
+	}
+
+	node, err := topology.Subtree(rootID, depth)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTopologyDeviceNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "设备不存在"})
+		case errors.Is(err, service.ErrTopologyCycle):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "cycle_device_ids": cycleIDs})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "查询成功",
+		"data":    node,
+	})
+}
+
+// checkSuperiorCycle 检查将设备的上级设置为superiorID后是否形成环
+func checkSuperiorCycle(deviceRepo repositories.DeviceRepository, deviceID, superiorID int) (bool, error) {
+	return service.CreatesCycle(deviceID, superiorID, func(id int) (int, bool, error) {
+		device, err := deviceRepo.FindByDeviceID(id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, false, nil
+			}
+			return 0, false, err
+		}
+		return device.SuperiorDeviceID, true, nil
+	})
+}
@@ -1,17 +1,23 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
+	"gin-server/auth/middleware"
+	"gin-server/auth/permission"
 	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
 	"gin-server/database"
 	"gin-server/database/models"
 	"gin-server/database/repositories"
 
 	// 临时保留，后续完全迁移后可删除
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // User 结构体定义用户信息
@@ -24,6 +30,7 @@ type User struct {
 	CertID          string `json:"cert_id"`                                   // 证书ID，允许为 NULL
 	KeyID           string `json:"key_id"`                                    // 密钥ID，允许为 NULL
 	Email           string `json:"email"`                                     // 邮箱，允许为 NULL
+	PermissionMask  string `json:"permission_mask"`                           // 权限位掩码，如 "0000000000000011"，允许为空
 }
 
 // RegisterUser 处理用户注册请求
@@ -49,9 +56,13 @@ func RegisterUser(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	userRepo := repoFactory.GetUserRepository()
 
-	// 检查用户 ID 是否存在
-	if _, err := userRepo.FindByID(uint(user.UserID)); err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "用户 ID 已存在"})
+	// 检查用户 ID 是否存在，包括已删除的用户
+	if !checkUserIDAvailable(c, userRepo, user.UserID) {
+		return
+	}
+
+	// 检查所属网关设备
+	if !checkUserGateway(c, repoFactory.GetDeviceRepository(), user.GatewayDeviceID) {
 		return
 	}
 
@@ -61,10 +72,25 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
+	// 校验权限位掩码，调用者只能授予自己拥有的权限
+	permissionMask, err := resolvePermissionMask(c, user.PermissionMask)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 计算口令哈希，数据库中不保存明文口令
+	passwordHash, err := crypto.HashPassword(user.PassWD)
+	if err != nil {
+		log.Printf("计算用户口令哈希失败: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法创建用户"})
+		return
+	}
+
 	// 创建新用户模型
 	newUser := &models.User{
 		Username:        user.UserName,
-		Password:        user.PassWD,
+		Password:        passwordHash,
 		UserID:          user.UserID,
 		UserType:        user.UserType,
 		GatewayDeviceID: user.GatewayDeviceID,
@@ -73,6 +99,7 @@ func RegisterUser(c *gin.Context) {
 		CertID:          user.CertID,
 		KeyID:           user.KeyID,
 		Email:           user.Email,
+		PermissionMask:  permissionMask,
 	}
 
 	// 创建用户
@@ -88,12 +115,19 @@ func RegisterUser(c *gin.Context) {
 	})
 }
 
-// GetUsers 处理获取所有用户的请求
+// GetUsers 处理获取用户列表的请求
+// 支持按状态、类型、网关设备、用户名前缀和创建时间过滤，按page或cursor分页
 func GetUsers(c *gin.Context) {
 	cfg := config.GetConfig() // 获取全局配置
 
 	if cfg.DebugLevel == "true" {
-		log.Println("接收到获取所有用户的请求")
+		log.Println("接收到获取用户列表的请求")
+	}
+
+	var query models.UserQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的查询参数"})
+		return
 	}
 
 	// 获取数据库连接和仓库
@@ -105,9 +139,15 @@ func GetUsers(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	userRepo := repoFactory.GetUserRepository()
 
-	// 获取所有用户
-	users, err := userRepo.FindAll()
+	users, total, nextCursor, err := userRepo.FindByConditions(&query)
 	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cfg.DebugLevel == "true" {
+			log.Printf("查询用户列表失败: %v\n", err)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法获取用户列表"})
 		return
 	}
@@ -121,7 +161,7 @@ func GetUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "获取用户列表成功",
-		"data":    userResponses,
+		"data":    listResponse(&query.ListQuery, total, nextCursor, userResponses),
 	})
 }
 
@@ -162,10 +202,27 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// 修改所属网关设备时检查新的网关设备
+	if requestUser.GatewayDeviceID != existingUser.GatewayDeviceID &&
+		!checkUserGateway(c, repoFactory.GetDeviceRepository(), requestUser.GatewayDeviceID) {
+		return
+	}
+
+	// 修改用户 ID 时检查用户未绑定过证书，且新 ID 未被使用，包括已删除的用户
+	if requestUser.UserID != existingUser.UserID && !checkUserIDChange(c, repoFactory, existingUser.UserID, requestUser.UserID) {
+		return
+	}
+
 	// 更新用户字段
 	existingUser.Username = requestUser.UserName
 	if requestUser.PassWD != "" {
-		existingUser.Password = requestUser.PassWD
+		passwordHash, err := crypto.HashPassword(requestUser.PassWD)
+		if err != nil {
+			log.Printf("计算用户口令哈希失败: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "无法更新用户信息"})
+			return
+		}
+		existingUser.Password = passwordHash
 	}
 	existingUser.UserID = requestUser.UserID
 	existingUser.UserType = requestUser.UserType
@@ -173,6 +230,14 @@ func UpdateUser(c *gin.Context) {
 	existingUser.CertID = requestUser.CertID
 	existingUser.KeyID = requestUser.KeyID
 	existingUser.Email = requestUser.Email
+	if requestUser.PermissionMask != "" {
+		permissionMask, err := resolvePermissionMask(c, requestUser.PermissionMask)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		existingUser.PermissionMask = permissionMask
+	}
 	// 不更新其他字段，保持原值
 
 	// 保存更新
@@ -192,6 +257,7 @@ func UpdateUser(c *gin.Context) {
 }
 
 // DeleteUser 处理删除用户的请求
+// 用户被软删除，已绑定的证书按certs参数归档或吊销，删除后可通过恢复接口恢复
 func DeleteUser(c *gin.Context) {
 	cfg := config.GetConfig() // 获取全局配置
 
@@ -205,6 +271,11 @@ func DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"}) // 返回无效用户 ID 错误信息
 		return
 	}
+	certPolicy, err := parseCertPolicy(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 获取数据库连接和仓库
 	db, err := database.GetDB()
@@ -215,14 +286,144 @@ func DeleteUser(c *gin.Context) {
 	repoFactory := repositories.NewRepositoryFactory(db)
 	userRepo := repoFactory.GetUserRepository()
 
-	// 删除用户
-	if err := userRepo.Delete(uint(userID)); err != nil {
+	// 查找用户
+	user, err := userRepo.FindByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "检查用户是否存在失败"})
+		}
+		return
+	}
+
+	// 处理证书并删除用户
+	var revocation *models.CertRevocation
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txFactory := repoFactory.WithTx(tx)
+		var err error
+		if revocation, err = retireEntityCerts(c, txFactory, "user", userIDStr, certPolicy); err != nil {
+			return err
+		}
+		return txFactory.GetUserRepository().Delete(user.ID)
+	})
+	if err != nil {
+		log.Printf("删除用户 %d 失败: %v\n", userID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法删除用户"})
 		return
 	}
+	publishRevocation(revocation)
 
+	log.Printf("用户 %d 已删除，证书处理方式: %s\n", userID, certPolicy)
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "用户删除成功",
+		"data": gin.H{
+			"userID":      userID,
+			"certs":       certPolicy,
+			"certRevoked": revocation != nil,
+		},
+	})
+}
+
+// RestoreUser 恢复已删除的用户，归档的证书一并恢复
+// 所属网关设备已删除时需要先恢复网关设备
+func RestoreUser(c *gin.Context) {
+	cfg := config.GetConfig() // 获取全局配置
+
+	if cfg.DebugLevel == "true" {
+		log.Println("接收到恢复用户的请求")
+	}
+
+	userIDStr := c.Param("id")
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户 ID"})
+		return
+	}
+
+	// 获取数据库连接和仓库
+	db, err := database.GetDB()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "数据库连接失败"})
+		return
+	}
+	repoFactory := repositories.NewRepositoryFactory(db)
+	userRepo := repoFactory.GetUserRepository()
+
+	if _, err := userRepo.FindByUserID(userID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "用户未被删除"})
+		return
+	}
+	user, err := userRepo.FindDeletedByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "未找到已删除的用户"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询已删除的用户失败"})
+		}
+		return
+	}
+
+	// 检查恢复后是否冲突
+	if _, err := userRepo.FindByUsername(user.Username); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "用户名已被其他用户使用"})
+		return
+	}
+	if _, err := repoFactory.GetDeviceRepository().FindByDeviceID(user.GatewayDeviceID); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "所属网关设备不存在，请先恢复网关设备"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txFactory := repoFactory.WithTx(tx)
+		if err := txFactory.GetUserRepository().Restore(user.ID); err != nil {
+			return err
+		}
+		return txFactory.GetCertRepository().RestoreByEntity("user", userIDStr)
+	})
+	if err != nil {
+		log.Printf("恢复用户 %d 失败: %v\n", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "无法恢复用户"})
+		return
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+
+	log.Printf("用户 %d 已恢复\n", userID)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "用户恢复成功",
+		"data":    convertUserModelToResponse(user),
 	})
 }
+
+// resolvePermissionMask 校验并规范化请求中的权限位掩码
+// 开启令牌认证时，调用者只能授予自己拥有的权限，防止越权提升
+func resolvePermissionMask(c *gin.Context, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	mask, err := permission.ParseMask(value)
+	if err != nil {
+		return "", err
+	}
+
+	if claims, ok := middleware.GetClaims(c); ok {
+		callerMask, err := permission.ParseMask(claims.Mask)
+		if err != nil {
+			callerMask = 0
+		}
+		var granted []permission.Permission
+		for _, p := range permission.All() {
+			if mask&permission.NewMask(p) != 0 {
+				granted = append(granted, p)
+			}
+		}
+		if missing := callerMask.Missing(granted...); len(missing) > 0 {
+			return "", fmt.Errorf("不能授予自己没有的权限: %s", missing[0].Description())
+		}
+	}
+
+	return mask.String(), nil
+}
@@ -0,0 +1,117 @@
+package inventory
+
+import (
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"gin-server/database/models"
+)
+
+// DeviceRecord 设备清单记录
+// 导入时pass_wd必填，ses_key只在导入时读取，device_status、register_ip、hardware_fingerprint和created_at只在导出时写出
+type DeviceRecord struct {
+	DeviceName          string `json:"device_name"`
+	DeviceType          int    `json:"device_type"`
+	PassWD              string `json:"pass_wd,omitempty"`
+	DeviceID            int    `json:"device_id"`
+	SuperiorDeviceID    int    `json:"superior_device_id"`
+	DeviceStatus        int    `json:"device_status,omitempty"`
+	RegisterIP          string `json:"register_ip,omitempty"`
+	Email               string `json:"email"`
+	LongAddress         string `json:"long_address"`
+	ShortAddress        string `json:"short_address"`
+	SESKey              string `json:"ses_key,omitempty"`
+	HardwareFingerprint string `json:"hardware_fingerprint,omitempty"`
+	CreatedAt           string `json:"created_at,omitempty"`
+}
+
+// deviceColumns 设备清单字段
+var deviceColumns = []column[DeviceRecord]{
+	{name: "device_name", importable: true, exportable: true, required: true,
+		get: func(r *DeviceRecord) string { return r.DeviceName },
+		set: func(r *DeviceRecord, v string) error { r.DeviceName = v; return nil }},
+	{name: "device_type", importable: true, exportable: true, required: true,
+		get: func(r *DeviceRecord) string { return strconv.Itoa(r.DeviceType) },
+		set: func(r *DeviceRecord, v string) (err error) { r.DeviceType, err = parseInt(v); return }},
+	{name: "pass_wd", importable: true, required: true,
+		set: func(r *DeviceRecord, v string) error { r.PassWD = v; return nil }},
+	{name: "device_id", importable: true, exportable: true, required: true,
+		get: func(r *DeviceRecord) string { return strconv.Itoa(r.DeviceID) },
+		set: func(r *DeviceRecord, v string) (err error) { r.DeviceID, err = parseInt(v); return }},
+	{name: "superior_device_id", importable: true, exportable: true, required: true,
+		get: func(r *DeviceRecord) string { return strconv.Itoa(r.SuperiorDeviceID) },
+		set: func(r *DeviceRecord, v string) (err error) { r.SuperiorDeviceID, err = parseInt(v); return }},
+	{name: "device_status", exportable: true,
+		get: func(r *DeviceRecord) string { return strconv.Itoa(r.DeviceStatus) }},
+	{name: "register_ip", exportable: true,
+		get: func(r *DeviceRecord) string { return r.RegisterIP }},
+	{name: "email", importable: true, exportable: true,
+		get: func(r *DeviceRecord) string { return r.Email },
+		set: func(r *DeviceRecord, v string) error { r.Email = v; return nil }},
+	{name: "long_address", importable: true, exportable: true,
+		get: func(r *DeviceRecord) string { return r.LongAddress },
+		set: func(r *DeviceRecord, v string) error { r.LongAddress = v; return nil }},
+	{name: "short_address", importable: true, exportable: true,
+		get: func(r *DeviceRecord) string { return r.ShortAddress },
+		set: func(r *DeviceRecord, v string) error { r.ShortAddress = v; return nil }},
+	{name: "ses_key", importable: true,
+		set: func(r *DeviceRecord, v string) error { r.SESKey = v; return nil }},
+	{name: "hardware_fingerprint", exportable: true,
+		get: func(r *DeviceRecord) string { return r.HardwareFingerprint }},
+	{name: "created_at", exportable: true,
+		get: func(r *DeviceRecord) string { return r.CreatedAt }},
+}
+
+// ReadDevices 解析设备清单，返回记录、记录所在的行号以及行级错误
+func ReadDevices(r io.Reader, format string, maxRows int) ([]DeviceRecord, []int, []RowError, error) {
+	return read(r, format, deviceColumns, maxRows)
+}
+
+// NewDeviceWriter 创建设备清单导出写入器
+func NewDeviceWriter(w io.Writer, format string) (Writer[DeviceRecord], error) {
+	return newWriter(w, format, deviceColumns)
+}
+
+// Validate 校验字段格式，规则与设备注册接口一致，层级关系由调用方校验
+func (r *DeviceRecord) Validate(row int) []RowError {
+	var errs []RowError
+	if n := utf8.RuneCountInString(r.DeviceName); n < 4 || n > 50 {
+		errs = append(errs, RowError{Row: row, Field: "device_name", Error: "设备名称长度必须为4-50个字符"})
+	}
+	if utf8.RuneCountInString(r.PassWD) < 8 {
+		errs = append(errs, RowError{Row: row, Field: "pass_wd", Error: "口令至少8个字符"})
+	}
+	if r.DeviceID == 0 {
+		errs = append(errs, RowError{Row: row, Field: "device_id", Error: "缺少设备ID"})
+	}
+	if models.IsGatewayType(r.DeviceType) {
+		if r.LongAddress == "" {
+			errs = append(errs, RowError{Row: row, Field: "long_address", Error: "网关设备必须提供长地址"})
+		}
+		if r.ShortAddress == "" {
+			errs = append(errs, RowError{Row: row, Field: "short_address", Error: "网关设备必须提供短地址"})
+		}
+		if r.SESKey == "" {
+			errs = append(errs, RowError{Row: row, Field: "ses_key", Error: "网关设备必须提供SES密钥"})
+		}
+	}
+	return errs
+}
+
+// DeviceRecordFromModel 将设备模型转换为导出记录，不导出口令和SES密钥
+func DeviceRecordFromModel(device *models.Device) DeviceRecord {
+	return DeviceRecord{
+		DeviceName:          device.DeviceName,
+		DeviceType:          device.DeviceType,
+		DeviceID:            device.DeviceID,
+		SuperiorDeviceID:    device.SuperiorDeviceID,
+		DeviceStatus:        device.DeviceStatus,
+		RegisterIP:          device.RegisterIP,
+		Email:               device.Email,
+		LongAddress:         device.LongAddress,
+		ShortAddress:        device.ShortAddress,
+		HardwareFingerprint: device.HardwareFingerprint,
+		CreatedAt:           device.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
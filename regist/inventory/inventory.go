@@ -0,0 +1,268 @@
+// Package inventory 实现用户和设备清单的CSV/JSON导入解析与流式导出
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 清单格式
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// 清单解析错误
+var (
+	ErrUnsupportedFormat = errors.New("不支持的格式，只能是csv或json")
+	ErrTooManyRows       = errors.New("记录数超过限制")
+)
+
+// RowError 行级错误，Row从1开始，CSV不计表头
+type RowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// column 清单字段
+type column[T any] struct {
+	name       string
+	importable bool // 导入时读取
+	exportable bool // 导出时写出
+	required   bool // 导入的CSV表头必须包含
+	get        func(*T) string
+	set        func(*T, string) error
+}
+
+// FormatFromFileName 根据文件扩展名确定格式
+func FormatFromFileName(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// CheckFormat 检查格式是否受支持
+func CheckFormat(format string) error {
+	if format != FormatCSV && format != FormatJSON {
+		return ErrUnsupportedFormat
+	}
+	return nil
+}
+
+// ContentType 返回导出格式对应的Content-Type
+func ContentType(format string) string {
+	if format == FormatJSON {
+		return "application/json; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// read 解析清单，返回成功解析的记录及其行号，以及字段类型错误等行级错误
+// 文件格式错误或记录数超过maxRows时返回error
+func read[T any](r io.Reader, format string, columns []column[T], maxRows int) ([]T, []int, []RowError, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, columns, maxRows)
+	case FormatJSON:
+		return readJSON(r, columns, maxRows)
+	}
+	return nil, nil, nil, ErrUnsupportedFormat
+}
+
+// readCSV 按表头解析CSV，未知的列被忽略
+func readCSV[T any](r io.Reader, columns []column[T], maxRows int) ([]T, []int, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil, nil, errors.New("CSV文件为空")
+		}
+		return nil, nil, nil, fmt.Errorf("解析CSV表头失败: %w", err)
+	}
+	// 去掉Excel导出时可能带有的BOM
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, col := range columns {
+		if _, ok := index[col.name]; col.required && !ok {
+			return nil, nil, nil, fmt.Errorf("CSV表头缺少%s列", col.name)
+		}
+	}
+
+	var records []T
+	var rows []int
+	var rowErrors []RowError
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("解析CSV第%d行失败: %w", row, err)
+		}
+		if row > maxRows {
+			return nil, nil, nil, ErrTooManyRows
+		}
+
+		var record T
+		valid := true
+		for _, col := range columns {
+			i, ok := index[col.name]
+			if !col.importable || !ok || i >= len(fields) {
+				continue
+			}
+			if err := col.set(&record, strings.TrimSpace(fields[i])); err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Field: col.name, Error: err.Error()})
+				valid = false
+			}
+		}
+		if valid {
+			records = append(records, record)
+			rows = append(rows, row)
+		}
+	}
+	return records, rows, rowErrors, nil
+}
+
+// readJSON 解析JSON数组，每个元素单独解码，字段类型错误只影响所在的行
+func readJSON[T any](r io.Reader, columns []column[T], maxRows int) ([]T, []int, []RowError, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, nil, nil, fmt.Errorf("解析JSON失败，内容必须是对象数组: %w", err)
+	}
+	if len(items) > maxRows {
+		return nil, nil, nil, ErrTooManyRows
+	}
+
+	var records []T
+	var rows []int
+	var rowErrors []RowError
+	for i, item := range items {
+		row := i + 1
+		var record T
+		if err := json.Unmarshal(item, &record); err != nil {
+			rowError := RowError{Row: row, Error: "无效的记录"}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				rowError.Field = typeErr.Field
+				rowError.Error = "字段类型错误"
+			}
+			rowErrors = append(rowErrors, rowError)
+			continue
+		}
+		records = append(records, record)
+		rows = append(rows, row)
+	}
+	return records, rows, rowErrors, nil
+}
+
+// Writer 流式写出导出记录
+type Writer[T any] interface {
+	// Write 写出一条记录
+	Write(record *T) error
+	// Close 写出结尾并刷新缓冲
+	Close() error
+}
+
+// newWriter 创建指定格式的导出写入器
+func newWriter[T any](w io.Writer, format string, columns []column[T]) (Writer[T], error) {
+	switch format {
+	case FormatCSV:
+		var exported []column[T]
+		var header []string
+		for _, col := range columns {
+			if col.exportable {
+				exported = append(exported, col)
+				header = append(header, col.name)
+			}
+		}
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvWriter[T]{writer: writer, columns: exported}, nil
+	case FormatJSON:
+		if _, err := io.WriteString(w, "["); err != nil {
+			return nil, err
+		}
+		return &jsonWriter[T]{w: w}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// csvWriter CSV导出写入器
+type csvWriter[T any] struct {
+	writer  *csv.Writer
+	columns []column[T]
+}
+
+func (w *csvWriter[T]) Write(record *T) error {
+	fields := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		fields[i] = col.get(record)
+	}
+	return w.writer.Write(fields)
+}
+
+func (w *csvWriter[T]) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// jsonWriter JSON数组导出写入器，逐条写出记录
+type jsonWriter[T any] struct {
+	w     io.Writer
+	count int
+}
+
+func (w *jsonWriter[T]) Write(record *T) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if w.count > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteByte('\n')
+	buf.Write(data)
+	w.count++
+	_, err = w.w.Write(buf.Bytes())
+	return err
+}
+
+func (w *jsonWriter[T]) Close() error {
+	_, err := io.WriteString(w.w, "\n]\n")
+	return err
+}
+
+// parseInt 解析整数字段，空值为0
+func parseInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("必须是整数")
+	}
+	return n, nil
+}
@@ -0,0 +1,169 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gin-server/database/models"
+)
+
+func TestReadUsersCSV(t *testing.T) {
+	data := "\ufeffuser_name,pass_wd,user_id,user_type,gateway_device_id,email,unknown\n" +
+		"alice01,password01,10001,1,2001,alice@example.com,x\n" +
+		"bob0001,password02,abc,1,2001,,\n"
+
+	records, rows, rowErrors, err := ReadUsers(strings.NewReader(data), FormatCSV, 10)
+	if err != nil {
+		t.Fatalf("ReadUsers() error = %v", err)
+	}
+	if len(records) != 1 || records[0].UserID != 10001 || records[0].Email != "alice@example.com" || rows[0] != 1 {
+		t.Errorf("ReadUsers() = %+v, rows %v", records, rows)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Row != 2 || rowErrors[0].Field != "user_id" {
+		t.Errorf("rowErrors = %+v, want 第2行user_id错误", rowErrors)
+	}
+}
+
+func TestReadUsersCSVMissingColumn(t *testing.T) {
+	data := "user_name,user_id,user_type,gateway_device_id\nalice01,10001,1,2001\n"
+	if _, _, _, err := ReadUsers(strings.NewReader(data), FormatCSV, 10); err == nil || !strings.Contains(err.Error(), "pass_wd") {
+		t.Errorf("缺少pass_wd列时应返回错误, got %v", err)
+	}
+}
+
+func TestReadDevicesJSON(t *testing.T) {
+	data := `[
+		{"device_name": "安全接入管理设备", "device_type": 4, "pass_wd": "password01", "device_id": 1000},
+		{"device_name": "网关设备01", "device_type": "gateway", "device_id": 2001}
+	]`
+
+	records, rows, rowErrors, err := ReadDevices(strings.NewReader(data), FormatJSON, 10)
+	if err != nil {
+		t.Fatalf("ReadDevices() error = %v", err)
+	}
+	if len(records) != 1 || records[0].DeviceID != 1000 || rows[0] != 1 {
+		t.Errorf("ReadDevices() = %+v, rows %v", records, rows)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Row != 2 || rowErrors[0].Field != "device_type" {
+		t.Errorf("rowErrors = %+v, want 第2行device_type错误", rowErrors)
+	}
+
+	if _, _, _, err := ReadDevices(strings.NewReader(`{"device_id": 1}`), FormatJSON, 10); err == nil {
+		t.Error("内容不是数组时应返回错误")
+	}
+}
+
+func TestReadTooManyRows(t *testing.T) {
+	data := "user_name,pass_wd,user_id,user_type,gateway_device_id\n" +
+		"alice01,password01,1,1,2001\nalice02,password01,2,1,2001\nalice03,password01,3,1,2001\n"
+	if _, _, _, err := ReadUsers(strings.NewReader(data), FormatCSV, 2); !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("CSV超过记录数上限应返回ErrTooManyRows, got %v", err)
+	}
+	if _, _, _, err := ReadUsers(strings.NewReader(`[{},{},{}]`), FormatJSON, 2); !errors.Is(err, ErrTooManyRows) {
+		t.Errorf("JSON超过记录数上限应返回ErrTooManyRows, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	user := UserRecord{UserName: "abc", PassWD: "short", UserID: 1, UserType: 1}
+	fields := map[string]bool{}
+	for _, e := range user.Validate(3) {
+		if e.Row != 3 {
+			t.Errorf("行号 = %d, want 3", e.Row)
+		}
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"user_name", "pass_wd", "gateway_device_id"} {
+		if !fields[field] {
+			t.Errorf("UserRecord.Validate() 缺少%s错误", field)
+		}
+	}
+
+	gateway := DeviceRecord{DeviceName: "网关设备01", DeviceType: models.DeviceTypeGatewayA, PassWD: "password01", DeviceID: 2001, SuperiorDeviceID: 1000}
+	if errs := gateway.Validate(1); len(errs) != 3 {
+		t.Errorf("网关设备缺少地址和SES密钥时应返回3个错误, got %+v", errs)
+	}
+	security := DeviceRecord{DeviceName: "安全接入管理设备", DeviceType: models.DeviceTypeSecurityMgmt, PassWD: "password01", DeviceID: 1000}
+	if errs := security.Validate(1); len(errs) != 0 {
+		t.Errorf("DeviceRecord.Validate() = %+v, want 无错误", errs)
+	}
+}
+
+func TestUserWriterRoundTrip(t *testing.T) {
+	status := models.UserStatusOffline
+	users := []models.User{
+		{Username: "alice01", UserID: 10001, UserType: 1, GatewayDeviceID: 2001, Status: &status, Email: "a@example.com"},
+		{Username: "bob,0002", UserID: 10002, UserType: 2, GatewayDeviceID: 2002, Password: "secret-hash"},
+	}
+
+	for _, format := range []string{FormatCSV, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewUserWriter(&buf, format)
+			if err != nil {
+				t.Fatalf("NewUserWriter() error = %v", err)
+			}
+			for i := range users {
+				record := UserRecordFromModel(&users[i])
+				if err := writer.Write(&record); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if strings.Contains(buf.String(), "secret-hash") || strings.Contains(buf.String(), "pass_wd") {
+				t.Errorf("导出内容不应包含口令: %s", buf.String())
+			}
+
+			if format == FormatJSON {
+				var decoded []UserRecord
+				if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 {
+					t.Fatalf("导出的JSON无效: %v, %s", err, buf.String())
+				}
+				if decoded[1].UserName != "bob,0002" || *decoded[0].Status != status {
+					t.Errorf("decoded = %+v", decoded)
+				}
+				return
+			}
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 3 || lines[0] != "user_name,user_id,user_type,gateway_device_id,status,email,permission_mask,created_at" {
+				t.Errorf("导出的CSV = %q", buf.String())
+			}
+			if !strings.HasPrefix(lines[2], `"bob,0002",10002,2,2002,,`) {
+				t.Errorf("CSV第2条记录 = %q", lines[2])
+			}
+		})
+	}
+}
+
+func TestEmptyJSONExport(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewDeviceWriter(&buf, FormatJSON)
+	if err != nil {
+		t.Fatalf("NewDeviceWriter() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	var decoded []DeviceRecord
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 0 {
+		t.Errorf("空导出应为空数组, got %q, %v", buf.String(), err)
+	}
+}
+
+func TestFormatFromFileName(t *testing.T) {
+	if format, err := FormatFromFileName("Users.CSV"); err != nil || format != FormatCSV {
+		t.Errorf("FormatFromFileName(Users.CSV) = %q, %v", format, err)
+	}
+	if format, err := FormatFromFileName("devices.json"); err != nil || format != FormatJSON {
+		t.Errorf("FormatFromFileName(devices.json) = %q, %v", format, err)
+	}
+	if _, err := FormatFromFileName("users.xlsx"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("FormatFromFileName(users.xlsx) error = %v", err)
+	}
+}
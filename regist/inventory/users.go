@@ -0,0 +1,101 @@
+package inventory
+
+import (
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"gin-server/database/models"
+)
+
+// UserRecord 用户清单记录
+// 导入时pass_wd必填，status和created_at只在导出时写出
+type UserRecord struct {
+	UserName        string `json:"user_name"`
+	PassWD          string `json:"pass_wd,omitempty"`
+	UserID          int    `json:"user_id"`
+	UserType        int    `json:"user_type"`
+	GatewayDeviceID int    `json:"gateway_device_id"`
+	Email           string `json:"email"`
+	PermissionMask  string `json:"permission_mask"`
+	Status          *int   `json:"status,omitempty"`
+	CreatedAt       string `json:"created_at,omitempty"`
+}
+
+// userColumns 用户清单字段
+var userColumns = []column[UserRecord]{
+	{name: "user_name", importable: true, exportable: true, required: true,
+		get: func(r *UserRecord) string { return r.UserName },
+		set: func(r *UserRecord, v string) error { r.UserName = v; return nil }},
+	{name: "pass_wd", importable: true, required: true,
+		set: func(r *UserRecord, v string) error { r.PassWD = v; return nil }},
+	{name: "user_id", importable: true, exportable: true, required: true,
+		get: func(r *UserRecord) string { return strconv.Itoa(r.UserID) },
+		set: func(r *UserRecord, v string) (err error) { r.UserID, err = parseInt(v); return }},
+	{name: "user_type", importable: true, exportable: true, required: true,
+		get: func(r *UserRecord) string { return strconv.Itoa(r.UserType) },
+		set: func(r *UserRecord, v string) (err error) { r.UserType, err = parseInt(v); return }},
+	{name: "gateway_device_id", importable: true, exportable: true, required: true,
+		get: func(r *UserRecord) string { return strconv.Itoa(r.GatewayDeviceID) },
+		set: func(r *UserRecord, v string) (err error) { r.GatewayDeviceID, err = parseInt(v); return }},
+	{name: "status", exportable: true,
+		get: func(r *UserRecord) string {
+			if r.Status == nil {
+				return ""
+			}
+			return strconv.Itoa(*r.Status)
+		}},
+	{name: "email", importable: true, exportable: true,
+		get: func(r *UserRecord) string { return r.Email },
+		set: func(r *UserRecord, v string) error { r.Email = v; return nil }},
+	{name: "permission_mask", importable: true, exportable: true,
+		get: func(r *UserRecord) string { return r.PermissionMask },
+		set: func(r *UserRecord, v string) error { r.PermissionMask = v; return nil }},
+	{name: "created_at", exportable: true,
+		get: func(r *UserRecord) string { return r.CreatedAt }},
+}
+
+// ReadUsers 解析用户清单，返回记录、记录所在的行号以及行级错误
+func ReadUsers(r io.Reader, format string, maxRows int) ([]UserRecord, []int, []RowError, error) {
+	return read(r, format, userColumns, maxRows)
+}
+
+// NewUserWriter 创建用户清单导出写入器
+func NewUserWriter(w io.Writer, format string) (Writer[UserRecord], error) {
+	return newWriter(w, format, userColumns)
+}
+
+// Validate 校验字段格式，规则与用户注册接口一致
+func (r *UserRecord) Validate(row int) []RowError {
+	var errs []RowError
+	if n := utf8.RuneCountInString(r.UserName); n < 4 || n > 20 {
+		errs = append(errs, RowError{Row: row, Field: "user_name", Error: "用户名长度必须为4-20个字符"})
+	}
+	if utf8.RuneCountInString(r.PassWD) < 8 {
+		errs = append(errs, RowError{Row: row, Field: "pass_wd", Error: "口令至少8个字符"})
+	}
+	if r.UserID == 0 {
+		errs = append(errs, RowError{Row: row, Field: "user_id", Error: "缺少用户ID"})
+	}
+	if r.UserType == 0 {
+		errs = append(errs, RowError{Row: row, Field: "user_type", Error: "缺少用户类型"})
+	}
+	if r.GatewayDeviceID == 0 {
+		errs = append(errs, RowError{Row: row, Field: "gateway_device_id", Error: "缺少所属网关设备ID"})
+	}
+	return errs
+}
+
+// UserRecordFromModel 将用户模型转换为导出记录
+func UserRecordFromModel(user *models.User) UserRecord {
+	return UserRecord{
+		UserName:        user.Username,
+		UserID:          user.UserID,
+		UserType:        user.UserType,
+		GatewayDeviceID: user.GatewayDeviceID,
+		Email:           user.Email,
+		PermissionMask:  user.PermissionMask,
+		Status:          user.Status,
+		CreatedAt:       user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
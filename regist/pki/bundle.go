@@ -0,0 +1,35 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+)
+
+// EncodeCertificates 按顺序PEM编码多个证书
+func EncodeCertificates(certs []*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		buf.Write(EncodeCertificate(cert))
+	}
+	return buf.Bytes()
+}
+
+// CABundle 从多组证书中取出CA证书组成证书包，按指纹去重并保持原有顺序
+func CABundle(sets ...[]*x509.Certificate) []*x509.Certificate {
+	seen := make(map[string]bool)
+	var bundle []*x509.Certificate
+	for _, certs := range sets {
+		for _, cert := range certs {
+			if !cert.IsCA {
+				continue
+			}
+			fingerprint := Fingerprint(cert)
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			bundle = append(bundle, cert)
+		}
+	}
+	return bundle
+}
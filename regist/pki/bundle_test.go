@@ -0,0 +1,44 @@
+package pki
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestCABundle(t *testing.T) {
+	ca, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+	other, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+
+	key, _ := GenerateKey("ECDSA", 256)
+	leaf, err := ca.Sign(key.Public(), IssueRequest{CommonName: "device-1001"})
+	if err != nil {
+		t.Fatalf("签发证书失败: %v", err)
+	}
+
+	// 实体证书被排除，重复的CA证书只保留一个
+	bundle := CABundle(
+		[]*x509.Certificate{ca.Certificate()},
+		[]*x509.Certificate{leaf, ca.Certificate(), other.Certificate()},
+	)
+	if len(bundle) != 2 {
+		t.Fatalf("证书包数量 = %d, want 2", len(bundle))
+	}
+	if Fingerprint(bundle[0]) != Fingerprint(ca.Certificate()) || Fingerprint(bundle[1]) != Fingerprint(other.Certificate()) {
+		t.Error("证书包顺序与输入顺序不一致")
+	}
+
+	// 编码后可以重新解析
+	parsed, err := ParseCertificateChain(EncodeCertificates(bundle))
+	if err != nil {
+		t.Fatalf("解析证书包失败: %v", err)
+	}
+	if len(parsed) != 2 {
+		t.Errorf("解析得到的证书数量 = %d, want 2", len(parsed))
+	}
+}
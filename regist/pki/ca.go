@@ -0,0 +1,407 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/fileutil"
+)
+
+// CA文件名
+const (
+	CACertFile = "ca.crt"
+	CAKeyFile  = "ca.key"
+)
+
+// 扩展密钥用途名称
+const (
+	ExtKeyUsageClientAuth      = "client_auth"
+	ExtKeyUsageServerAuth      = "server_auth"
+	ExtKeyUsageEmailProtection = "email_protection"
+	ExtKeyUsageCodeSigning     = "code_signing"
+)
+
+// 证书签发错误
+var (
+	ErrCANotInitialized        = errors.New("证书颁发机构未初始化")
+	ErrInvalidCSR              = errors.New("无效的证书签名请求")
+	ErrInvalidValidity         = errors.New("无效的证书有效期")
+	ErrUnknownExtKeyUsage      = errors.New("不支持的扩展密钥用途")
+	ErrInvalidIPAddress        = errors.New("无效的IP地址")
+	ErrUnsupportedKeyAlgorithm = errors.New("不支持的密钥算法")
+)
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	ExtKeyUsageClientAuth:      x509.ExtKeyUsageClientAuth,
+	ExtKeyUsageServerAuth:      x509.ExtKeyUsageServerAuth,
+	ExtKeyUsageEmailProtection: x509.ExtKeyUsageEmailProtection,
+	ExtKeyUsageCodeSigning:     x509.ExtKeyUsageCodeSigning,
+}
+
+// IssueRequest 证书签发参数
+type IssueRequest struct {
+	CommonName     string   // 通用名称
+	Organization   string   // 组织名称，为空时使用CA的组织名称
+	DNSNames       []string // DNS主题备用名称
+	IPAddresses    []string // IP主题备用名称
+	EmailAddresses []string // 邮箱主题备用名称
+	ValidityDays   int      // 有效期（天），0表示使用默认有效期
+	ExtKeyUsages   []string // 扩展密钥用途，为空时仅用于客户端认证
+}
+
+// CA 内置证书颁发机构
+type CA struct {
+	cert    *x509.Certificate
+	key     crypto.Signer
+	certPEM []byte
+	cfg     config.CAConfig
+	now     func() time.Time
+}
+
+var (
+	defaultCA *CA
+	caMutex   sync.RWMutex
+)
+
+// InitCA 加载或生成CA并设置为全局CA
+func InitCA(cfg *config.Config) error {
+	ca, err := EnsureCA(cfg.CA)
+	if err != nil {
+		return err
+	}
+
+	caMutex.Lock()
+	defaultCA = ca
+	caMutex.Unlock()
+	return nil
+}
+
+// GetCA 获取全局CA
+func GetCA() (*CA, error) {
+	caMutex.RLock()
+	defer caMutex.RUnlock()
+	if defaultCA == nil {
+		return nil, ErrCANotInitialized
+	}
+	return defaultCA, nil
+}
+
+// EnsureCA 确保CA证书和私钥存在，不存在时生成自签名根证书
+func EnsureCA(cfg config.CAConfig) (*CA, error) {
+	if err := fileutil.EnsureDir(cfg.Dir); err != nil {
+		return nil, fmt.Errorf("创建CA目录失败: %w", err)
+	}
+	if err := os.Chmod(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("设置CA目录权限失败: %w", err)
+	}
+
+	certPath := filepath.Join(cfg.Dir, CACertFile)
+	keyPath := filepath.Join(cfg.Dir, CAKeyFile)
+	if fileutil.IsFileExists(certPath) && fileutil.IsFileExists(keyPath) {
+		return LoadCA(cfg)
+	}
+
+	key, err := GenerateKey(cfg.KeyAlgorithm, cfg.KeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("生成CA私钥失败: %w", err)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	subject := pkix.Name{CommonName: cfg.CommonName}
+	if cfg.Organization != "" {
+		subject.Organization = []string{cfg.Organization}
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.AddDate(0, 0, cfg.ValidityDays),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		SubjectKeyId:          subjectKeyID(key.Public()),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("生成CA证书失败: %w", err)
+	}
+
+	keyPEM, err := EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("保存CA私钥失败: %w", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		// 如果保存证书失败，删除已生成的私钥
+		os.Remove(keyPath)
+		return nil, fmt.Errorf("保存CA证书失败: %w", err)
+	}
+
+	return LoadCA(cfg)
+}
+
+// LoadCA 从CA目录加载CA证书和私钥
+func LoadCA(cfg config.CAConfig) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(cfg.Dir, CACertFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取CA证书失败: %w", err)
+	}
+	chain, err := ParseCertificateChain(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("解析CA证书失败: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(cfg.Dir, CAKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取CA私钥失败: %w", err)
+	}
+	key, err := ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("解析CA私钥失败: %w", err)
+	}
+
+	cert := chain[0]
+	if !cert.IsCA {
+		return nil, errors.New("CA证书缺少CA基本约束")
+	}
+	if !KeyMatchesCertificate(key, cert) {
+		return nil, fmt.Errorf("CA私钥与CA证书不匹配: %w", ErrKeyCertMismatch)
+	}
+
+	return &CA{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+		cfg:     cfg,
+		now:     time.Now,
+	}, nil
+}
+
+// Certificate 返回CA证书
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// CertificatePEM 返回PEM编码的CA证书
+func (ca *CA) CertificatePEM() []byte {
+	return ca.certPEM
+}
+
+// Sign 为指定公钥签发证书
+func (ca *CA) Sign(pub crypto.PublicKey, req IssueRequest) (*x509.Certificate, error) {
+	validityDays := req.ValidityDays
+	if validityDays == 0 {
+		validityDays = ca.cfg.CertValidityDays
+	}
+	// 请求和CA_CERT_VALIDITY_DAYS都未指定有效期时不签发零有效期的证书
+	if validityDays <= 0 {
+		return nil, fmt.Errorf("%w: 有效期必须至少1天", ErrInvalidValidity)
+	}
+	if ca.cfg.MaxCertValidityDays > 0 && validityDays > ca.cfg.MaxCertValidityDays {
+		return nil, fmt.Errorf("%w: 有效期必须在1到%d天之间", ErrInvalidValidity, ca.cfg.MaxCertValidityDays)
+	}
+
+	usages := req.ExtKeyUsages
+	if len(usages) == 0 {
+		usages = []string{ExtKeyUsageClientAuth}
+	}
+	extKeyUsage := make([]x509.ExtKeyUsage, 0, len(usages))
+	for _, name := range usages {
+		usage, ok := extKeyUsages[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownExtKeyUsage, name)
+		}
+		extKeyUsage = append(extKeyUsage, usage)
+	}
+
+	ips := make([]net.IP, 0, len(req.IPAddresses))
+	for _, addr := range req.IPAddresses {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIPAddress, addr)
+		}
+		ips = append(ips, ip)
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	organization := req.Organization
+	if organization == "" {
+		organization = ca.cfg.Organization
+	}
+	subject := pkix.Name{CommonName: req.CommonName}
+	if organization != "" {
+		subject.Organization = []string{organization}
+	}
+
+	// 证书有效期不能超过CA证书
+	now := ca.now()
+	notAfter := now.AddDate(0, 0, validityDays)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := pub.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           extKeyUsage,
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+		DNSNames:              req.DNSNames,
+		IPAddresses:           ips,
+		EmailAddresses:        req.EmailAddresses,
+		SubjectKeyId:          subjectKeyID(pub),
+		AuthorityKeyId:        ca.cert.SubjectKeyId,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, pub, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("签发证书失败: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// SignCSR 校验证书签名请求并签发证书
+// 签发参数中未指定的通用名称和主题备用名称取自证书签名请求
+func (ca *CA) SignCSR(csrPEM []byte, req IssueRequest) (*x509.Certificate, error) {
+	csr, err := ParseCSR(csrPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.CommonName == "" {
+		req.CommonName = csr.Subject.CommonName
+	}
+	if req.CommonName == "" {
+		return nil, fmt.Errorf("%w: 缺少通用名称", ErrInvalidCSR)
+	}
+	if len(req.DNSNames) == 0 {
+		req.DNSNames = csr.DNSNames
+	}
+	if len(req.EmailAddresses) == 0 {
+		req.EmailAddresses = csr.EmailAddresses
+	}
+	if len(req.IPAddresses) == 0 {
+		for _, ip := range csr.IPAddresses {
+			req.IPAddresses = append(req.IPAddresses, ip.String())
+		}
+	}
+
+	return ca.Sign(csr.PublicKey, req)
+}
+
+// ParseCSR 解析并校验PEM编码的证书签名请求
+func ParseCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || (block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST") {
+		return nil, fmt.Errorf("%w: 缺少PEM编码的CERTIFICATE REQUEST", ErrInvalidCSR)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: 签名校验失败: %v", ErrInvalidCSR, err)
+	}
+	return csr, nil
+}
+
+// GenerateKey 生成私钥
+// algorithm可选值: "RSA", "ECDSA", "ED25519"，bits为RSA位数或ECDSA曲线位数
+func GenerateKey(algorithm string, bits int) (crypto.Signer, error) {
+	switch strings.ToUpper(algorithm) {
+	case "RSA":
+		if bits < 2048 {
+			return nil, fmt.Errorf("%w: RSA密钥长度不能小于2048", ErrUnsupportedKeyAlgorithm)
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "ECDSA":
+		var curve elliptic.Curve
+		switch bits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: 不支持的ECDSA曲线长度 %d", ErrUnsupportedKeyAlgorithm, bits)
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	case "ED25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyAlgorithm, algorithm)
+	}
+}
+
+// EncodeCertificate PEM编码证书
+func EncodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// EncodePrivateKey 以PKCS#8格式PEM编码私钥
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码私钥失败: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// newSerialNumber 生成128位随机序列号
+func newSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("生成证书序列号失败: %w", err)
+	}
+	return serial, nil
+}
+
+// subjectKeyID 计算公钥标识（公钥DER编码的SHA-1摘要）
+func subjectKeyID(pub crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil
+	}
+	sum := sha1.Sum(der)
+	return sum[:]
+}
@@ -0,0 +1,187 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gin-server/config"
+)
+
+func newTestCAConfig(t *testing.T) config.CAConfig {
+	t.Helper()
+	cfg := config.DefaultConfig().CA
+	cfg.Dir = filepath.Join(t.TempDir(), "ca")
+	cfg.KeyLength = 256
+	return cfg
+}
+
+func TestEnsureCAGeneratesAndReloads(t *testing.T) {
+	cfg := newTestCAConfig(t)
+
+	ca, err := EnsureCA(cfg)
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+	if !ca.Certificate().IsCA {
+		t.Error("CA证书应带有CA基本约束")
+	}
+	if ca.Certificate().Subject.CommonName != cfg.CommonName {
+		t.Errorf("CA通用名称 = %s, want %s", ca.Certificate().Subject.CommonName, cfg.CommonName)
+	}
+
+	// 再次调用应加载已有的CA而不是重新生成
+	reloaded, err := EnsureCA(cfg)
+	if err != nil {
+		t.Fatalf("加载CA失败: %v", err)
+	}
+	if Fingerprint(reloaded.Certificate()) != Fingerprint(ca.Certificate()) {
+		t.Error("重新加载的CA证书与首次生成的不一致")
+	}
+}
+
+func TestCASign(t *testing.T) {
+	ca, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+
+	key, err := GenerateKey("ECDSA", 256)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	cert, err := ca.Sign(key.Public(), IssueRequest{
+		CommonName:   "device-1001",
+		DNSNames:     []string{"device-1001.local"},
+		IPAddresses:  []string{"192.168.1.10"},
+		ValidityDays: 30,
+		ExtKeyUsages: []string{ExtKeyUsageClientAuth, ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		t.Fatalf("签发证书失败: %v", err)
+	}
+
+	if !KeyMatchesCertificate(key, cert) {
+		t.Error("签发的证书应与密钥匹配")
+	}
+	if err := ValidateChain([]*x509.Certificate{cert, ca.Certificate()}, time.Now()); err != nil {
+		t.Errorf("签发的证书链校验失败: %v", err)
+	}
+	if cert.IsCA {
+		t.Error("签发的证书不应是CA证书")
+	}
+	if len(cert.IPAddresses) != 1 || cert.IPAddresses[0].String() != "192.168.1.10" {
+		t.Errorf("IP主题备用名称错误: %v", cert.IPAddresses)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate())
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     pool,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("签发的证书不能用于客户端认证: %v", err)
+	}
+}
+
+func TestCASignRejectsInvalidRequest(t *testing.T) {
+	ca, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+	key, _ := GenerateKey("ED25519", 0)
+
+	tests := []struct {
+		name string
+		req  IssueRequest
+		want error
+	}{
+		{"有效期过长", IssueRequest{CommonName: "u", ValidityDays: 10000}, ErrInvalidValidity},
+		{"有效期为负数", IssueRequest{CommonName: "u", ValidityDays: -1}, ErrInvalidValidity},
+		{"未知扩展用途", IssueRequest{CommonName: "u", ExtKeyUsages: []string{"unknown"}}, ErrUnknownExtKeyUsage},
+		{"无效IP", IssueRequest{CommonName: "u", IPAddresses: []string{"not-an-ip"}}, ErrInvalidIPAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ca.Sign(key.Public(), tt.req); !errors.Is(err, tt.want) {
+				t.Errorf("Sign() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCASignValidityDefaults(t *testing.T) {
+	cfg := newTestCAConfig(t)
+	cfg.CertValidityDays = 0
+	cfg.MaxCertValidityDays = 0
+	ca, err := EnsureCA(cfg)
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+	key, _ := GenerateKey("ED25519", 0)
+
+	// 未配置默认有效期且请求未指定时拒绝签发
+	_, err = ca.Sign(key.Public(), IssueRequest{CommonName: "u"})
+	if !errors.Is(err, ErrInvalidValidity) {
+		t.Fatalf("Sign() error = %v, want ErrInvalidValidity", err)
+	}
+	if strings.Contains(err.Error(), "到0天") {
+		t.Errorf("最长有效期不限时错误信息不应包含上限: %v", err)
+	}
+
+	// 最长有效期不限时允许较长的有效期
+	cert, err := ca.Sign(key.Public(), IssueRequest{CommonName: "u", ValidityDays: 1000})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if days := cert.NotAfter.Sub(cert.NotBefore).Hours() / 24; days < 1000 {
+		t.Errorf("证书有效期 = %.0f天, want 至少1000天", days)
+	}
+}
+
+func TestCASignCSR(t *testing.T) {
+	ca, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "user1"},
+		DNSNames: []string{"user1.example.com"},
+	}, key)
+	if err != nil {
+		t.Fatalf("生成CSR失败: %v", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+
+	cert, err := ca.SignCSR(csrPEM, IssueRequest{})
+	if err != nil {
+		t.Fatalf("签发CSR失败: %v", err)
+	}
+	if cert.Subject.CommonName != "user1" {
+		t.Errorf("通用名称 = %s, want user1", cert.Subject.CommonName)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "user1.example.com" {
+		t.Errorf("DNS主题备用名称应取自CSR: %v", cert.DNSNames)
+	}
+	if !KeyMatchesCertificate(key, cert) {
+		t.Error("签发的证书应使用CSR中的公钥")
+	}
+
+	// 篡改CSR内容导致签名校验失败
+	tampered := append([]byte{}, der...)
+	tampered[len(tampered)-1] ^= 0xff
+	tamperedPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: tampered})
+	if _, err := ca.SignCSR(tamperedPEM, IssueRequest{}); !errors.Is(err, ErrInvalidCSR) {
+		t.Errorf("篡改的CSR应返回ErrInvalidCSR, got %v", err)
+	}
+}
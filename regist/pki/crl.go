@@ -0,0 +1,112 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// 吊销原因代码（RFC 5280 5.3.1）
+const (
+	RevocationReasonUnspecified          = 0
+	RevocationReasonKeyCompromise        = 1
+	RevocationReasonCACompromise         = 2
+	RevocationReasonAffiliationChanged   = 3
+	RevocationReasonSuperseded           = 4
+	RevocationReasonCessationOfOperation = 5
+	RevocationReasonCertificateHold      = 6
+	RevocationReasonPrivilegeWithdrawn   = 9
+	RevocationReasonAACompromise         = 10
+)
+
+// 吊销错误
+var (
+	ErrInvalidRevocationReason = errors.New("无效的吊销原因代码")
+	ErrInvalidSerialNumber     = errors.New("无效的证书序列号")
+)
+
+var revocationReasonNames = map[int]string{
+	RevocationReasonUnspecified:          "unspecified",
+	RevocationReasonKeyCompromise:        "keyCompromise",
+	RevocationReasonCACompromise:         "cACompromise",
+	RevocationReasonAffiliationChanged:   "affiliationChanged",
+	RevocationReasonSuperseded:           "superseded",
+	RevocationReasonCessationOfOperation: "cessationOfOperation",
+	RevocationReasonCertificateHold:      "certificateHold",
+	RevocationReasonPrivilegeWithdrawn:   "privilegeWithdrawn",
+	RevocationReasonAACompromise:         "aACompromise",
+}
+
+// RevocationReasonName 返回吊销原因代码的名称，无效代码返回空字符串
+func RevocationReasonName(code int) string {
+	return revocationReasonNames[code]
+}
+
+// ValidateRevocationReason 校验吊销原因代码
+// 7未定义，8（removeFromCRL）只能用于增量CRL，均不允许使用
+func ValidateRevocationReason(code int) error {
+	if _, ok := revocationReasonNames[code]; !ok {
+		return fmt.Errorf("%w: %d", ErrInvalidRevocationReason, code)
+	}
+	return nil
+}
+
+// RevokedCert CRL中的一条吊销记录
+type RevokedCert struct {
+	SerialNumber string    // 序列号（十六进制）
+	RevokedAt    time.Time // 吊销时间
+	ReasonCode   int       // 吊销原因代码
+}
+
+// CreateCRL 生成由CA签名的DER编码证书吊销列表
+// number为CRL序号，每次生成必须递增
+func (ca *CA) CreateCRL(revoked []RevokedCert, number *big.Int, nextUpdate time.Time) ([]byte, error) {
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, r := range revoked {
+		serial, ok := new(big.Int).SetString(r.SerialNumber, 16)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSerialNumber, r.SerialNumber)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: r.RevokedAt.UTC(),
+			ReasonCode:     r.ReasonCode,
+		})
+	}
+
+	template := &x509.RevocationList{
+		Number:                    number,
+		ThisUpdate:                ca.now().UTC(),
+		NextUpdate:                nextUpdate.UTC(),
+		RevokedCertificateEntries: entries,
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("生成证书吊销列表失败: %w", err)
+	}
+	return der, nil
+}
+
+// ParseCRL 解析DER或PEM编码的证书吊销列表
+func ParseCRL(data []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedPEMBlock, block.Type)
+		}
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书吊销列表失败: %w", err)
+	}
+	return crl, nil
+}
+
+// EncodeCRL PEM编码证书吊销列表
+func EncodeCRL(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
@@ -0,0 +1,82 @@
+package pki
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestCACreateCRL(t *testing.T) {
+	ca, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+
+	key, _ := GenerateKey("ECDSA", 256)
+	cert, err := ca.Sign(key.Public(), IssueRequest{CommonName: "device-1001"})
+	if err != nil {
+		t.Fatalf("签发证书失败: %v", err)
+	}
+
+	revokedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	der, err := ca.CreateCRL([]RevokedCert{{
+		SerialNumber: SerialHex(cert),
+		RevokedAt:    revokedAt,
+		ReasonCode:   RevocationReasonKeyCompromise,
+	}}, big.NewInt(3), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("生成CRL失败: %v", err)
+	}
+
+	// DER和PEM编码都应能解析
+	for _, data := range [][]byte{der, EncodeCRL(der)} {
+		crl, err := ParseCRL(data)
+		if err != nil {
+			t.Fatalf("解析CRL失败: %v", err)
+		}
+		if err := crl.CheckSignatureFrom(ca.Certificate()); err != nil {
+			t.Errorf("CRL签名校验失败: %v", err)
+		}
+		if crl.Number.Int64() != 3 {
+			t.Errorf("CRL序号 = %v, want 3", crl.Number)
+		}
+		if len(crl.RevokedCertificateEntries) != 1 {
+			t.Fatalf("吊销记录数量 = %d, want 1", len(crl.RevokedCertificateEntries))
+		}
+		entry := crl.RevokedCertificateEntries[0]
+		if entry.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			t.Errorf("吊销记录序列号 = %x, want %x", entry.SerialNumber, cert.SerialNumber)
+		}
+		if entry.ReasonCode != RevocationReasonKeyCompromise {
+			t.Errorf("吊销原因 = %d, want %d", entry.ReasonCode, RevocationReasonKeyCompromise)
+		}
+		if !entry.RevocationTime.Equal(revokedAt) {
+			t.Errorf("吊销时间 = %v, want %v", entry.RevocationTime, revokedAt)
+		}
+	}
+}
+
+func TestCACreateCRLRejectsInvalidSerial(t *testing.T) {
+	ca, err := EnsureCA(newTestCAConfig(t))
+	if err != nil {
+		t.Fatalf("生成CA失败: %v", err)
+	}
+	_, err = ca.CreateCRL([]RevokedCert{{SerialNumber: "not-hex", RevokedAt: time.Now()}}, big.NewInt(1), time.Now().Add(time.Hour))
+	if !errors.Is(err, ErrInvalidSerialNumber) {
+		t.Errorf("无效序列号应返回ErrInvalidSerialNumber, got %v", err)
+	}
+}
+
+func TestValidateRevocationReason(t *testing.T) {
+	for _, code := range []int{RevocationReasonUnspecified, RevocationReasonSuperseded, RevocationReasonAACompromise} {
+		if err := ValidateRevocationReason(code); err != nil {
+			t.Errorf("ValidateRevocationReason(%d) error = %v", code, err)
+		}
+	}
+	for _, code := range []int{-1, 7, 8, 11} {
+		if err := ValidateRevocationReason(code); !errors.Is(err, ErrInvalidRevocationReason) {
+			t.Errorf("ValidateRevocationReason(%d) 应返回ErrInvalidRevocationReason, got %v", code, err)
+		}
+	}
+}
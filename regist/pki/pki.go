@@ -0,0 +1,187 @@
+// Package pki 提供证书和私钥的解析与校验
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 证书和私钥校验错误
+var (
+	ErrNoCertificate      = errors.New("文件中没有PEM编码的证书")
+	ErrNoPrivateKey       = errors.New("文件中没有PEM编码的私钥")
+	ErrEncryptedKey       = errors.New("不支持加密的私钥，请上传未加密的私钥")
+	ErrUnsupportedKey     = errors.New("不支持的私钥类型")
+	ErrCertNotYetValid    = errors.New("证书尚未生效")
+	ErrCertExpired        = errors.New("证书已过期")
+	ErrChainBroken        = errors.New("证书链不完整或签名无效")
+	ErrKeyCertMismatch    = errors.New("私钥与证书的公钥不匹配")
+	ErrUnexpectedPEMBlock = errors.New("证书文件中包含非证书内容")
+)
+
+// CertInfo 证书解析结果
+type CertInfo struct {
+	Subject      string    `json:"subject"`       // 证书主题
+	Issuer       string    `json:"issuer"`        // 签发者
+	SerialNumber string    `json:"serial_number"` // 序列号（十六进制）
+	Fingerprint  string    `json:"fingerprint"`   // SHA-256指纹（十六进制）
+	NotBefore    time.Time `json:"not_before"`    // 生效时间
+	NotAfter     time.Time `json:"not_after"`     // 过期时间
+	KeyType      string    `json:"key_type"`      // 公钥类型
+}
+
+// NewCertInfo 提取证书信息
+func NewCertInfo(cert *x509.Certificate) *CertInfo {
+	return &CertInfo{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: SerialHex(cert),
+		Fingerprint:  Fingerprint(cert),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		KeyType:      PublicKeyType(cert.PublicKey),
+	}
+}
+
+// Fingerprint 计算证书的SHA-256指纹
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// SerialHex 返回十六进制格式的证书序列号
+func SerialHex(cert *x509.Certificate) string {
+	return strings.ToLower(cert.SerialNumber.Text(16))
+}
+
+// ParseCertificateChain 解析PEM编码的证书链，第一个证书为实体证书
+// 文件中只允许出现CERTIFICATE块
+func ParseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%w: %s", ErrUnexpectedPEMBlock, block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, ErrNoCertificate
+	}
+	return chain, nil
+}
+
+// ValidateChain 校验证书链中每个证书的有效期，以及相邻证书之间的签名关系
+func ValidateChain(chain []*x509.Certificate, now time.Time) error {
+	for i, cert := range chain {
+		if err := ValidateValidity(cert, now); err != nil {
+			if i == 0 {
+				return err
+			}
+			return fmt.Errorf("证书链第 %d 个证书: %w", i+1, err)
+		}
+		if i+1 < len(chain) {
+			if err := cert.CheckSignatureFrom(chain[i+1]); err != nil {
+				return fmt.Errorf("%w: %v", ErrChainBroken, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateValidity 校验证书有效期
+func ValidateValidity(cert *x509.Certificate, now time.Time) error {
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("%w，生效时间: %s", ErrCertNotYetValid, cert.NotBefore.Format("2006-01-02 15:04:05"))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("%w，过期时间: %s", ErrCertExpired, cert.NotAfter.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// ParsePrivateKey 解析PEM编码的私钥
+// 支持PKCS#8、PKCS#1 RSA、SEC1 EC以及KeyManager生成的ECDSA和ED25519私钥格式
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, ErrNoPrivateKey
+		}
+
+		if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+			return nil, ErrEncryptedKey
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("解析PKCS#8私钥失败: %w", err)
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, ErrUnsupportedKey
+			}
+			return signer, nil
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("解析RSA私钥失败: %w", err)
+			}
+			return key, nil
+		case "EC PRIVATE KEY", "ECDSA PRIVATE KEY":
+			key, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("解析EC私钥失败: %w", err)
+			}
+			return key, nil
+		case "ED25519 PRIVATE KEY":
+			if len(block.Bytes) != ed25519.PrivateKeySize {
+				return nil, fmt.Errorf("%w: 无效的ED25519私钥长度", ErrUnsupportedKey)
+			}
+			return ed25519.PrivateKey(block.Bytes), nil
+		}
+		// 跳过EC PARAMETERS等非私钥块
+	}
+}
+
+// KeyMatchesCertificate 判断私钥是否与证书的公钥对应
+func KeyMatchesCertificate(key crypto.Signer, cert *x509.Certificate) bool {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
+
+// PublicKeyType 返回公钥类型名称，如RSA-2048、ECDSA-P-256、ED25519
+func PublicKeyType(pub crypto.PublicKey) string {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "ED25519"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -0,0 +1,180 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// newTestCert 生成测试证书，parent为nil时生成自签名证书
+func newTestCert(t *testing.T, key crypto.Signer, cn string, notBefore, notAfter time.Time, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("解析证书失败: %v", err)
+	}
+	return cert
+}
+
+func encodeCerts(certs ...*x509.Certificate) []byte {
+	var data []byte
+	for _, cert := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return data
+}
+
+func TestParseCertificateChain(t *testing.T) {
+	now := time.Now()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca := newTestCert(t, caKey, "test-ca", now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+	leaf := newTestCert(t, leafKey, "device-1001", now.Add(-time.Hour), now.Add(time.Hour), ca, caKey)
+
+	chain, err := ParseCertificateChain(encodeCerts(leaf, ca))
+	if err != nil {
+		t.Fatalf("解析证书链失败: %v", err)
+	}
+	if len(chain) != 2 {
+		t.Fatalf("证书链长度 = %d, want 2", len(chain))
+	}
+	if err := ValidateChain(chain, now); err != nil {
+		t.Errorf("有效的证书链校验失败: %v", err)
+	}
+
+	// 顺序颠倒的证书链签名关系不成立
+	reversed, _ := ParseCertificateChain(encodeCerts(ca, leaf))
+	if err := ValidateChain(reversed, now); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("顺序颠倒的证书链应返回ErrChainBroken, got %v", err)
+	}
+
+	info := NewCertInfo(chain[0])
+	if info.Subject != "CN=device-1001" || info.Issuer != "CN=test-ca" {
+		t.Errorf("证书主题或签发者解析错误: %s / %s", info.Subject, info.Issuer)
+	}
+	if len(info.Fingerprint) != 64 {
+		t.Errorf("指纹长度 = %d, want 64", len(info.Fingerprint))
+	}
+	if info.KeyType != "ECDSA-P-256" {
+		t.Errorf("KeyType = %s, want ECDSA-P-256", info.KeyType)
+	}
+}
+
+func TestParseCertificateChainRejectsInvalidContent(t *testing.T) {
+	if _, err := ParseCertificateChain([]byte("not a pem file")); !errors.Is(err, ErrNoCertificate) {
+		t.Errorf("非PEM内容应返回ErrNoCertificate, got %v", err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1, 2, 3}})
+	if _, err := ParseCertificateChain(keyPEM); !errors.Is(err, ErrUnexpectedPEMBlock) {
+		t.Errorf("私钥内容应返回ErrUnexpectedPEMBlock, got %v", err)
+	}
+}
+
+func TestValidateValidity(t *testing.T) {
+	now := time.Now()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	expired := newTestCert(t, key, "expired", now.Add(-2*time.Hour), now.Add(-time.Hour), nil, nil)
+	if err := ValidateValidity(expired, now); !errors.Is(err, ErrCertExpired) {
+		t.Errorf("过期证书应返回ErrCertExpired, got %v", err)
+	}
+
+	future := newTestCert(t, key, "future", now.Add(time.Hour), now.Add(2*time.Hour), nil, nil)
+	if err := ValidateValidity(future, now); !errors.Is(err, ErrCertNotYetValid) {
+		t.Errorf("未生效证书应返回ErrCertNotYetValid, got %v", err)
+	}
+}
+
+func TestParsePrivateKeyAndMatch(t *testing.T) {
+	now := time.Now()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(rsaKey)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		pem     []byte
+		keyType string
+	}{
+		{"PKCS8-RSA", rsaKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), "RSA-2048"},
+		{"PKCS1-RSA", rsaKey, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), "RSA-2048"},
+		{"SEC1-EC", ecKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}), "ECDSA-P-384"},
+		{"KeyManager-ED25519", edKey, pem.EncodeToMemory(&pem.Block{Type: "ED25519 PRIVATE KEY", Bytes: edKey}), "ED25519"},
+	}
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherCert := newTestCert(t, other, "other", now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePrivateKey(tt.pem)
+			if err != nil {
+				t.Fatalf("解析私钥失败: %v", err)
+			}
+			if got := PublicKeyType(key.Public()); got != tt.keyType {
+				t.Errorf("PublicKeyType = %s, want %s", got, tt.keyType)
+			}
+
+			cert := newTestCert(t, tt.key, tt.name, now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+			if !KeyMatchesCertificate(key, cert) {
+				t.Error("私钥应与自身证书匹配")
+			}
+			if KeyMatchesCertificate(key, otherCert) {
+				t.Error("私钥不应与其他证书匹配")
+			}
+		})
+	}
+}
+
+func TestParsePrivateKeyRejectsEncrypted(t *testing.T) {
+	encrypted := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{1, 2, 3}})
+	if _, err := ParsePrivateKey(encrypted); !errors.Is(err, ErrEncryptedKey) {
+		t.Errorf("加密私钥应返回ErrEncryptedKey, got %v", err)
+	}
+
+	legacy := pem.EncodeToMemory(&pem.Block{
+		Type:    "RSA PRIVATE KEY",
+		Headers: map[string]string{"Proc-Type": "4,ENCRYPTED"},
+		Bytes:   []byte{1, 2, 3},
+	})
+	if _, err := ParsePrivateKey(legacy); !errors.Is(err, ErrEncryptedKey) {
+		t.Errorf("传统加密私钥应返回ErrEncryptedKey, got %v", err)
+	}
+
+	if _, err := ParsePrivateKey([]byte("garbage")); !errors.Is(err, ErrNoPrivateKey) {
+		t.Errorf("非PEM内容应返回ErrNoPrivateKey, got %v", err)
+	}
+}
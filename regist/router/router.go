@@ -1,6 +1,8 @@
 package router
 
 import (
+	"gin-server/auth/middleware"
+	"gin-server/auth/permission"
 	"gin-server/config"
 	"gin-server/regist/handler" // 导入处理器包
 
@@ -23,22 +25,69 @@ func SetupRouter(r *gin.Engine) {
 		}
 	})
 
+	// 各路由所需的权限
+	userManage := middleware.RequirePermission(permission.UserManage)
+	deviceManage := middleware.RequirePermission(permission.DeviceManage)
+	certManage := middleware.RequirePermission(permission.CertManage)
+	keyExport := middleware.RequirePermission(permission.KeyExport)
+
 	// 用户管理路由
-	r.POST("/regist/users", handler.RegisterUser)  // 注册用户接口
-	r.GET("/search/users", handler.GetUsers)       // 获取所有用户接口
-	r.PUT("/update/users/:id", handler.UpdateUser) // 更新用户接口
-	r.GET("/search/user", handler.GetUserByID)     // 根据ID查询用户接口
+	r.POST("/regist/users", userManage, handler.RegisterUser)     // 注册用户接口
+	r.GET("/search/users", userManage, handler.GetUsers)          // 获取所有用户接口
+	r.PUT("/update/users/:id", userManage, handler.UpdateUser)    // 更新用户接口
+	r.PATCH("/update/users/:id", userManage, handler.PatchUser)   // 部分更新用户接口
+	r.GET("/search/user", userManage, handler.GetUserByID)        // 根据ID查询用户接口
+	r.DELETE("/delete/users/:id", userManage, handler.DeleteUser) // 删除用户接口
+	r.POST("/restore/users/:id", userManage, handler.RestoreUser) // 恢复已删除用户接口
+	r.POST("/import/users", userManage, handler.ImportUsers)      // 批量导入用户接口
+	r.GET("/export/users", userManage, handler.ExportUsers)       // 导出用户接口
+
+	// 用户生命周期路由
+	r.POST("/freeze/users/:id", userManage, handler.FreezeUser)           // 冻结用户接口
+	r.POST("/unfreeze/users/:id", userManage, handler.UnfreezeUser)       // 解冻用户接口
+	r.POST("/cancel/users/:id", userManage, handler.CancelUser)           // 注销用户接口（同时吊销当前证书）
+	r.POST("/reactivate/users/:id", userManage, handler.ReactivateUser)   // 重新激活已注销用户接口
+	r.GET("/history/users/:id", userManage, handler.GetUserStatusHistory) // 查询用户状态变更历史接口
 
 	// 设备管理路由
-	r.POST("/regist/devices", handler.RegisterDevice)  // 注册设备接口
-	r.GET("/search/devices", handler.GetDevices)       // 获取所有设备接口
-	r.PUT("/update/devices/:id", handler.UpdateDevice) // 更新设备接口
-	r.GET("/search/device", handler.GetDeviceByID)     // 根据ID查询设备接口
+	r.POST("/regist/devices", deviceManage, handler.RegisterDevice)       // 注册设备接口
+	r.GET("/search/devices", deviceManage, handler.GetDevices)            // 获取所有设备接口
+	r.PUT("/update/devices/:id", deviceManage, handler.UpdateDevice)      // 更新设备接口
+	r.PATCH("/update/devices/:id", deviceManage, handler.PatchDevice)     // 部分更新设备接口
+	r.GET("/search/device", deviceManage, handler.GetDeviceByID)          // 根据ID查询设备接口
+	r.DELETE("/delete/devices/:id", deviceManage, handler.DeleteDevice)   // 删除设备接口
+	r.POST("/restore/devices/:id", deviceManage, handler.RestoreDevice)   // 恢复已删除设备接口
+	r.GET("/topology", deviceManage, handler.GetTopology)                 // 查询设备拓扑接口
+	r.POST("/import/devices", deviceManage, handler.ImportDevices)        // 批量导入设备接口
+	r.GET("/export/devices", deviceManage, handler.ExportDevices)         // 导出设备接口
+	r.GET("/devices/:id/metrics", deviceManage, handler.GetDeviceMetrics) // 查询设备性能数据接口
+
+	// 设备上报路由（需设备会话令牌或客户端证书）
+	r.POST("/devices/heartbeat", middleware.RequireDevice(), handler.ReportHeartbeat) // 设备心跳上报接口
 
 	// 证书管理路由
-	r.POST("/bind/users/:id/cert", handler.BindUserCert)     // 用户证书绑定接口
-	r.POST("/bind/users/:id/key", handler.BindUserKey)       // 用户密钥绑定接口
-	r.POST("/bind/devices/:id/cert", handler.BindDeviceCert) // 设备证书绑定接口
-	r.POST("/bind/devices/:id/key", handler.BindDeviceKey)   // 设备密钥绑定接口
-	r.GET("/cert/info", handler.GetCertInfo)                 // 获取证书信息接口
+	r.POST("/bind/users/:id/cert", certManage, handler.BindUserCert)     // 用户证书绑定接口
+	r.POST("/bind/users/:id/key", certManage, handler.BindUserKey)       // 用户密钥绑定接口
+	r.POST("/bind/devices/:id/cert", certManage, handler.BindDeviceCert) // 设备证书绑定接口
+	r.POST("/bind/devices/:id/key", certManage, handler.BindDeviceKey)   // 设备密钥绑定接口
+	r.POST("/bind/batch", certManage, handler.BindCertBatch)             // 从压缩包批量绑定证书和密钥接口
+	r.GET("/cert/info", certManage, handler.GetCertInfo)                 // 获取证书信息接口
+	r.GET("/cert/expiring", certManage, handler.GetExpiringCerts)        // 查询即将过期的证书接口
+	r.GET("/cert/history", certManage, handler.GetCertHistory)           // 查询证书版本历史接口
+	r.POST("/cert/activate", certManage, handler.ActivateCert)           // 切换当前证书版本接口
+	r.GET("/cert/download", certManage, handler.DownloadCert)            // 下载证书接口
+	r.GET("/cert/chain", certManage, handler.DownloadCertChain)          // 下载证书链接口
+	r.POST("/cert/key/export", keyExport, handler.ExportKey)             // 导出私钥接口（需私钥导出权限，记录审计日志）
+	r.GET("/cert/key/exports", certManage, handler.GetKeyExportAudits)   // 查询私钥导出记录接口
+
+	// 内置CA路由
+	r.POST("/issue/users/:id/cert", certManage, handler.IssueUserCert)     // 为用户签发证书接口
+	r.POST("/issue/devices/:id/cert", certManage, handler.IssueDeviceCert) // 为设备签发证书接口
+	r.GET("/cert/ca", handler.GetCACert)                                   // 下载CA证书接口
+	r.GET("/cert/ca/bundle", handler.GetCABundle)                          // 下载CA证书包接口
+
+	// 证书吊销路由
+	r.POST("/revoke/users/:id/cert", certManage, handler.RevokeUserCert)     // 吊销用户证书接口
+	r.POST("/revoke/devices/:id/cert", certManage, handler.RevokeDeviceCert) // 吊销设备证书接口
+	r.GET("/cert/crl", handler.GetCRL)                                       // 下载证书吊销列表接口（免认证）
 }
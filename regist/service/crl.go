@@ -0,0 +1,214 @@
+// Package service 提供注册管理模块的后台服务
+package service
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/alert"
+	"gin-server/configmanager/common/fileutil"
+	"gin-server/configmanager/common/transfer"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+)
+
+// CRLPublisher 证书吊销列表发布器
+// 定期或在证书吊销后重新生成由内置CA签名的CRL，保存到本地并按配置推送到远程存储
+type CRLPublisher struct {
+	revocationRepo repositories.CertRevocationRepository
+	alerter        alert.Alerter
+	cfg            *config.Config
+	mutex          sync.Mutex
+	trigger        chan struct{}
+	stopChan       chan struct{}
+	wg             sync.WaitGroup
+}
+
+var (
+	defaultPublisher *CRLPublisher
+	publisherMutex   sync.RWMutex
+)
+
+// NewCRLPublisher 创建证书吊销列表发布器
+func NewCRLPublisher(repoFactory repositories.RepositoryFactory) *CRLPublisher {
+	return &CRLPublisher{
+		revocationRepo: repoFactory.GetCertRevocationRepository(),
+		alerter:        alert.GetDefaultAlerter(),
+		cfg:            config.GetConfig(),
+		trigger:        make(chan struct{}, 1),
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// InitCRLPublisher 创建并启动证书吊销列表发布器，同时设置为全局发布器
+func InitCRLPublisher(repoFactory repositories.RepositoryFactory) (*CRLPublisher, error) {
+	publisher := NewCRLPublisher(repoFactory)
+	if err := publisher.Start(); err != nil {
+		return nil, err
+	}
+
+	publisherMutex.Lock()
+	defaultPublisher = publisher
+	publisherMutex.Unlock()
+	return publisher, nil
+}
+
+// GetCRLPublisher 获取全局证书吊销列表发布器，未初始化时返回nil
+func GetCRLPublisher() *CRLPublisher {
+	publisherMutex.RLock()
+	defer publisherMutex.RUnlock()
+	return defaultPublisher
+}
+
+// Start 立即生成一次CRL并启动后台发布
+// 内置CA不可用时返回错误；首次生成失败只记录日志，由后台发布重试
+func (p *CRLPublisher) Start() error {
+	if _, err := pki.GetCA(); err != nil {
+		return err
+	}
+	if err := p.Publish(); err != nil {
+		log.Printf("首次发布证书吊销列表失败: %v\n", err)
+	}
+
+	p.wg.Add(1)
+	go p.run()
+	return nil
+}
+
+// Stop 停止后台发布
+func (p *CRLPublisher) Stop() {
+	close(p.stopChan)
+	p.wg.Wait()
+}
+
+// Trigger 请求尽快重新生成CRL，不等待生成完成
+func (p *CRLPublisher) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// run 后台发布循环
+func (p *CRLPublisher) run() {
+	defer p.wg.Done()
+
+	var tick <-chan time.Time
+	if p.cfg.CRL.UpdateInterval > 0 {
+		ticker := time.NewTicker(time.Duration(p.cfg.CRL.UpdateInterval) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-p.trigger:
+		case <-tick:
+		}
+		if err := p.Publish(); err != nil {
+			log.Printf("发布证书吊销列表失败: %v\n", err)
+		}
+	}
+}
+
+// Publish 生成CRL并保存到本地，开启远程发布时推送到远程存储
+// CRL只包含内置CA签发且尚未过期的证书的吊销记录，序号在上一次生成的CRL基础上递增
+func (p *CRLPublisher) Publish() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ca, err := pki.GetCA()
+	if err != nil {
+		return err
+	}
+
+	revocations, err := p.revocationRepo.FindByIssuer(ca.Certificate().Subject.String())
+	if err != nil {
+		return fmt.Errorf("查询吊销记录失败: %w", err)
+	}
+
+	now := time.Now()
+	revoked := make([]pki.RevokedCert, 0, len(revocations))
+	for _, r := range revocations {
+		if r.NotAfter != nil && r.NotAfter.Before(now) {
+			continue
+		}
+		revoked = append(revoked, pki.RevokedCert{
+			SerialNumber: r.SerialNumber,
+			RevokedAt:    r.RevokedAt,
+			ReasonCode:   r.ReasonCode,
+		})
+	}
+
+	number := big.NewInt(1)
+	if data, err := os.ReadFile(p.cfg.CRL.Path); err == nil {
+		if previous, err := pki.ParseCRL(data); err == nil && previous.Number != nil {
+			number.Add(previous.Number, big.NewInt(1))
+		}
+	}
+
+	nextUpdate := now.Add(time.Duration(p.cfg.CRL.Validity) * time.Second)
+	der, err := ca.CreateCRL(revoked, number, nextUpdate)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(p.cfg.CRL.Path, der); err != nil {
+		return fmt.Errorf("保存证书吊销列表失败: %w", err)
+	}
+
+	if p.cfg.DebugLevel == "true" {
+		log.Printf("证书吊销列表已生成，序号: %s，吊销证书数量: %d，下次更新: %s\n",
+			number.String(), len(revoked), nextUpdate.Format(time.RFC3339))
+	}
+
+	if p.cfg.CRL.RemoteEnabled {
+		if err := p.upload(); err != nil {
+			p.alerter.Alert(&alert.Alert{
+				Level:     alert.AlertLevelError,
+				Type:      alert.AlertTypeCRLPublish,
+				Message:   "推送证书吊销列表到远程存储失败",
+				Error:     err,
+				Module:    "CRLPublisher",
+				Timestamp: now,
+			})
+			return err
+		}
+	}
+	return nil
+}
+
+// upload 将本地CRL推送到远程存储
+func (p *CRLPublisher) upload() error {
+	transporter, err := transfer.NewFileTransporter(transfer.TransporterType(p.cfg.ConfigManager.Storage.Type), p.cfg)
+	if err != nil {
+		return err
+	}
+	defer transporter.Close()
+
+	return transporter.Upload(p.cfg.CRL.Path, p.cfg.CRL.RemotePath)
+}
+
+// writeFileAtomic 先写入临时文件再替换，避免读取到写了一半的CRL
+func writeFileAtomic(path string, data []byte) error {
+	if err := fileutil.EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
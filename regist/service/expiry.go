@@ -0,0 +1,222 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/alert"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+	"gin-server/regist/pki"
+	"gin-server/regist/store"
+)
+
+// CertExpiryScanner 证书有效期扫描器
+// 定期检查certs表中已绑定证书的剩余有效期，首次低于告警阈值或已过期时产生告警
+type CertExpiryScanner struct {
+	certRepo       repositories.CertRepository
+	revocationRepo repositories.CertRevocationRepository
+	alerter        alert.Alerter
+	cfg            *config.Config
+	stopChan       chan struct{}
+	wg             sync.WaitGroup
+}
+
+// NewCertExpiryScanner 创建证书有效期扫描器
+func NewCertExpiryScanner(repoFactory repositories.RepositoryFactory) *CertExpiryScanner {
+	return &CertExpiryScanner{
+		certRepo:       repoFactory.GetCertRepository(),
+		revocationRepo: repoFactory.GetCertRevocationRepository(),
+		alerter:        alert.GetDefaultAlerter(),
+		cfg:            config.GetConfig(),
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Start 立即扫描一次并启动定期扫描
+func (s *CertExpiryScanner) Start() {
+	s.Scan()
+
+	interval := time.Duration(s.cfg.CertExpiry.ScanInterval) * time.Second
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+				s.Scan()
+			}
+		}
+	}()
+
+	if s.cfg.DebugLevel == "true" {
+		log.Printf("证书有效期扫描器已启动，扫描间隔: %v，告警阈值: %v天\n", interval, s.cfg.CertExpiry.AlertThresholds)
+	}
+}
+
+// Stop 停止扫描器
+func (s *CertExpiryScanner) Stop() {
+	close(s.stopChan)
+	s.wg.Wait()
+}
+
+// Scan 执行一次扫描
+func (s *CertExpiryScanner) Scan() {
+	s.backfillExpiry()
+
+	now := time.Now()
+	thresholds := sortedThresholds(s.cfg.CertExpiry.AlertThresholds)
+	before := now
+	if len(thresholds) > 0 {
+		before = now.Add(time.Duration(thresholds[0]) * 24 * time.Hour)
+	}
+
+	certs, err := s.certRepo.FindExpiringBefore("", before)
+	if err != nil {
+		log.Printf("查询即将过期的证书失败: %v\n", err)
+		return
+	}
+
+	for i := range certs {
+		cert := &certs[i]
+		level := expiryAlertLevel(thresholds, *cert.NotAfter, now)
+		if !needExpiryAlert(cert.ExpiryAlertDays, level) {
+			continue
+		}
+
+		// 已吊销的证书不再提醒
+		if cert.Fingerprint != "" {
+			if revoked, err := s.revocationRepo.IsRevoked(cert.Fingerprint); err == nil && revoked {
+				continue
+			}
+		}
+
+		s.alert(cert, level, now)
+		if err := s.certRepo.UpdateExpiryAlert(cert.ID, level); err != nil {
+			log.Printf("更新证书 %d 过期告警状态失败: %v\n", cert.ID, err)
+		}
+	}
+}
+
+// alert 产生证书过期告警
+func (s *CertExpiryScanner) alert(cert *models.Cert, level int, now time.Time) {
+	notAfter := cert.NotAfter.Format("2006-01-02 15:04:05")
+	a := &alert.Alert{
+		Level:     alert.AlertLevelWarning,
+		Type:      alert.AlertTypeCertExpiry,
+		Message:   fmt.Sprintf("%s %s的证书将在%d天内过期，过期时间: %s", cert.EntityType, cert.EntityID, level, notAfter),
+		Module:    "CertExpiryScanner",
+		Timestamp: now,
+	}
+	if level == models.CertExpiryAlertExpired {
+		a.Level = alert.AlertLevelError
+		a.Message = fmt.Sprintf("%s %s的证书已过期，过期时间: %s", cert.EntityType, cert.EntityID, notAfter)
+	}
+	s.alerter.Alert(a)
+}
+
+// backfillExpiry 为未记录过期时间的历史证书补充解析证书字段
+func (s *CertExpiryScanner) backfillExpiry() {
+	certs, err := s.certRepo.FindWithoutExpiry()
+	if err != nil {
+		log.Printf("查询未记录过期时间的证书失败: %v\n", err)
+		return
+	}
+
+	certStore, err := store.Default()
+	if err != nil {
+		log.Printf("获取证书存储失败: %v\n", err)
+		return
+	}
+
+	for i := range certs {
+		cert := &certs[i]
+		data, err := certStore.GetCert(cert)
+		if err != nil {
+			if s.cfg.DebugLevel == "true" {
+				log.Printf("读取证书文件 %s 失败: %v\n", cert.CertPath, err)
+			}
+			continue
+		}
+		chain, err := pki.ParseCertificateChain(data)
+		if err != nil {
+			if s.cfg.DebugLevel == "true" {
+				log.Printf("解析证书文件 %s 失败: %v\n", cert.CertPath, err)
+			}
+			continue
+		}
+
+		info := pki.NewCertInfo(chain[0])
+		cert.Subject = info.Subject
+		cert.Issuer = info.Issuer
+		cert.SerialNumber = info.SerialNumber
+		cert.Fingerprint = info.Fingerprint
+		cert.NotBefore = &info.NotBefore
+		cert.NotAfter = &info.NotAfter
+		cert.CertKeyType = info.KeyType
+		cert.ChainLength = len(chain)
+		if err := s.certRepo.Update(cert); err != nil {
+			log.Printf("更新证书 %d 的证书字段失败: %v\n", cert.ID, err)
+		}
+	}
+}
+
+// RemainingDays 计算证书剩余有效天数，不足一天按0天计，已过期时为负数
+func RemainingDays(notAfter, now time.Time) int {
+	return int(math.Floor(notAfter.Sub(now).Hours() / 24))
+}
+
+// sortedThresholds 返回按从大到小排序的有效告警阈值
+func sortedThresholds(thresholds []int) []int {
+	sorted := make([]int, 0, len(thresholds))
+	for _, t := range thresholds {
+		if t > 0 {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	return sorted
+}
+
+// expiryAlertLevel 计算证书当前所处的告警级别
+// 已过期返回CertExpiryAlertExpired，否则返回剩余有效期不超过的最小阈值，未达到任何阈值时返回0
+func expiryAlertLevel(thresholds []int, notAfter, now time.Time) int {
+	if now.After(notAfter) {
+		return models.CertExpiryAlertExpired
+	}
+
+	level := models.CertExpiryAlertNone
+	remaining := notAfter.Sub(now)
+	for _, t := range thresholds {
+		if remaining <= time.Duration(t)*24*time.Hour {
+			level = t
+		}
+	}
+	return level
+}
+
+// needExpiryAlert 判断是否需要告警，每个阈值和过期状态只告警一次
+func needExpiryAlert(alerted, level int) bool {
+	switch {
+	case level == models.CertExpiryAlertNone:
+		return false
+	case alerted == models.CertExpiryAlertExpired:
+		return false
+	case level == models.CertExpiryAlertExpired:
+		return true
+	case alerted == models.CertExpiryAlertNone:
+		return true
+	default:
+		return level < alerted
+	}
+}
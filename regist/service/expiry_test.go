@@ -0,0 +1,69 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"gin-server/database/models"
+)
+
+func TestExpiryAlertLevel(t *testing.T) {
+	now := time.Now()
+	thresholds := sortedThresholds([]int{1, 30, 7, 0})
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     int
+	}{
+		{"剩余60天", now.Add(60 * 24 * time.Hour), models.CertExpiryAlertNone},
+		{"剩余不足30天", now.Add(30*24*time.Hour - time.Minute), 30},
+		{"剩余10天", now.Add(10 * 24 * time.Hour), 30},
+		{"剩余3天", now.Add(3 * 24 * time.Hour), 7},
+		{"剩余12小时", now.Add(12 * time.Hour), 1},
+		{"已过期", now.Add(-time.Minute), models.CertExpiryAlertExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiryAlertLevel(thresholds, tt.notAfter, now); got != tt.want {
+				t.Errorf("expiryAlertLevel() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNeedExpiryAlert(t *testing.T) {
+	tests := []struct {
+		name    string
+		alerted int
+		level   int
+		want    bool
+	}{
+		{"未达到阈值", models.CertExpiryAlertNone, models.CertExpiryAlertNone, false},
+		{"首次达到阈值", models.CertExpiryAlertNone, 30, true},
+		{"同一阈值不重复告警", 30, 30, false},
+		{"进入更小的阈值", 30, 7, true},
+		{"首次过期", 1, models.CertExpiryAlertExpired, true},
+		{"过期不重复告警", models.CertExpiryAlertExpired, models.CertExpiryAlertExpired, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needExpiryAlert(tt.alerted, tt.level); got != tt.want {
+				t.Errorf("needExpiryAlert(%d, %d) = %v, want %v", tt.alerted, tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemainingDays(t *testing.T) {
+	now := time.Now()
+	if got := RemainingDays(now.Add(36*time.Hour), now); got != 1 {
+		t.Errorf("剩余36小时 = %d天, want 1", got)
+	}
+	if got := RemainingDays(now.Add(12*time.Hour), now); got != 0 {
+		t.Errorf("剩余12小时 = %d天, want 0", got)
+	}
+	if got := RemainingDays(now.Add(-12*time.Hour), now); got != -1 {
+		t.Errorf("过期12小时 = %d天, want -1", got)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database/models"
+
+	"gorm.io/gorm"
+)
+
+// defaultMissedThreshold 未配置HEARTBEAT_MISSED_THRESHOLD时判定离线的未上报心跳次数
+const defaultMissedThreshold = 3
+
+// ErrDeviceDisabled 冻结或注销的设备不能上报心跳
+var ErrDeviceDisabled = errors.New("设备已冻结或注销")
+
+// HeartbeatSample 设备心跳上报的运行状态
+type HeartbeatSample struct {
+	CPUUsage    int       // CPU使用率（%）
+	MemoryUsage int       // 内存使用率（%）
+	Uptime      int       // 设备运行时长（秒）
+	At          time.Time // 收到心跳的时间
+}
+
+// HeartbeatTimeout 返回判定设备离线的心跳超时时长，即心跳间隔乘以允许未上报的次数
+func HeartbeatTimeout(cfg *config.HeartbeatConfig) time.Duration {
+	missed := cfg.MissedThreshold
+	if missed <= 0 {
+		missed = defaultMissedThreshold
+	}
+	return time.Duration(cfg.Interval*missed) * time.Second
+}
+
+// ApplyHeartbeat 根据心跳更新设备的性能峰值、在线时长和状态，返回需要保存的字段
+// 峰值按peakWindow对齐的窗口统计，进入新窗口时以本次上报值重新开始，同一窗口内在数据库中取较大值，
+// 避免同一设备并发的心跳按各自读取的旧峰值覆盖较大的峰值；
+// 设备在线且两次心跳间隔不超过timeout时累加在线时长，设备期间重启过时只累加本次运行时长
+func ApplyHeartbeat(device *models.Device, sample HeartbeatSample, timeout, peakWindow time.Duration) (map[string]interface{}, error) {
+	if device.DeviceStatus == models.DeviceStatusFrozen || device.DeviceStatus == models.DeviceStatusCancelled {
+		return nil, ErrDeviceDisabled
+	}
+
+	fields := map[string]interface{}{
+		"device_status":     models.DeviceStatusOnline,
+		"last_heartbeat_at": sample.At,
+		"uptime":            sample.Uptime,
+	}
+
+	// 峰值统计
+	windowStart := sample.At
+	if peakWindow > 0 {
+		windowStart = sample.At.Truncate(peakWindow)
+	}
+	if device.PeakWindowStart == nil || !device.PeakWindowStart.Equal(windowStart) {
+		device.PeakCPUUsage = sample.CPUUsage
+		device.PeakMemoryUsage = sample.MemoryUsage
+		device.PeakWindowStart = &windowStart
+		fields["peak_window_start"] = windowStart
+		fields["peak_cpu_usage"] = sample.CPUUsage
+		fields["peak_memory_usage"] = sample.MemoryUsage
+	} else {
+		device.PeakCPUUsage = max(device.PeakCPUUsage, sample.CPUUsage)
+		device.PeakMemoryUsage = max(device.PeakMemoryUsage, sample.MemoryUsage)
+		fields["peak_cpu_usage"] = gorm.Expr("GREATEST(peak_cpu_usage, ?)", sample.CPUUsage)
+		fields["peak_memory_usage"] = gorm.Expr("GREATEST(peak_memory_usage, ?)", sample.MemoryUsage)
+	}
+
+	// 在线时长
+	if elapsed := onlineElapsed(device, sample, timeout); elapsed > 0 {
+		device.OnlineDuration += elapsed
+		fields["online_duration"] = gorm.Expr("online_duration + ?", elapsed)
+	}
+
+	device.DeviceStatus = models.DeviceStatusOnline
+	device.LastHeartbeatAt = &sample.At
+	device.Uptime = sample.Uptime
+	return fields, nil
+}
+
+// onlineElapsed 计算两次心跳之间可计入在线时长的秒数
+func onlineElapsed(device *models.Device, sample HeartbeatSample, timeout time.Duration) int {
+	if device.DeviceStatus != models.DeviceStatusOnline || device.LastHeartbeatAt == nil {
+		return 0
+	}
+	elapsed := sample.At.Sub(*device.LastHeartbeatAt)
+	if elapsed <= 0 || (timeout > 0 && elapsed > timeout) {
+		return 0
+	}
+	seconds := int(elapsed / time.Second)
+	// 运行时长小于心跳间隔说明设备期间重启过
+	if sample.Uptime > 0 && sample.Uptime < seconds {
+		seconds = sample.Uptime
+	}
+	return seconds
+}
+
+// DeviceRecovered 判断设备是否在因心跳超时被判定离线后重新上报心跳
+// 需要在ApplyHeartbeat之前调用
+func DeviceRecovered(device *models.Device) bool {
+	return device.OffLineTimeStamp != nil && device.LastHeartbeatAt != nil &&
+		device.OffLineTimeStamp.After(*device.LastHeartbeatAt)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database/models"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestApplyHeartbeatPeaks(t *testing.T) {
+	timeout := 90 * time.Second
+	window := 10 * time.Minute
+	start := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	device := &models.Device{DeviceStatus: models.DeviceStatusOffline}
+
+	samples := []struct {
+		offset           time.Duration
+		cpu, memory      int
+		wantCPU, wantMem int
+		newWindow        bool
+	}{
+		{0, 40, 50, 40, 50, true},
+		{30 * time.Second, 70, 30, 70, 50, false},
+		{60 * time.Second, 20, 60, 70, 60, false},
+		// 进入新的统计窗口，峰值重新统计
+		{10 * time.Minute, 10, 20, 10, 20, true},
+	}
+	for i, s := range samples {
+		fields, err := ApplyHeartbeat(device, HeartbeatSample{
+			CPUUsage: s.cpu, MemoryUsage: s.memory, Uptime: 3600, At: start.Add(s.offset),
+		}, timeout, window)
+		if err != nil {
+			t.Fatalf("第%d次心跳 ApplyHeartbeat() error = %v", i+1, err)
+		}
+		if device.PeakCPUUsage != s.wantCPU || device.PeakMemoryUsage != s.wantMem {
+			t.Errorf("第%d次心跳后峰值 = %d/%d, want %d/%d", i+1,
+				device.PeakCPUUsage, device.PeakMemoryUsage, s.wantCPU, s.wantMem)
+		}
+
+		// 新窗口直接写入本次上报值，同一窗口内由数据库取较大值
+		if s.newWindow {
+			if fields["peak_cpu_usage"] != s.cpu || fields["peak_memory_usage"] != s.memory {
+				t.Errorf("第%d次心跳保存的峰值 = %v/%v, want %d/%d", i+1,
+					fields["peak_cpu_usage"], fields["peak_memory_usage"], s.cpu, s.memory)
+			}
+			continue
+		}
+		for column, value := range map[string]int{"peak_cpu_usage": s.cpu, "peak_memory_usage": s.memory} {
+			expr, ok := fields[column].(clause.Expr)
+			if !ok || expr.SQL != "GREATEST("+column+", ?)" || len(expr.Vars) != 1 || expr.Vars[0] != value {
+				t.Errorf("第%d次心跳保存的%s = %#v, want GREATEST(%s, %d)", i+1, column, fields[column], column, value)
+			}
+		}
+	}
+	if device.DeviceStatus != models.DeviceStatusOnline {
+		t.Errorf("心跳后设备状态 = %d, want 在线", device.DeviceStatus)
+	}
+}
+
+func TestApplyHeartbeatOnlineDuration(t *testing.T) {
+	timeout := 90 * time.Second
+	start := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	device := &models.Device{DeviceStatus: models.DeviceStatusOffline, OnlineDuration: 100}
+
+	beat := func(offset time.Duration, uptime int) {
+		t.Helper()
+		if _, err := ApplyHeartbeat(device, HeartbeatSample{Uptime: uptime, At: start.Add(offset)}, timeout, time.Minute); err != nil {
+			t.Fatalf("ApplyHeartbeat() error = %v", err)
+		}
+	}
+
+	// 离线设备的首次心跳不累加在线时长
+	beat(0, 1000)
+	if device.OnlineDuration != 100 {
+		t.Errorf("首次心跳后在线时长 = %d, want 100", device.OnlineDuration)
+	}
+	beat(30*time.Second, 1030)
+	if device.OnlineDuration != 130 {
+		t.Errorf("正常心跳后在线时长 = %d, want 130", device.OnlineDuration)
+	}
+	// 期间重启，只累加本次运行时长
+	beat(60*time.Second, 10)
+	if device.OnlineDuration != 140 {
+		t.Errorf("重启后在线时长 = %d, want 140", device.OnlineDuration)
+	}
+	// 间隔超过心跳超时时长，视为期间离线
+	beat(10*time.Minute, 600)
+	if device.OnlineDuration != 140 {
+		t.Errorf("长时间未上报后在线时长 = %d, want 140", device.OnlineDuration)
+	}
+}
+
+func TestApplyHeartbeatDisabledDevice(t *testing.T) {
+	for _, status := range []int{models.DeviceStatusFrozen, models.DeviceStatusCancelled} {
+		device := &models.Device{DeviceStatus: status}
+		if _, err := ApplyHeartbeat(device, HeartbeatSample{At: time.Now()}, time.Minute, time.Minute); !errors.Is(err, ErrDeviceDisabled) {
+			t.Errorf("状态%d的设备 ApplyHeartbeat() error = %v, want ErrDeviceDisabled", status, err)
+		}
+	}
+}
+
+func TestHeartbeatTimeout(t *testing.T) {
+	tests := []struct {
+		cfg  config.HeartbeatConfig
+		want time.Duration
+	}{
+		{config.HeartbeatConfig{Interval: 30, MissedThreshold: 3}, 90 * time.Second},
+		{config.HeartbeatConfig{Interval: 10, MissedThreshold: 5}, 50 * time.Second},
+		{config.HeartbeatConfig{Interval: 30}, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := HeartbeatTimeout(&tt.cfg); got != tt.want {
+			t.Errorf("HeartbeatTimeout(%+v) = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestDeviceRecovered(t *testing.T) {
+	last := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	offline := last.Add(2 * time.Minute)
+	earlier := last.Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		device models.Device
+		want   bool
+	}{
+		{"从未离线", models.Device{LastHeartbeatAt: &last}, false},
+		{"离线后未上报", models.Device{LastHeartbeatAt: &last, OffLineTimeStamp: &offline}, true},
+		{"离线后已上报", models.Device{LastHeartbeatAt: &last, OffLineTimeStamp: &earlier}, false},
+		{"从未上报心跳", models.Device{OffLineTimeStamp: &offline}, false},
+	}
+	for _, tt := range tests {
+		if got := DeviceRecovered(&tt.device); got != tt.want {
+			t.Errorf("%s: DeviceRecovered() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+
+	"gin-server/database/models"
+)
+
+// 设备层级校验错误
+var (
+	ErrInvalidDeviceType       = errors.New("无效的设备类型")
+	ErrSecurityDeviceSuperior  = errors.New("安全接入管理设备的上级设备ID必须为0")
+	ErrGatewaySuperiorNotFound = errors.New("网关设备的上级设备不存在")
+	ErrGatewaySuperiorType     = errors.New("网关设备的上级设备必须是安全接入管理设备")
+	ErrUserGatewayNotFound     = errors.New("所属网关设备不存在")
+	ErrUserGatewayType         = errors.New("用户所属设备必须是网关设备")
+	ErrDeviceHasSubordinates   = errors.New("存在下级设备，不能改为网关设备")
+	ErrGatewayHasUsers         = errors.New("存在所属用户，不能改为安全接入管理设备")
+)
+
+// ValidateDeviceHierarchy 校验设备类型与上级设备的组合
+// 安全接入管理设备的上级必须为0，网关设备的上级必须是已存在的安全接入管理设备；superior为nil表示上级设备不存在
+func ValidateDeviceHierarchy(deviceType, superiorID int, superior *models.Device) error {
+	switch {
+	case deviceType == models.DeviceTypeSecurityMgmt:
+		if superiorID != 0 {
+			return ErrSecurityDeviceSuperior
+		}
+	case models.IsGatewayType(deviceType):
+		if superiorID == 0 || superior == nil {
+			return ErrGatewaySuperiorNotFound
+		}
+		if superior.DeviceType != models.DeviceTypeSecurityMgmt {
+			return ErrGatewaySuperiorType
+		}
+	default:
+		return ErrInvalidDeviceType
+	}
+	return nil
+}
+
+// ValidateDeviceTypeChange 校验修改设备类型后已有的下级设备和所属用户是否仍满足层级关系
+func ValidateDeviceTypeChange(oldType, newType int, subordinates, users int) error {
+	if oldType == newType {
+		return nil
+	}
+	if models.IsGatewayType(newType) && subordinates > 0 {
+		return ErrDeviceHasSubordinates
+	}
+	if newType == models.DeviceTypeSecurityMgmt && users > 0 {
+		return ErrGatewayHasUsers
+	}
+	return nil
+}
+
+// ValidateUserGateway 校验用户所属的设备，gateway为nil表示设备不存在
+func ValidateUserGateway(gateway *models.Device) error {
+	if gateway == nil {
+		return ErrUserGatewayNotFound
+	}
+	if !models.IsGatewayType(gateway.DeviceType) {
+		return ErrUserGatewayType
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"gin-server/database/models"
+)
+
+func TestValidateDeviceHierarchy(t *testing.T) {
+	security := &models.Device{DeviceID: 1000, DeviceType: models.DeviceTypeSecurityMgmt}
+	gateway := &models.Device{DeviceID: 2001, DeviceType: models.DeviceTypeGatewayA}
+
+	tests := []struct {
+		name       string
+		deviceType int
+		superiorID int
+		superior   *models.Device
+		want       error
+	}{
+		{"安全接入管理设备", models.DeviceTypeSecurityMgmt, 0, nil, nil},
+		{"安全接入管理设备有上级", models.DeviceTypeSecurityMgmt, 1000, security, ErrSecurityDeviceSuperior},
+		{"网关设备", models.DeviceTypeGatewayB, 1000, security, nil},
+		{"网关设备没有上级", models.DeviceTypeGatewayA, 0, nil, ErrGatewaySuperiorNotFound},
+		{"网关设备上级不存在", models.DeviceTypeGatewayA, 1000, nil, ErrGatewaySuperiorNotFound},
+		{"网关设备上级是网关", models.DeviceTypeGatewayC, 2001, gateway, ErrGatewaySuperiorType},
+		{"无效的设备类型", 5, 0, nil, ErrInvalidDeviceType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDeviceHierarchy(tt.deviceType, tt.superiorID, tt.superior); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateDeviceHierarchy() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDeviceTypeChange(t *testing.T) {
+	tests := []struct {
+		name         string
+		oldType      int
+		newType      int
+		subordinates int
+		users        int
+		want         error
+	}{
+		{"类型不变", models.DeviceTypeSecurityMgmt, models.DeviceTypeSecurityMgmt, 3, 0, nil},
+		{"网关之间修改", models.DeviceTypeGatewayA, models.DeviceTypeGatewayB, 0, 5, nil},
+		{"有下级设备改为网关", models.DeviceTypeSecurityMgmt, models.DeviceTypeGatewayA, 1, 0, ErrDeviceHasSubordinates},
+		{"没有下级设备改为网关", models.DeviceTypeSecurityMgmt, models.DeviceTypeGatewayA, 0, 0, nil},
+		{"有用户改为安全接入管理设备", models.DeviceTypeGatewayA, models.DeviceTypeSecurityMgmt, 0, 2, ErrGatewayHasUsers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDeviceTypeChange(tt.oldType, tt.newType, tt.subordinates, tt.users); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateDeviceTypeChange() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateUserGateway(t *testing.T) {
+	if err := ValidateUserGateway(&models.Device{DeviceType: models.DeviceTypeGatewayC}); err != nil {
+		t.Errorf("网关设备 ValidateUserGateway() = %v", err)
+	}
+	if err := ValidateUserGateway(nil); !errors.Is(err, ErrUserGatewayNotFound) {
+		t.Errorf("设备不存在 ValidateUserGateway() = %v", err)
+	}
+	if err := ValidateUserGateway(&models.Device{DeviceType: models.DeviceTypeSecurityMgmt}); !errors.Is(err, ErrUserGatewayType) {
+		t.Errorf("安全接入管理设备 ValidateUserGateway() = %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+)
+
+// 用户生命周期操作
+const (
+	UserActionFreeze     = "freeze"     // 冻结
+	UserActionUnfreeze   = "unfreeze"   // 解冻
+	UserActionCancel     = "cancel"     // 注销
+	UserActionReactivate = "reactivate" // 重新激活已注销的用户
+)
+
+// ActorSystem 系统自动变更用户状态时记录的操作人
+const ActorSystem = "system"
+
+// 用户状态变更事件代码
+const (
+	EventCodeUserFrozen      = "USER_FROZEN"
+	EventCodeUserUnfrozen    = "USER_UNFROZEN"
+	EventCodeUserCancelled   = "USER_CANCELLED"
+	EventCodeUserReactivated = "USER_REACTIVATED"
+	EventCodeUserOnline      = "USER_ONLINE"
+	EventCodeUserOffline     = "USER_OFFLINE"
+)
+
+// 用户生命周期错误
+var (
+	ErrUnknownUserAction     = errors.New("未知的用户生命周期操作")
+	ErrInvalidUserTransition = errors.New("不允许的用户状态变更")
+)
+
+// userTransitions 允许的用户状态变更，未设置状态的用户按离线处理
+// 冻结的用户只能解冻或注销，注销的用户只能重新激活
+var userTransitions = map[int][]int{
+	models.UserStatusOnline:    {models.UserStatusOffline, models.UserStatusFrozen, models.UserStatusCancelled},
+	models.UserStatusOffline:   {models.UserStatusOnline, models.UserStatusFrozen, models.UserStatusCancelled},
+	models.UserStatusFrozen:    {models.UserStatusOffline, models.UserStatusCancelled},
+	models.UserStatusCancelled: {models.UserStatusOffline},
+}
+
+// userActions 生命周期操作要求的原状态和目标状态，原状态为空表示不限
+var userActions = map[string]struct {
+	from []int
+	to   int
+}{
+	UserActionFreeze:     {to: models.UserStatusFrozen},
+	UserActionUnfreeze:   {from: []int{models.UserStatusFrozen}, to: models.UserStatusOffline},
+	UserActionCancel:     {to: models.UserStatusCancelled},
+	UserActionReactivate: {from: []int{models.UserStatusCancelled}, to: models.UserStatusOffline},
+}
+
+// UserTransitionError 用户状态变更不被允许
+type UserTransitionError struct {
+	From int
+	To   int
+}
+
+// Error 实现error接口
+func (e *UserTransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", ErrInvalidUserTransition.Error(), UserStatusName(e.From), UserStatusName(e.To))
+}
+
+// Unwrap 支持errors.Is(err, ErrInvalidUserTransition)
+func (e *UserTransitionError) Unwrap() error {
+	return ErrInvalidUserTransition
+}
+
+// UserStatusName 返回用户状态的名称
+func UserStatusName(status int) string {
+	switch status {
+	case models.UserStatusOnline:
+		return "在线"
+	case models.UserStatusOffline:
+		return "离线"
+	case models.UserStatusFrozen:
+		return "冻结"
+	case models.UserStatusCancelled:
+		return "注销"
+	default:
+		return fmt.Sprintf("未知(%d)", status)
+	}
+}
+
+// CurrentUserStatus 返回用户的当前状态，未设置时按离线处理
+func CurrentUserStatus(user *models.User) int {
+	if user.Status == nil {
+		return models.UserStatusOffline
+	}
+	return *user.Status
+}
+
+// CanTransitionUser 判断用户状态能否从from变更为to
+func CanTransitionUser(from, to int) bool {
+	for _, status := range userTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UserActionTarget 校验生命周期操作能否作用于处于from状态的用户，返回目标状态
+func UserActionTarget(action string, from int) (int, error) {
+	rule, ok := userActions[action]
+	if !ok {
+		return 0, ErrUnknownUserAction
+	}
+	if len(rule.from) > 0 && !containsStatus(rule.from, from) {
+		return 0, &UserTransitionError{From: from, To: rule.to}
+	}
+	if !CanTransitionUser(from, rule.to) {
+		return 0, &UserTransitionError{From: from, To: rule.to}
+	}
+	return rule.to, nil
+}
+
+// userTransitionEventCode 返回状态变更对应的事件代码
+func userTransitionEventCode(from, to int) string {
+	switch {
+	case to == models.UserStatusFrozen:
+		return EventCodeUserFrozen
+	case to == models.UserStatusCancelled:
+		return EventCodeUserCancelled
+	case from == models.UserStatusFrozen:
+		return EventCodeUserUnfrozen
+	case from == models.UserStatusCancelled:
+		return EventCodeUserReactivated
+	case to == models.UserStatusOnline:
+		return EventCodeUserOnline
+	default:
+		return EventCodeUserOffline
+	}
+}
+
+// UserTransition 用户状态变更请求
+type UserTransition struct {
+	To     int       // 目标状态
+	Reason string    // 变更原因
+	Actor  string    // 操作人，为空时记为system
+	At     time.Time // 变更时间，为空时取当前时间
+	// Expiring 冻结是否按USER_FREEZE_DURATION自动到期，仅对冻结有效
+	// 连续非法登录触发的冻结会自动到期，管理员冻结只能由管理员解冻
+	Expiring bool
+}
+
+// TransitionUser 按生命周期规则变更用户状态，记录状态变更历史并生成安全事件
+// repoFactory可以是事务中的仓库工厂；吊销证书等附带操作由调用方在同一事务中完成
+func TransitionUser(repoFactory repositories.RepositoryFactory, user *models.User, t UserTransition) (*models.UserStatusHistory, error) {
+	from := CurrentUserStatus(user)
+	if !CanTransitionUser(from, t.To) {
+		return nil, &UserTransitionError{From: from, To: t.To}
+	}
+	if t.At.IsZero() {
+		t.At = time.Now()
+	}
+	if t.Actor == "" {
+		t.Actor = ActorSystem
+	}
+
+	var frozenAt *time.Time
+	if t.To == models.UserStatusFrozen && t.Expiring {
+		frozenAt = &t.At
+	}
+	userRepo := repoFactory.GetUserRepository()
+	if err := userRepo.UpdateStatus(user.ID, t.To, frozenAt); err != nil {
+		return nil, fmt.Errorf("更新用户状态失败: %w", err)
+	}
+	// 解冻或重新激活后重新累计非法登录次数
+	if from == models.UserStatusFrozen || from == models.UserStatusCancelled {
+		if err := userRepo.ResetIllegalLoginTimes(user.ID); err != nil {
+			return nil, fmt.Errorf("清零非法登录次数失败: %w", err)
+		}
+		zero := 0
+		user.IllegalLoginTimes = &zero
+	}
+
+	history := &models.UserStatusHistory{
+		UserID:     user.UserID,
+		FromStatus: from,
+		ToStatus:   t.To,
+		Reason:     t.Reason,
+		Actor:      t.Actor,
+		ChangedAt:  t.At,
+	}
+	if err := repoFactory.GetUserStatusHistoryRepository().Create(history); err != nil {
+		return nil, fmt.Errorf("保存状态变更记录失败: %w", err)
+	}
+
+	desc := fmt.Sprintf("用户 %s(%d) 状态由%s变更为%s，操作人: %s", user.Username, user.UserID,
+		UserStatusName(from), UserStatusName(t.To), t.Actor)
+	if t.Reason != "" {
+		desc += "，原因: " + t.Reason
+	}
+	if len([]rune(desc)) > 255 {
+		desc = string([]rune(desc)[:255])
+	}
+	event := &models.Event{
+		EventID:   time.Now().UnixNano(),
+		DeviceID:  user.GatewayDeviceID,
+		EventTime: t.At,
+		EventType: models.EventTypeSecurity,
+		EventCode: userTransitionEventCode(from, t.To),
+		EventDesc: desc,
+	}
+	if err := repoFactory.GetEventRepository().Create(event); err != nil {
+		return nil, fmt.Errorf("保存状态变更事件失败: %w", err)
+	}
+
+	status := t.To
+	user.Status = &status
+	user.FrozenAt = frozenAt
+	log.Println(desc)
+	return history, nil
+}
+
+// containsStatus 判断状态列表中是否包含指定状态
+func containsStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"gin-server/database/models"
+)
+
+func TestUserActionTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		from    int
+		want    int
+		wantErr error
+	}{
+		{"冻结在线用户", UserActionFreeze, models.UserStatusOnline, models.UserStatusFrozen, nil},
+		{"冻结离线用户", UserActionFreeze, models.UserStatusOffline, models.UserStatusFrozen, nil},
+		{"重复冻结", UserActionFreeze, models.UserStatusFrozen, 0, ErrInvalidUserTransition},
+		{"冻结已注销用户", UserActionFreeze, models.UserStatusCancelled, 0, ErrInvalidUserTransition},
+		{"解冻", UserActionUnfreeze, models.UserStatusFrozen, models.UserStatusOffline, nil},
+		{"解冻未冻结用户", UserActionUnfreeze, models.UserStatusOffline, 0, ErrInvalidUserTransition},
+		{"注销冻结用户", UserActionCancel, models.UserStatusFrozen, models.UserStatusCancelled, nil},
+		{"重复注销", UserActionCancel, models.UserStatusCancelled, 0, ErrInvalidUserTransition},
+		{"重新激活", UserActionReactivate, models.UserStatusCancelled, models.UserStatusOffline, nil},
+		{"重新激活未注销用户", UserActionReactivate, models.UserStatusFrozen, 0, ErrInvalidUserTransition},
+		{"未知操作", "delete", models.UserStatusOffline, 0, ErrUnknownUserAction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UserActionTarget(tt.action, tt.from)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("UserActionTarget() = %d, %v, want %d, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCanTransitionUser(t *testing.T) {
+	// 注销的用户只能重新激活为离线，不能直接上线或冻结
+	for _, to := range []int{models.UserStatusOnline, models.UserStatusFrozen} {
+		if CanTransitionUser(models.UserStatusCancelled, to) {
+			t.Errorf("CanTransitionUser(注销, %s) = true, want false", UserStatusName(to))
+		}
+	}
+	// 冻结的用户不能直接上线
+	if CanTransitionUser(models.UserStatusFrozen, models.UserStatusOnline) {
+		t.Error("CanTransitionUser(冻结, 在线) = true, want false")
+	}
+	if !CanTransitionUser(models.UserStatusOnline, models.UserStatusOffline) {
+		t.Error("CanTransitionUser(在线, 离线) = false, want true")
+	}
+	// 未知状态不允许任何变更
+	if CanTransitionUser(0, models.UserStatusOffline) {
+		t.Error("CanTransitionUser(0, 离线) = true, want false")
+	}
+}
+
+func TestUserTransitionEventCode(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     string
+	}{
+		{models.UserStatusOffline, models.UserStatusFrozen, EventCodeUserFrozen},
+		{models.UserStatusFrozen, models.UserStatusOffline, EventCodeUserUnfrozen},
+		{models.UserStatusFrozen, models.UserStatusCancelled, EventCodeUserCancelled},
+		{models.UserStatusCancelled, models.UserStatusOffline, EventCodeUserReactivated},
+		{models.UserStatusOffline, models.UserStatusOnline, EventCodeUserOnline},
+		{models.UserStatusOnline, models.UserStatusOffline, EventCodeUserOffline},
+	}
+	for _, tt := range tests {
+		if got := userTransitionEventCode(tt.from, tt.to); got != tt.want {
+			t.Errorf("userTransitionEventCode(%d, %d) = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/database/metrics"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+)
+
+// MetricsRollup 设备性能数据聚合器
+// 定期将原始采样聚合为分钟数据、将分钟数据聚合为小时数据，并按保留时长清理过期数据
+type MetricsRollup struct {
+	metricRepo repositories.DeviceMetricRepository
+	cfg        *config.Config
+	stopChan   chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewMetricsRollup 创建设备性能数据聚合器
+func NewMetricsRollup(repoFactory repositories.RepositoryFactory) *MetricsRollup {
+	return &MetricsRollup{
+		metricRepo: repoFactory.GetDeviceMetricRepository(),
+		cfg:        config.GetConfig(),
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start 立即聚合一次并启动定期聚合
+func (m *MetricsRollup) Start() {
+	m.Run()
+
+	interval := time.Duration(m.cfg.Metrics.RollupInterval) * time.Second
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stopChan:
+				return
+			case <-ticker.C:
+				m.Run()
+			}
+		}
+	}()
+
+	if m.cfg.DebugLevel == "true" {
+		log.Printf("设备性能数据聚合器已启动，聚合间隔: %v\n", interval)
+	}
+}
+
+// Stop 停止聚合器
+func (m *MetricsRollup) Stop() {
+	close(m.stopChan)
+	m.wg.Wait()
+}
+
+// Run 执行一次聚合和清理
+func (m *MetricsRollup) Run() {
+	now := time.Now()
+	metricsCfg := &m.cfg.Metrics
+
+	if err := m.rollupMinutes(now); err != nil {
+		log.Printf("聚合设备性能分钟数据失败: %v\n", err)
+		// 分钟数据不完整时不聚合小时数据，也不清理原始采样
+		return
+	}
+	if err := m.rollupHours(now); err != nil {
+		log.Printf("聚合设备性能小时数据失败: %v\n", err)
+		return
+	}
+
+	if before := metrics.RetentionStart(metricsCfg.RawRetention, now); !before.IsZero() {
+		if _, err := m.metricRepo.DeleteSamplesBefore(before); err != nil {
+			log.Printf("清理过期的设备性能采样失败: %v\n", err)
+		}
+	}
+	if before := metrics.RetentionStart(metricsCfg.MinuteRetention, now); !before.IsZero() {
+		if _, err := m.metricRepo.DeleteRollupsBefore(models.MetricResolutionMinute, before); err != nil {
+			log.Printf("清理过期的设备性能分钟数据失败: %v\n", err)
+		}
+	}
+	if before := metrics.RetentionStart(metricsCfg.HourRetention, now); !before.IsZero() {
+		if _, err := m.metricRepo.DeleteRollupsBefore(models.MetricResolutionHour, before); err != nil {
+			log.Printf("清理过期的设备性能小时数据失败: %v\n", err)
+		}
+	}
+}
+
+// rollupMinutes 将已结束的分钟内的原始采样聚合为分钟数据
+// 从最新一条分钟数据所在的区间开始重新聚合，服务停止期间的采样在保留时长内都会补齐
+func (m *MetricsRollup) rollupMinutes(now time.Time) error {
+	from, to, err := m.rollupRange(models.MetricResolutionMinute, m.cfg.Metrics.RawRetention, now)
+	if err != nil || !from.Before(to) {
+		return err
+	}
+	samples, err := m.metricRepo.FindSamples(0, from, to, 0)
+	if err != nil {
+		return err
+	}
+	return m.metricRepo.SaveRollups(metrics.AggregateSamples(samples, models.MetricResolutionMinute))
+}
+
+// rollupHours 将已结束的小时内的分钟数据聚合为小时数据
+func (m *MetricsRollup) rollupHours(now time.Time) error {
+	from, to, err := m.rollupRange(models.MetricResolutionHour, m.cfg.Metrics.MinuteRetention, now)
+	if err != nil || !from.Before(to) {
+		return err
+	}
+	rollups, err := m.metricRepo.FindRollups(0, models.MetricResolutionMinute, from, to)
+	if err != nil {
+		return err
+	}
+	return m.metricRepo.SaveRollups(metrics.MergeRollups(rollups, models.MetricResolutionHour))
+}
+
+// rollupRange 返回需要聚合为resolution精度的时间范围
+// sourceRetention为聚合来源数据的保留时长（小时），早于保留时长的来源数据已被清理，无需聚合
+func (m *MetricsRollup) rollupRange(resolution, sourceRetention int, now time.Time) (time.Time, time.Time, error) {
+	width := time.Duration(resolution) * time.Second
+	from := metrics.RetentionStart(sourceRetention, now)
+	latest, err := m.metricRepo.LatestRollupStart(resolution)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if latest != nil && latest.After(from) {
+		from = *latest
+	}
+	return from.Truncate(width), now.Truncate(width), nil
+}
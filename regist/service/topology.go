@@ -0,0 +1,197 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	"gin-server/database/models"
+)
+
+// 设备拓扑错误
+var (
+	ErrTopologyDeviceNotFound = errors.New("设备不存在")
+	ErrTopologyCycle          = errors.New("设备位于上级关系环中")
+)
+
+// TopologySummary 子树统计，包含节点自身
+type TopologySummary struct {
+	Devices       int   `json:"devices"`
+	OnlineDevices int   `json:"online_devices"`
+	Users         int64 `json:"users"`
+	OnlineUsers   int64 `json:"online_users"`
+}
+
+// TopologyNode 设备拓扑树节点
+type TopologyNode struct {
+	DeviceID         int             `json:"device_id"`
+	DeviceName       string          `json:"device_name"`
+	DeviceType       int             `json:"device_type"`
+	SuperiorDeviceID int             `json:"superior_device_id"`
+	DeviceStatus     int             `json:"device_status"`
+	Online           bool            `json:"online"`
+	Orphan           bool            `json:"orphan,omitempty"` // 上级设备不存在
+	Depth            int             `json:"depth"`            // 相对于返回的根节点的层级，根节点为0
+	UserCount        int64           `json:"user_count"`       // 直接所属的用户数
+	OnlineUserCount  int64           `json:"online_user_count"`
+	Summary          TopologySummary `json:"summary"`
+	Truncated        bool            `json:"truncated,omitempty"` // 因深度限制未展开下级设备
+	Children         []*TopologyNode `json:"children"`
+}
+
+// Topology 设备拓扑
+// 设备通过SuperiorDeviceID形成树，上级为0或上级不存在的设备作为根节点，
+// 从根节点无法到达的设备位于上级关系环中或挂在环下
+type Topology struct {
+	nodes    map[int]*TopologyNode
+	roots    []*TopologyNode
+	cycleIDs []int
+}
+
+// BuildTopology 根据设备列表和各网关的用户数构建设备拓扑
+func BuildTopology(devices []models.Device, userCounts []models.GatewayUserCount) *Topology {
+	t := &Topology{nodes: make(map[int]*TopologyNode, len(devices)), cycleIDs: []int{}}
+	for _, device := range devices {
+		t.nodes[device.DeviceID] = &TopologyNode{
+			DeviceID:         device.DeviceID,
+			DeviceName:       device.DeviceName,
+			DeviceType:       device.DeviceType,
+			SuperiorDeviceID: device.SuperiorDeviceID,
+			DeviceStatus:     device.DeviceStatus,
+			Online:           device.DeviceStatus == models.DeviceStatusOnline,
+			Children:         []*TopologyNode{},
+		}
+	}
+	for _, count := range userCounts {
+		if node, ok := t.nodes[count.GatewayDeviceID]; ok {
+			node.UserCount += count.Total
+			node.OnlineUserCount += count.Online
+		}
+	}
+
+	for _, node := range t.nodes {
+		if node.SuperiorDeviceID == 0 {
+			t.roots = append(t.roots, node)
+			continue
+		}
+		superior, ok := t.nodes[node.SuperiorDeviceID]
+		if !ok {
+			node.Orphan = true
+			t.roots = append(t.roots, node)
+			continue
+		}
+		superior.Children = append(superior.Children, node)
+	}
+	sortNodes(t.roots)
+	for _, node := range t.nodes {
+		sortNodes(node.Children)
+	}
+
+	reached := make(map[int]bool, len(t.nodes))
+	for _, root := range t.roots {
+		summarize(root, reached)
+	}
+	for id := range t.nodes {
+		if !reached[id] {
+			t.cycleIDs = append(t.cycleIDs, id)
+		}
+	}
+	sort.Ints(t.cycleIDs)
+	return t
+}
+
+// Roots 返回所有根节点的拓扑树，depth为展开的下级层数，小于0时不限制
+func (t *Topology) Roots(depth int) []*TopologyNode {
+	roots := make([]*TopologyNode, 0, len(t.roots))
+	for _, root := range t.roots {
+		roots = append(roots, prune(root, 0, depth))
+	}
+	return roots
+}
+
+// Subtree 返回以指定设备为根的子树，depth为展开的下级层数，小于0时不限制
+func (t *Topology) Subtree(deviceID, depth int) (*TopologyNode, error) {
+	node, ok := t.nodes[deviceID]
+	if !ok {
+		return nil, ErrTopologyDeviceNotFound
+	}
+	if t.inCycle(deviceID) {
+		return nil, ErrTopologyCycle
+	}
+	return prune(node, 0, depth), nil
+}
+
+// CycleDeviceIDs 返回位于上级关系环中或挂在环下的设备ID
+func (t *Topology) CycleDeviceIDs() []int {
+	return t.cycleIDs
+}
+
+// inCycle 判断设备是否无法从根节点到达
+func (t *Topology) inCycle(deviceID int) bool {
+	index := sort.SearchInts(t.cycleIDs, deviceID)
+	return index < len(t.cycleIDs) && t.cycleIDs[index] == deviceID
+}
+
+// summarize 自下而上计算子树统计，记录已到达的设备
+func summarize(node *TopologyNode, reached map[int]bool) TopologySummary {
+	reached[node.DeviceID] = true
+	summary := TopologySummary{Devices: 1, Users: node.UserCount, OnlineUsers: node.OnlineUserCount}
+	if node.Online {
+		summary.OnlineDevices = 1
+	}
+	for _, child := range node.Children {
+		sub := summarize(child, reached)
+		summary.Devices += sub.Devices
+		summary.OnlineDevices += sub.OnlineDevices
+		summary.Users += sub.Users
+		summary.OnlineUsers += sub.OnlineUsers
+	}
+	node.Summary = summary
+	return summary
+}
+
+// prune 复制节点并按深度限制截断下级设备，子树统计保持完整
+func prune(node *TopologyNode, level, depth int) *TopologyNode {
+	copied := *node
+	copied.Depth = level
+	copied.Children = []*TopologyNode{}
+	if depth >= 0 && level >= depth {
+		copied.Truncated = len(node.Children) > 0
+		return &copied
+	}
+	for _, child := range node.Children {
+		copied.Children = append(copied.Children, prune(child, level+1, depth))
+	}
+	return &copied
+}
+
+// sortNodes 按设备ID排序
+func sortNodes(nodes []*TopologyNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].DeviceID < nodes[j].DeviceID
+	})
+}
+
+// CreatesCycle 判断将设备的上级设置为superiorID后是否形成环
+// superiorOf返回设备的上级设备ID以及设备是否存在；已存在的环不会导致死循环
+func CreatesCycle(deviceID, superiorID int, superiorOf func(int) (int, bool, error)) (bool, error) {
+	visited := make(map[int]bool)
+	for current := superiorID; current != 0; {
+		if current == deviceID {
+			return true, nil
+		}
+		if visited[current] {
+			return false, nil
+		}
+		visited[current] = true
+
+		next, ok, err := superiorOf(current)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+		current = next
+	}
+	return false, nil
+}
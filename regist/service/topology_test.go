@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gin-server/database/models"
+)
+
+// testTopologyDevices 安全接入管理设备1000下有网关2001、2002，网关2003的上级不存在，3001和3002互为上级
+func testTopologyDevices() []models.Device {
+	return []models.Device{
+		{DeviceID: 1000, DeviceType: 4, SuperiorDeviceID: 0, DeviceStatus: models.DeviceStatusOnline},
+		{DeviceID: 2002, DeviceType: 1, SuperiorDeviceID: 1000, DeviceStatus: models.DeviceStatusOffline},
+		{DeviceID: 2001, DeviceType: 1, SuperiorDeviceID: 1000, DeviceStatus: models.DeviceStatusOnline},
+		{DeviceID: 2003, DeviceType: 2, SuperiorDeviceID: 9999, DeviceStatus: models.DeviceStatusOnline},
+		{DeviceID: 3001, DeviceType: 1, SuperiorDeviceID: 3002},
+		{DeviceID: 3002, DeviceType: 1, SuperiorDeviceID: 3001},
+	}
+}
+
+func TestBuildTopology(t *testing.T) {
+	counts := []models.GatewayUserCount{
+		{GatewayDeviceID: 2001, Total: 5, Online: 2},
+		{GatewayDeviceID: 2002, Total: 3, Online: 0},
+		{GatewayDeviceID: 8888, Total: 1, Online: 1},
+	}
+	topology := BuildTopology(testTopologyDevices(), counts)
+
+	roots := topology.Roots(-1)
+	if len(roots) != 2 || roots[0].DeviceID != 1000 || roots[1].DeviceID != 2003 {
+		t.Fatalf("Roots() = %v, want [1000 2003]", roots)
+	}
+	if !roots[1].Orphan {
+		t.Error("上级不存在的设备应标记为orphan")
+	}
+
+	root := roots[0]
+	if len(root.Children) != 2 || root.Children[0].DeviceID != 2001 || root.Children[1].DeviceID != 2002 {
+		t.Fatalf("下级设备应按设备ID排序, got %v", root.Children)
+	}
+	if root.Children[0].Depth != 1 || root.Children[0].UserCount != 5 || root.Children[0].OnlineUserCount != 2 {
+		t.Errorf("网关2001 = %+v", root.Children[0])
+	}
+	want := TopologySummary{Devices: 3, OnlineDevices: 2, Users: 8, OnlineUsers: 2}
+	if root.Summary != want {
+		t.Errorf("Summary = %+v, want %+v", root.Summary, want)
+	}
+
+	if got := topology.CycleDeviceIDs(); !reflect.DeepEqual(got, []int{3001, 3002}) {
+		t.Errorf("CycleDeviceIDs() = %v, want [3001 3002]", got)
+	}
+}
+
+func TestTopologySubtreeDepth(t *testing.T) {
+	topology := BuildTopology(testTopologyDevices(), nil)
+
+	node, err := topology.Subtree(1000, 0)
+	if err != nil {
+		t.Fatalf("Subtree() error = %v", err)
+	}
+	if len(node.Children) != 0 || !node.Truncated {
+		t.Errorf("depth=0时不应展开下级设备, got %+v", node)
+	}
+	if node.Summary.Devices != 3 {
+		t.Errorf("截断后子树统计应保持完整, got %+v", node.Summary)
+	}
+
+	node, err = topology.Subtree(2001, -1)
+	if err != nil || node.Depth != 0 || node.Truncated {
+		t.Errorf("Subtree(2001) = %+v, %v", node, err)
+	}
+
+	if _, err := topology.Subtree(4242, -1); !errors.Is(err, ErrTopologyDeviceNotFound) {
+		t.Errorf("设备不存在时应返回ErrTopologyDeviceNotFound, got %v", err)
+	}
+	if _, err := topology.Subtree(3001, -1); !errors.Is(err, ErrTopologyCycle) {
+		t.Errorf("环中的设备应返回ErrTopologyCycle, got %v", err)
+	}
+}
+
+func TestCreatesCycle(t *testing.T) {
+	superiors := map[int]int{1000: 0, 2001: 1000, 3001: 2001, 4001: 4002, 4002: 4001}
+	superiorOf := func(id int) (int, bool, error) {
+		superior, ok := superiors[id]
+		return superior, ok, nil
+	}
+
+	tests := []struct {
+		name       string
+		deviceID   int
+		superiorID int
+		want       bool
+	}{
+		{"设为根设备", 2001, 0, false},
+		{"以自身为上级", 2001, 2001, true},
+		{"以下级为上级", 1000, 3001, true},
+		{"移动到其他分支", 3001, 1000, false},
+		{"上级不存在", 2001, 9999, false},
+		{"上级链中已存在环", 1000, 4001, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CreatesCycle(tt.deviceID, tt.superiorID, superiorOf)
+			if err != nil || got != tt.want {
+				t.Errorf("CreatesCycle() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/alert"
+	"gin-server/database/models"
+	"gin-server/database/repositories"
+)
+
+// 设备离线检测事件代码
+const (
+	EventCodeDeviceOffline   = "DEVICE_OFFLINE"
+	EventCodeDeviceRecovered = "DEVICE_RECOVERED"
+)
+
+// DeviceWatchdog 设备离线检测器
+// 定期检查在线设备的最后心跳时间，连续HEARTBEAT_MISSED_THRESHOLD个心跳间隔未上报时将设备置为离线，
+// 记录故障事件并产生告警；从未上报过心跳的设备不做检测
+type DeviceWatchdog struct {
+	repoFactory repositories.RepositoryFactory
+	alerter     alert.Alerter
+	cfg         *config.Config
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewDeviceWatchdog 创建设备离线检测器
+func NewDeviceWatchdog(repoFactory repositories.RepositoryFactory) *DeviceWatchdog {
+	return &DeviceWatchdog{
+		repoFactory: repoFactory,
+		alerter:     alert.GetDefaultAlerter(),
+		cfg:         config.GetConfig(),
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start 立即检查一次并启动定期检查
+func (w *DeviceWatchdog) Start() {
+	w.Check()
+
+	interval := time.Duration(w.cfg.Heartbeat.CheckInterval) * time.Second
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stopChan:
+				return
+			case <-ticker.C:
+				w.Check()
+			}
+		}
+	}()
+
+	if w.cfg.DebugLevel == "true" {
+		log.Printf("设备离线检测器已启动，检查间隔: %v，心跳超时: %v\n", interval, HeartbeatTimeout(&w.cfg.Heartbeat))
+	}
+}
+
+// Stop 停止检测器
+func (w *DeviceWatchdog) Stop() {
+	close(w.stopChan)
+	w.wg.Wait()
+}
+
+// Check 执行一次检查，返回本次判定离线的设备数
+func (w *DeviceWatchdog) Check() int {
+	now := time.Now()
+	before := now.Add(-HeartbeatTimeout(&w.cfg.Heartbeat))
+
+	deviceRepo := w.repoFactory.GetDeviceRepository()
+	devices, err := deviceRepo.FindHeartbeatTimeout(before)
+	if err != nil {
+		log.Printf("查询心跳超时的设备失败: %v\n", err)
+		return 0
+	}
+
+	count := 0
+	for i := range devices {
+		device := &devices[i]
+		marked, err := deviceRepo.MarkOffline(device.ID, before, now)
+		if err != nil {
+			log.Printf("将设备 %d 置为离线失败: %v\n", device.DeviceID, err)
+			continue
+		}
+		// 查询后设备重新上报了心跳
+		if !marked {
+			continue
+		}
+		count++
+
+		message := fmt.Sprintf("设备 %s(%d) 超过 %d 个心跳间隔未上报，已判定离线，最后心跳时间: %s",
+			device.DeviceName, device.DeviceID, w.missedThreshold(), device.LastHeartbeatAt.Format("2006-01-02 15:04:05"))
+		log.Println(message)
+		recordDeviceFault(w.repoFactory, device.DeviceID, EventCodeDeviceOffline, message, now)
+		w.alerter.Alert(&alert.Alert{
+			Level:     alert.AlertLevelWarning,
+			Type:      alert.AlertTypeDeviceOffline,
+			Message:   message,
+			Module:    "DeviceWatchdog",
+			Timestamp: now,
+		})
+	}
+	return count
+}
+
+// missedThreshold 返回判定离线的未上报心跳次数
+func (w *DeviceWatchdog) missedThreshold() int {
+	if w.cfg.Heartbeat.MissedThreshold <= 0 {
+		return defaultMissedThreshold
+	}
+	return w.cfg.Heartbeat.MissedThreshold
+}
+
+// NotifyDeviceRecovered 记录设备恢复上报的故障事件并产生告警
+// offlineAt为设备被判定离线的时间
+func NotifyDeviceRecovered(repoFactory repositories.RepositoryFactory, device *models.Device, offlineAt, at time.Time) {
+	message := fmt.Sprintf("设备 %s(%d) 已恢复上报心跳，离线时长 %v",
+		device.DeviceName, device.DeviceID, at.Sub(offlineAt).Round(time.Second))
+	log.Println(message)
+	recordDeviceFault(repoFactory, device.DeviceID, EventCodeDeviceRecovered, message, at)
+	alert.GetDefaultAlerter().Alert(&alert.Alert{
+		Level:     alert.AlertLevelInfo,
+		Type:      alert.AlertTypeDeviceOffline,
+		Message:   message,
+		Module:    "DeviceWatchdog",
+		Timestamp: at,
+	})
+}
+
+// recordDeviceFault 记录设备故障事件，失败时只记录日志
+func recordDeviceFault(repoFactory repositories.RepositoryFactory, deviceID int, code, desc string, at time.Time) {
+	event := &models.Event{
+		EventID:   time.Now().UnixNano(),
+		DeviceID:  deviceID,
+		EventTime: at,
+		EventType: models.EventTypeFault,
+		EventCode: code,
+		EventDesc: desc,
+	}
+	if err := repoFactory.GetEventRepository().Create(event); err != nil {
+		log.Printf("保存设备 %d 的故障事件失败: %v\n", deviceID, err)
+	}
+}
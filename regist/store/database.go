@@ -0,0 +1,55 @@
+package store
+
+import (
+	"fmt"
+	"path"
+
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+)
+
+// DatabaseStore 数据库证书存储
+// 证书和私钥内容保存在certs表的cert_pem和key_pem字段中，多个服务实例无需共享磁盘即可使用同一批证书
+type DatabaseStore struct{}
+
+// NewDatabaseStore 创建数据库证书存储
+func NewDatabaseStore() *DatabaseStore {
+	return &DatabaseStore{}
+}
+
+// Type 返回存储类型
+func (s *DatabaseStore) Type() StoreType {
+	return StoreTypeDatabase
+}
+
+// PutCert 将证书保存到证书记录中
+func (s *DatabaseStore) PutCert(cert *models.Cert, data []byte) error {
+	cert.CertPEM = data
+	cert.CertPath = DBLocationPrefix + path.Join(CertsDir, fileName(cert))
+	return nil
+}
+
+// PutKey 将私钥保存到证书记录中
+func (s *DatabaseStore) PutKey(cert *models.Cert, data []byte) error {
+	sealed, err := crypto.SealKey(data)
+	if err != nil {
+		return fmt.Errorf("加密私钥失败: %w", err)
+	}
+	cert.KeyPEM = sealed
+	cert.KeyPath = DBLocationPrefix + path.Join(KeysDir, fileName(cert))
+	return nil
+}
+
+// GetCert 读取证书
+func (s *DatabaseStore) GetCert(cert *models.Cert) ([]byte, error) {
+	return load(cert.CertPath, cert.CertPEM)
+}
+
+// GetKey 读取私钥
+func (s *DatabaseStore) GetKey(cert *models.Cert) ([]byte, error) {
+	data, err := load(cert.KeyPath, cert.KeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return openKey(data)
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gin-server/database/models"
+)
+
+// journalFile 写入前的文件状态
+type journalFile struct {
+	path     string
+	perm     os.FileMode
+	existed  bool
+	previous []byte
+}
+
+// JournaledStore 记录写入的证书存储
+// 本地存储写入文件前记录文件原有内容，数据库事务回滚时调用Rollback删除新写入的文件、恢复被覆盖的文件，
+// 避免回滚后遗留的文件被下次绑定同一版本时误用；数据库存储的内容随事务回滚，无需额外处理
+type JournaledStore struct {
+	CertStore
+	local *LocalStore
+	files []journalFile
+}
+
+// NewJournaledStore 创建记录写入的证书存储
+func NewJournaledStore(s CertStore) *JournaledStore {
+	local, _ := s.(*LocalStore)
+	return &JournaledStore{CertStore: s, local: local}
+}
+
+// PutCert 记录证书文件原有内容后保存证书
+func (j *JournaledStore) PutCert(cert *models.Cert, data []byte) error {
+	if err := j.snapshot(CertsDir, cert, 0644); err != nil {
+		return err
+	}
+	return j.CertStore.PutCert(cert, data)
+}
+
+// PutKey 记录私钥文件原有内容后保存私钥
+func (j *JournaledStore) PutKey(cert *models.Cert, data []byte) error {
+	if err := j.snapshot(KeysDir, cert, 0600); err != nil {
+		return err
+	}
+	return j.CertStore.PutKey(cert, data)
+}
+
+// Rollback 按写入的逆序恢复文件，返回遇到的错误
+func (j *JournaledStore) Rollback() error {
+	var errs []error
+	for i := len(j.files) - 1; i >= 0; i-- {
+		file := j.files[i]
+		var err error
+		if file.existed {
+			err = os.WriteFile(file.path, file.previous, file.perm)
+		} else if err = os.Remove(file.path); os.IsNotExist(err) {
+			err = nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("恢复文件%s失败: %w", file.path, err))
+		}
+	}
+	j.files = nil
+	return errors.Join(errs...)
+}
+
+// snapshot 记录即将写入的文件的原有内容
+func (j *JournaledStore) snapshot(dir string, cert *models.Cert, perm os.FileMode) error {
+	if j.local == nil {
+		return nil
+	}
+	path := filepath.Join(j.local.baseDir, dir, fileName(cert))
+	previous, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取文件%s失败: %w", path, err)
+	}
+	j.files = append(j.files, journalFile{path: path, perm: perm, existed: err == nil, previous: previous})
+	return nil
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+)
+
+// 本地存储的子目录
+const (
+	CertsDir = "certs" // 证书目录
+	KeysDir  = "keys"  // 私钥目录
+)
+
+// LocalStore 本地文件证书存储
+// 证书保存在 <baseDir>/certs，私钥保存在 <baseDir>/keys，保存位置为文件的绝对路径
+type LocalStore struct {
+	baseDir string
+}
+
+// NewLocalStore 创建本地文件证书存储
+func NewLocalStore(baseDir string) *LocalStore {
+	return &LocalStore{baseDir: baseDir}
+}
+
+// Type 返回存储类型
+func (s *LocalStore) Type() StoreType {
+	return StoreTypeLocal
+}
+
+// PutCert 保存证书文件
+func (s *LocalStore) PutCert(cert *models.Cert, data []byte) error {
+	path, err := s.writeFile(CertsDir, fileName(cert), data, 0644)
+	if err != nil {
+		return err
+	}
+	cert.CertPath = path
+	cert.CertPEM = nil
+	return nil
+}
+
+// PutKey 保存私钥文件
+func (s *LocalStore) PutKey(cert *models.Cert, data []byte) error {
+	sealed, err := crypto.SealKey(data)
+	if err != nil {
+		return fmt.Errorf("加密私钥失败: %w", err)
+	}
+	path, err := s.writeFile(KeysDir, fileName(cert), sealed, 0600)
+	if err != nil {
+		return err
+	}
+	cert.KeyPath = path
+	cert.KeyPEM = nil
+	return nil
+}
+
+// GetCert 读取证书
+func (s *LocalStore) GetCert(cert *models.Cert) ([]byte, error) {
+	return load(cert.CertPath, cert.CertPEM)
+}
+
+// GetKey 读取私钥
+func (s *LocalStore) GetKey(cert *models.Cert) ([]byte, error) {
+	data, err := load(cert.KeyPath, cert.KeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return openKey(data)
+}
+
+// writeFile 写入文件并返回绝对路径
+func (s *LocalStore) writeFile(dir, name string, data []byte, perm os.FileMode) (string, error) {
+	cfg := config.GetConfig()
+
+	if err := s.ensureDirs(); err != nil {
+		return "", err
+	}
+
+	filePath := filepath.Join(s.baseDir, dir, name)
+	if err := os.WriteFile(filePath, data, perm); err != nil {
+		if cfg.DebugLevel == "true" {
+			log.Printf("写入文件失败: %v\n", err)
+		}
+		return "", fmt.Errorf("写入文件失败: %w", err)
+	}
+
+	// 文件已存在时WriteFile不会修改权限
+	if err := os.Chmod(filePath, perm); err != nil {
+		log.Printf("警告: 设置文件权限失败: %v\n", err)
+	}
+
+	absPath := absolutePath(filePath)
+	if cfg.DebugLevel == "true" {
+		log.Printf("文件保存成功: %s\n", absPath)
+	}
+	return absPath, nil
+}
+
+// ensureDirs 确保证书和私钥目录存在，私钥目录权限更严格
+func (s *LocalStore) ensureDirs() error {
+	if err := os.MkdirAll(filepath.Join(s.baseDir, CertsDir), 0755); err != nil {
+		return fmt.Errorf("创建证书目录失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.baseDir, KeysDir), 0700); err != nil {
+		return fmt.Errorf("创建密钥目录失败: %w", err)
+	}
+	return nil
+}
+
+// absolutePath 获取文件的绝对路径
+func absolutePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		log.Printf("获取工作目录失败: %v\n", err)
+		return path
+	}
+	absPath := filepath.Join(workDir, path)
+
+	// Windows 下使用反斜杠
+	if runtime.GOOS == "windows" {
+		absPath = strings.ReplaceAll(absPath, "/", "\\")
+	}
+	return absPath
+}
@@ -0,0 +1,92 @@
+// Package store 提供用户和设备证书、私钥的存储
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gin-server/config"
+	"gin-server/configmanager/common/crypto"
+	"gin-server/database/models"
+)
+
+// StoreType 证书存储类型
+type StoreType string
+
+const (
+	// StoreTypeLocal 本地文件存储
+	StoreTypeLocal StoreType = "local"
+	// StoreTypeDatabase 数据库存储
+	StoreTypeDatabase StoreType = "database"
+)
+
+// DBLocationPrefix 数据库存储的证书和私钥位置前缀
+const DBLocationPrefix = "db:"
+
+// ErrNotStored 证书记录中没有对应的证书或私钥
+var ErrNotStored = errors.New("证书记录中没有保存的内容")
+
+// CertStore 证书存储接口
+// 证书和私钥的保存位置记录在证书记录的CertPath和KeyPath中，数据库存储时内容保存在CertPEM和KeyPEM中，
+// 调用方需要在保存后持久化证书记录
+type CertStore interface {
+	// Type 返回存储类型
+	Type() StoreType
+	// PutCert 保存证书PEM，并将保存位置写入证书记录
+	PutCert(cert *models.Cert, data []byte) error
+	// PutKey 保存私钥PEM，按配置使用主密钥加密，并将保存位置写入证书记录
+	PutKey(cert *models.Cert, data []byte) error
+	// GetCert 读取证书记录对应的证书PEM
+	GetCert(cert *models.Cert) ([]byte, error)
+	// GetKey 读取证书记录对应的私钥PEM，已加密的私钥在内存中解密后返回
+	GetKey(cert *models.Cert) ([]byte, error)
+}
+
+// NewCertStore 创建证书存储
+func NewCertStore(typ StoreType, cfg *config.Config) (CertStore, error) {
+	switch typ {
+	case StoreTypeLocal, "":
+		return NewLocalStore(cfg.CertStore.LocalDir), nil
+	case StoreTypeDatabase:
+		return NewDatabaseStore(), nil
+	default:
+		return nil, fmt.Errorf("不支持的证书存储类型: %s", typ)
+	}
+}
+
+// Default 按全局配置创建证书存储
+func Default() (CertStore, error) {
+	cfg := config.GetConfig()
+	return NewCertStore(StoreType(cfg.CertStore.Type), cfg)
+}
+
+// fileName 返回证书或私钥的文件名，每个证书版本使用单独的文件
+func fileName(cert *models.Cert) string {
+	return fmt.Sprintf("%s_%s_v%d.pem", cert.EntityType, cert.EntityID, cert.Version)
+}
+
+// load 读取证书或私钥内容
+// 内容保存在数据库中时直接返回，否则从文件读取，因此切换存储类型后之前保存的记录仍可读取
+func load(location string, data []byte) ([]byte, error) {
+	if len(data) > 0 {
+		return data, nil
+	}
+	if location == "" || strings.HasPrefix(location, DBLocationPrefix) {
+		return nil, ErrNotStored
+	}
+	return os.ReadFile(location)
+}
+
+// openKey 解密私钥内容，未加密的私钥原样返回
+func openKey(data []byte) ([]byte, error) {
+	if !crypto.IsEnvelope(data) {
+		return data, nil
+	}
+	envelope, err := crypto.GetKeyEnvelope()
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Open(data)
+}